@@ -0,0 +1,62 @@
+package healthcheck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// SQL pings db.
+func SQL(db *sql.DB) HealthCheckFunc {
+	return db.PingContext
+}
+
+// HTTPEndpoint checks that a GET of url answers with a 2xx status.
+func HTTPEndpoint(url string) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s answered %s", url, resp.Status)
+		}
+		return nil
+	}
+}
+
+// TCP checks that a TCP connection to addr can be established.
+func TCP(addr string) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// DiskSpace checks that the file system holding path has at least minFree
+// bytes available to unprivileged users.
+func DiskSpace(path string, minFree uint64) HealthCheckFunc {
+	return func(context.Context) error {
+		free, err := freeSpace(path)
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d bytes free on %s, below %d", free, path, minFree)
+		}
+		return nil
+	}
+}
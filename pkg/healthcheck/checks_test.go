@@ -0,0 +1,101 @@
+package healthcheck
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pingDriver is a database/sql driver whose connections fail to open
+// when the DSN is "down".
+type pingDriver struct{}
+
+func (pingDriver) Open(dsn string) (driver.Conn, error) {
+	if dsn == "down" {
+		return nil, errors.New("connection refused")
+	}
+	return pingConn{}, nil
+}
+
+type pingConn struct{}
+
+func (pingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (pingConn) Close() error                        { return nil }
+func (pingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func init() {
+	sql.Register("healthcheck-ping", pingDriver{})
+}
+
+func TestChecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/slow":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	openDB := func(dsn string) *sql.DB {
+		db, err := sql.Open("healthcheck-ping", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	tests := []struct {
+		name    string
+		check   HealthCheckFunc
+		timeout time.Duration
+		wantErr string
+	}{
+		{"sql up", SQL(openDB("up")), time.Second, ""},
+		{"sql down", SQL(openDB("down")), time.Second, "connection refused"},
+		{"http 2xx", HTTPEndpoint(srv.URL + "/ok"), time.Second, ""},
+		{"http 5xx", HTTPEndpoint(srv.URL + "/fail"), time.Second, "answered 500 Internal Server Error"},
+		{"http timeout", HTTPEndpoint(srv.URL + "/slow"), 20 * time.Millisecond, "context deadline exceeded"},
+		{"http bad url", HTTPEndpoint("://nowhere"), time.Second, "missing protocol scheme"},
+		{"tcp up", TCP(ln.Addr().String()), time.Second, ""},
+		{"tcp refused", TCP(closedAddr), time.Second, "refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			err := tt.check(ctx)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("check failed: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
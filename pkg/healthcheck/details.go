@@ -0,0 +1,46 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Details holds the reports of all probes.
+type Details struct {
+	Startup   Report `json:"startup"`
+	Readiness Report `json:"readiness"`
+	Liveness  Report `json:"liveness"`
+}
+
+// Details returns the reports of all probes, as served by the probes
+// themselves.
+func (r *Registry) Details(ctx context.Context) Details {
+	return Details{
+		Startup:   r.Startup(ctx),
+		Readiness: r.Readiness(ctx),
+		Liveness:  r.Liveness(ctx),
+	}
+}
+
+// RegisterDetails mounts /healthz/details, which always answers GET and
+// HEAD with 200 and the Details as body, and other methods with 405. Pass
+// guards such as middleware.BasicAuth or middleware.StaticBearer to keep
+// the diagnostics private.
+func (r *Registry) RegisterDetails(router gin.IRoutes, guards ...gin.HandlerFunc) {
+	handlers := make([]gin.HandlerFunc, 0, len(guards)+1)
+	handlers = append(handlers, guards...)
+	router.Any("/healthz/details", append(handlers, gin.WrapF(r.DetailsHandler()))...)
+}
+
+// DetailsHandler returns the handler of RegisterDetails for net/http
+// servers. It does no authentication of its own.
+func (r *Registry) DetailsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !allowRead(w, req) {
+			return
+		}
+		writeJSON(w, http.StatusOK, r.Details(req.Context()))
+	}
+}
@@ -0,0 +1,91 @@
+package healthcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PhilipKram/gms-foundation/pkg/middleware"
+)
+
+func TestTerse(t *testing.T) {
+	h := handlerFor(New().Terse().AddReadiness("db", down).AddLiveness("loop", up))
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/healthz/readiness", http.StatusServiceUnavailable, "down"},
+		{"/healthz/liveness", http.StatusOK, "up"},
+		{"/healthz/startup", http.StatusOK, "up"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serveProbe(h, http.MethodGet, tt.path)
+			if w.Code != tt.wantCode || w.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), tt.wantCode, tt.wantBody)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if strings.Contains(w.Body.String(), "connection refused") {
+				t.Error("terse probe exposed a check error")
+			}
+		})
+	}
+}
+
+func TestRegisterDetails(t *testing.T) {
+	r := New().Terse().AddReadiness("db", down).AddLiveness("loop", up)
+	router := gin.New()
+	r.RegisterDetails(router, middleware.StaticBearer("secret"))
+
+	tests := []struct {
+		name     string
+		method   string
+		auth     string
+		wantCode int
+	}{
+		{"authorized", http.MethodGet, "Bearer secret", http.StatusOK},
+		{"authorized HEAD", http.MethodHead, "Bearer secret", http.StatusOK},
+		{"missing token", http.MethodGet, "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "Bearer guess", http.StatusUnauthorized},
+		{"wrong scheme", http.MethodGet, "Basic c2VjcmV0", http.StatusUnauthorized},
+		{"unsupported method", http.MethodPost, "Bearer secret", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/healthz/details", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			leaked := strings.Contains(w.Body.String(), "connection refused")
+			if wantDetails := tt.wantCode == http.StatusOK; leaked != wantDetails {
+				t.Errorf("details exposed = %v, want %v: %s", leaked, wantDetails, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDetailsHandler(t *testing.T) {
+	h := New().AddStartup("warmup", up).AddReadiness("db", down).DetailsHandler()
+	w := serveProbe(h, http.MethodGet, "/healthz/details")
+	if w.Code != http.StatusOK {
+		t.Fatalf("code = %d, want 200 even while a probe is down", w.Code)
+	}
+	body := decodeReport(t, w)
+	for probe, want := range map[string]string{"startup": "up", "readiness": "down", "liveness": "up"} {
+		report, _ := body[probe].(map[string]interface{})
+		if report["status"] != want {
+			t.Errorf("%s = %v, want %s", probe, report["status"], want)
+		}
+	}
+}
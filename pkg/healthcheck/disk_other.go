@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package healthcheck
+
+import "errors"
+
+func freeSpace(string) (uint64, error) {
+	return 0, errors.New("disk space check is not supported on this platform")
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin || freebsd
+
+package healthcheck
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		path    string
+		minFree uint64
+		wantErr string
+	}{
+		{"enough", dir, 1, ""},
+		{"too little", dir, math.MaxUint64, "bytes free on " + dir},
+		{"missing path", filepath.Join(dir, "missing"), 1, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DiskSpace(tt.path, tt.minFree)(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("check failed: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	checkStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "healthcheck_status",
+		Help: "Latest result of a health check: 1 when up, 0 when down.",
+	}, []string{"probe", "check"})
+	checkDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "healthcheck_duration_seconds",
+		Help:    "Latency of health checks.",
+		Buckets: []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"probe", "check"})
+	checkTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthcheck_transitions_total",
+		Help: "Health check transitions between up and down, by new status.",
+	}, []string{"probe", "check", "status"})
+)
+
+// Event describes a check changing between up and down.
+type Event struct {
+	// Probe is "startup", "readiness" or "liveness".
+	Probe string
+	Check string
+	From  Status
+	To    Status
+	// Error is the error of the check when it went down.
+	Error string
+	Time  time.Time
+}
+
+// OnStateChange registers fn to be called, without locks held, whenever a
+// check's status differs from its previous result. The first result of a
+// check is not a change. fn runs on the probe's goroutine and should not
+// block.
+func (r *Registry) OnStateChange(fn func(Event)) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+	return r
+}
+
+// observe records the results of a probe in the metrics and fires state
+// change events.
+func (r *Registry) observe(p *probe, report Report) {
+	var events []Event
+	p.mu.Lock()
+	if p.last == nil {
+		p.last = make(map[string]Status)
+	}
+	for _, res := range report.Checks {
+		up := 0.0
+		if res.Status == StatusUp {
+			up = 1
+		}
+		checkStatus.WithLabelValues(p.name, res.Name).Set(up)
+		checkDuration.WithLabelValues(p.name, res.Name).Observe(res.Latency.Seconds())
+
+		prev, seen := p.last[res.Name]
+		p.last[res.Name] = res.Status
+		if seen && prev != res.Status {
+			checkTransitions.WithLabelValues(p.name, res.Name, string(res.Status)).Inc()
+			events = append(events, Event{
+				Probe: p.name,
+				Check: res.Name,
+				From:  prev,
+				To:    res.Status,
+				Error: res.Error,
+				Time:  report.Time,
+			})
+		}
+	}
+	p.mu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+	r.mu.RLock()
+	callbacks := r.onChange
+	r.mu.RUnlock()
+	for _, ev := range events {
+		for _, fn := range callbacks {
+			fn(ev)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package healthcheck
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestOnStateChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []bool
+		want    []Event
+	}{
+		{"first result is not a change", []bool{false}, nil},
+		{"steady", []bool{true, true, true}, nil},
+		{"goes down", []bool{true, false}, []Event{{From: StatusUp, To: StatusDown, Error: "failing"}}},
+		{"flaps", []bool{false, true, false}, []Event{
+			{From: StatusDown, To: StatusUp},
+			{From: StatusUp, To: StatusDown, Error: "failing"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var got []Event
+			var runs atomic.Int32
+			var fail atomic.Bool
+			r := New().AddLiveness("dep", counting(&runs, &fail)).OnStateChange(func(ev Event) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, ev)
+			})
+			for _, ok := range tt.results {
+				fail.Store(!ok)
+				r.Liveness(context.Background())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %+v, want %+v", got, tt.want)
+			}
+			for i, want := range tt.want {
+				ev := got[i]
+				if ev.Probe != "liveness" || ev.Check != "dep" || ev.From != want.From || ev.To != want.To || ev.Error != want.Error {
+					t.Errorf("event %d = %+v, want %+v", i, ev, want)
+				}
+				if ev.Time.IsZero() {
+					t.Errorf("event %d has no time", i)
+				}
+			}
+		})
+	}
+}
+
+func TestOnStateChangeCallsEveryCallback(t *testing.T) {
+	var fail atomic.Bool
+	var runs, calls atomic.Int32
+	r := New().AddReadiness("dep", counting(&runs, &fail))
+	for i := 0; i < 3; i++ {
+		r.OnStateChange(func(Event) { calls.Add(1) })
+	}
+	r.Readiness(context.Background())
+	fail.Store(true)
+	r.Readiness(context.Background())
+	if n := calls.Load(); n != 3 {
+		t.Errorf("%d callbacks ran, want 3", n)
+	}
+}
+
+func TestCheckMetrics(t *testing.T) {
+	var fail atomic.Bool
+	var runs atomic.Int32
+	r := New().AddStartup("metrics-check", counting(&runs, &fail))
+	transitions := func(status Status) float64 {
+		return testutil.ToFloat64(checkTransitions.WithLabelValues("startup", "metrics-check", string(status)))
+	}
+	status := func() float64 {
+		return testutil.ToFloat64(checkStatus.WithLabelValues("startup", "metrics-check"))
+	}
+
+	fail.Store(true)
+	r.Startup(context.Background())
+	if status() != 0 || transitions(StatusDown) != 0 {
+		t.Errorf("after first failure: status %v, down transitions %v", status(), transitions(StatusDown))
+	}
+	fail.Store(false)
+	r.Startup(context.Background())
+	if status() != 1 || transitions(StatusUp) != 1 {
+		t.Errorf("after recovery: status %v, up transitions %v", status(), transitions(StatusUp))
+	}
+	if n := testutil.CollectAndCount(checkDuration, "healthcheck_duration_seconds"); n == 0 {
+		t.Error("no latency observed")
+	}
+}
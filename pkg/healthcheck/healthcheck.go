@@ -1,17 +1,59 @@
+// Package healthcheck serves Kubernetes-style readiness and liveness probes.
 package healthcheck
 
 import (
-	"github.com/gin-gonic/gin"
 	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
 )
 
+// notReady holds the reason readiness is forced down, or nil.
+var notReady atomic.Pointer[string]
+
+// SetReady forces readiness probes of this process down when false,
+// whatever their checks report, and releases them again when true.
+// Liveness is not affected.
+func SetReady(ready bool) {
+	if ready {
+		notReady.Store(nil)
+		return
+	}
+	reason := "marked not ready"
+	notReady.Store(&reason)
+}
+
+// Shutdown marks the process as draining: readiness answers 503 from now
+// on so load balancers stop routing to it, while liveness keeps answering
+// 200 so it is not killed before in-flight requests complete. server.Start
+// calls it when a termination signal is received.
+func Shutdown() {
+	reason := "shutting down"
+	notReady.Store(&reason)
+}
+
+// Ready reports whether readiness is not forced down by SetReady or
+// Shutdown.
+func Ready() bool {
+	return notReady.Load() == nil
+}
+
 // healthCheckHandler responds with the health status of the application.
 func healthCheckHandler(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// readinessHandler responds 503 while readiness is forced down.
+func readinessHandler(c *gin.Context) {
+	if !Ready() {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
 // Register sets up health check endpoints on the provided router.
 func Register(router *gin.Engine) {
-	router.GET("/healthz/readiness", healthCheckHandler)
+	router.GET("/healthz/readiness", readinessHandler)
 	router.GET("/healthz/liveness", healthCheckHandler)
 }
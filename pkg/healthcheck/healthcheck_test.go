@@ -0,0 +1,76 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadinessToggle(t *testing.T) {
+	tests := []struct {
+		name       string
+		toggle     func()
+		wantReady  bool
+		wantReason string
+	}{
+		{"ready", func() { SetReady(true) }, true, ""},
+		{"marked not ready", func() { SetReady(false) }, false, "marked not ready"},
+		{"shutting down", Shutdown, false, "shutting down"},
+		{"ready again", func() { Shutdown(); SetReady(true) }, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { SetReady(true) })
+			var runs atomic.Int32
+			r := New().AddReadiness("db", counting(&runs, nil)).AddLiveness("loop", up)
+			legacy := gin.New()
+			Register(legacy)
+			tt.toggle()
+
+			if Ready() != tt.wantReady {
+				t.Errorf("Ready() = %v", Ready())
+			}
+			report := r.Readiness(context.Background())
+			if (report.Status == StatusUp) != tt.wantReady || report.Reason != tt.wantReason {
+				t.Errorf("readiness = %+v", report)
+			}
+			if !tt.wantReady && runs.Load() != 0 {
+				t.Error("checks ran while readiness was forced down")
+			}
+			if got := r.Liveness(context.Background()).Status; got != StatusUp {
+				t.Errorf("liveness = %s, want it unaffected", got)
+			}
+
+			wantCode := http.StatusOK
+			if !tt.wantReady {
+				wantCode = http.StatusServiceUnavailable
+			}
+			w := serveProbe(handlerFor(r), http.MethodGet, "/healthz/readiness")
+			if w.Code != wantCode {
+				t.Errorf("readiness answered %d, want %d", w.Code, wantCode)
+			}
+			if reason, _ := decodeReport(t, w)["reason"].(string); reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if w := serveProbe(legacy, http.MethodGet, "/healthz/readiness"); w.Code != wantCode {
+				t.Errorf("legacy readiness answered %d, want %d", w.Code, wantCode)
+			}
+			if w := serveProbe(legacy, http.MethodGet, "/healthz/liveness"); w.Code != http.StatusOK {
+				t.Errorf("legacy liveness answered %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestReadinessToggleOverridesLatch(t *testing.T) {
+	t.Cleanup(func() { SetReady(true) })
+	r := New().LatchReadiness().AddReadiness("db", up)
+	r.Readiness(context.Background())
+	SetReady(false)
+	if got := r.Readiness(context.Background()); got.Status != StatusDown {
+		t.Errorf("latched readiness = %+v, want it forced down", got)
+	}
+}
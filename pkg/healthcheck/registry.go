@@ -0,0 +1,417 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultTimeout bounds each check, matching the kubelet's default probe
+// timeout.
+const DefaultTimeout = time.Second
+
+// DefaultInterval is used by Start for intervals that are not positive,
+// matching the kubelet's default probe period.
+const DefaultInterval = 10 * time.Second
+
+// HealthCheckFunc reports a dependency as healthy by returning nil. It
+// should give up when ctx is done.
+type HealthCheckFunc func(ctx context.Context) error
+
+// Simple adapts a check without context support. The timeout is still
+// enforced, but fn keeps running in the background until it returns.
+func Simple(fn func() error) HealthCheckFunc {
+	return func(context.Context) error { return fn() }
+}
+
+// CheckOption configures a single check.
+type CheckOption func(*check)
+
+// Timeout overrides the registry's timeout for one check.
+func Timeout(d time.Duration) CheckOption {
+	return func(ch *check) { ch.timeout = d }
+}
+
+// Status is the outcome of a check or a whole probe.
+type Status string
+
+// Check statuses.
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// CheckResult is the outcome of a single named check.
+type CheckResult struct {
+	Name    string
+	Status  Status
+	Latency time.Duration
+	Error   string
+}
+
+// MarshalJSON renders Latency as a duration string such as "1.2ms".
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name    string `json:"name"`
+		Status  Status `json:"status"`
+		Latency string `json:"latency"`
+		Error   string `json:"error,omitempty"`
+	}{r.Name, r.Status, r.Latency.String(), r.Error})
+}
+
+// Report is the response body of a probe. Status is down when any check
+// is down.
+type Report struct {
+	Status Status        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+	// Time is when the checks ran, which for cached reports may be up to
+	// one interval ago.
+	Time time.Time `json:"time"`
+	// Latched is set when the probe stays up after its first success and
+	// the checks were not run.
+	Latched bool `json:"latched,omitempty"`
+	// Reason explains why readiness was forced down by SetReady or
+	// Shutdown.
+	Reason string `json:"reason,omitempty"`
+}
+
+type check struct {
+	name    string
+	fn      HealthCheckFunc
+	timeout time.Duration
+}
+
+// probe is a set of checks and, while Start runs, their latest report.
+// A latching probe stops running its checks after the first success.
+type probe struct {
+	name    string
+	checks  []check
+	cached  atomic.Pointer[Report]
+	latch   bool
+	latched atomic.Bool
+
+	// mu guards last, the previous status of each check.
+	mu   sync.Mutex
+	last map[string]Status
+}
+
+// Registry holds named startup, readiness and liveness checks. It is safe
+// for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	timeout   time.Duration
+	startup   probe
+	readiness probe
+	liveness  probe
+	onChange  []func(Event)
+	terse     bool
+}
+
+// New returns an empty Registry; probes without checks are always up.
+func New() *Registry {
+	r := &Registry{timeout: DefaultTimeout}
+	r.startup.name, r.readiness.name, r.liveness.name = "startup", "readiness", "liveness"
+	r.startup.latch = true
+	return r
+}
+
+// WithTimeout sets the timeout of checks added without their own Timeout.
+func (r *Registry) WithTimeout(d time.Duration) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeout = d
+	return r
+}
+
+// AddStartup adds a check that must pass once before the service is
+// considered started. Like a Kubernetes startupProbe, the startup probe
+// stays up after its first success.
+func (r *Registry) AddStartup(name string, fn HealthCheckFunc, opts ...CheckOption) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.startup.checks = append(r.startup.checks, newCheck(name, fn, opts))
+	return r
+}
+
+// LatchReadiness makes readiness stay up after its first success, until
+// ResetReadiness is called, so transient dependency failures do not take
+// an already serving instance out of the load balancer.
+func (r *Registry) LatchReadiness() *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness.latch = true
+	return r
+}
+
+// Terse makes probes answer with a bare "up" or "down" instead of the
+// Report, so check names and errors are not exposed to anyone who can
+// reach them. Serve the full reports with RegisterDetails.
+func (r *Registry) Terse() *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.terse = true
+	return r
+}
+
+// ResetReadiness clears a latched readiness so it reflects the checks
+// again until they next succeed.
+func (r *Registry) ResetReadiness() {
+	r.readiness.latched.Store(false)
+}
+
+// AddReadiness adds a check that must pass for the service to receive
+// traffic.
+func (r *Registry) AddReadiness(name string, fn HealthCheckFunc, opts ...CheckOption) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness.checks = append(r.readiness.checks, newCheck(name, fn, opts))
+	return r
+}
+
+// AddLiveness adds a check whose failure means the process should be
+// restarted.
+func (r *Registry) AddLiveness(name string, fn HealthCheckFunc, opts ...CheckOption) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness.checks = append(r.liveness.checks, newCheck(name, fn, opts))
+	return r
+}
+
+func newCheck(name string, fn HealthCheckFunc, opts []CheckOption) check {
+	ch := check{name: name, fn: fn}
+	for _, opt := range opts {
+		opt(&ch)
+	}
+	return ch
+}
+
+// Startup returns the startup report. Once up, it stays up.
+func (r *Registry) Startup(ctx context.Context) Report {
+	return r.report(ctx, &r.startup)
+}
+
+// Readiness returns the cached readiness report while Start runs, and
+// otherwise runs the readiness checks concurrently. While readiness is
+// forced down by SetReady or Shutdown no checks are run.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	if reason := notReady.Load(); reason != nil {
+		return Report{Status: StatusDown, Checks: []CheckResult{}, Time: time.Now(), Reason: *reason}
+	}
+	return r.report(ctx, &r.readiness)
+}
+
+// Liveness returns the cached liveness report while Start runs, and
+// otherwise runs the liveness checks concurrently.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.report(ctx, &r.liveness)
+}
+
+func (r *Registry) report(ctx context.Context, p *probe) Report {
+	if p.latched.Load() {
+		return latchedReport()
+	}
+	if cached := p.cached.Load(); cached != nil {
+		return *cached
+	}
+	return r.evaluate(ctx, p)
+}
+
+func (r *Registry) evaluate(ctx context.Context, p *probe) Report {
+	if p.latched.Load() {
+		return latchedReport()
+	}
+	r.mu.RLock()
+	checks, timeout, latch := p.checks, r.timeout, p.latch
+	r.mu.RUnlock()
+	report := run(ctx, checks, timeout)
+	r.observe(p, report)
+	if latch && report.Status == StatusUp {
+		p.latched.Store(true)
+	}
+	return report
+}
+
+func latchedReport() Report {
+	return Report{Status: StatusUp, Checks: []CheckResult{}, Time: time.Now(), Latched: true}
+}
+
+// Start evaluates all checks every interval in the background until ctx is
+// done, so probes are answered from the latest result instead of hitting
+// the dependencies on every request. Probes run the checks themselves
+// until the first evaluation completes and again after ctx is done. The
+// probes are evaluated concurrently, so a slow one does not delay the
+// others.
+func (r *Registry) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	probes := []*probe{&r.startup, &r.readiness, &r.liveness}
+	refresh := func() {
+		var wg sync.WaitGroup
+		for _, p := range probes {
+			wg.Add(1)
+			go func(p *probe) {
+				defer wg.Done()
+				report := r.evaluate(ctx, p)
+				if ctx.Err() == nil {
+					p.cached.Store(&report)
+				}
+			}(p)
+		}
+		wg.Wait()
+	}
+	go func() {
+		defer func() {
+			for _, p := range probes {
+				p.cached.Store(nil)
+			}
+		}()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			refresh()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Register mounts /healthz/startup, /healthz/readiness and
+// /healthz/liveness. Probes answer 200 when up and 503 when down, with the
+// Report as body. They serve GET and HEAD and answer 405 to other methods.
+func (r *Registry) Register(router gin.IRoutes) {
+	router.Any("/healthz/startup", gin.WrapF(r.reportHandler(r.Startup)))
+	router.Any("/healthz/readiness", gin.WrapF(r.reportHandler(r.Readiness)))
+	router.Any("/healthz/liveness", gin.WrapF(r.reportHandler(r.Liveness)))
+}
+
+// RegisterMux mounts the probes of Register on a standard library mux.
+func (r *Registry) RegisterMux(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz/startup", r.reportHandler(r.Startup))
+	mux.HandleFunc("/healthz/readiness", r.reportHandler(r.Readiness))
+	mux.HandleFunc("/healthz/liveness", r.reportHandler(r.Liveness))
+}
+
+// Handler returns an http.Handler serving the probes of Register, for
+// servers that do not use Gin.
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	r.RegisterMux(mux)
+	return mux
+}
+
+func (r *Registry) reportHandler(probe func(context.Context) Report) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !allowRead(w, req) {
+			return
+		}
+		report := probe(req.Context())
+		status := http.StatusOK
+		if report.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		r.mu.RLock()
+		terse := r.terse
+		r.mu.RUnlock()
+		if terse {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(status)
+			io.WriteString(w, string(report.Status))
+			return
+		}
+		writeJSON(w, status, report)
+	}
+}
+
+// allowRead answers 405 to methods other than GET and HEAD.
+func allowRead(w http.ResponseWriter, req *http.Request) bool {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func run(ctx context.Context, checks []check, timeout time.Duration) Report {
+	report := Report{Status: StatusUp, Checks: make([]CheckResult, len(checks)), Time: time.Now()}
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch check) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, ch, timeout)
+		}(i, ch)
+	}
+	wg.Wait()
+	for _, res := range report.Checks {
+		if res.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, ch check, timeout time.Duration) CheckResult {
+	if ch.timeout > 0 {
+		timeout = ch.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- ch.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Checks ignoring ctx are abandoned rather than waited for.
+		err = ctx.Err()
+	}
+	res := CheckResult{Name: ch.name, Status: StatusUp, Latency: time.Since(start)}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil:
+		res.Status = StatusDown
+		res.Error = fmt.Sprintf("timed out after %s", timeout)
+	case err != nil:
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
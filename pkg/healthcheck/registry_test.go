@@ -0,0 +1,489 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// up and down are checks with a fixed result.
+func up(context.Context) error   { return nil }
+func down(context.Context) error { return errors.New("connection refused") }
+
+// serveProbe requests path from h.
+func serveProbe(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+// decodeReport decodes a probe response body.
+func decodeReport(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%v: %s", err, w.Body.String())
+	}
+	return body
+}
+
+func TestRegistryReports(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]HealthCheckFunc
+		wantStatus Status
+		wantDown   []string
+	}{
+		{"no checks", nil, StatusUp, nil},
+		{"all up", map[string]HealthCheckFunc{"db": up, "cache": up}, StatusUp, nil},
+		{"one down", map[string]HealthCheckFunc{"db": up, "cache": down}, StatusDown, []string{"cache"}},
+		{"all down", map[string]HealthCheckFunc{"db": down, "cache": down}, StatusDown, []string{"cache", "db"}},
+		{"panic", map[string]HealthCheckFunc{"db": func(context.Context) error { panic("boom") }}, StatusDown, []string{"db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New()
+			for name, fn := range tt.checks {
+				r.AddReadiness(name, fn)
+			}
+			report := r.Readiness(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("%d results for %d checks", len(report.Checks), len(tt.checks))
+			}
+			var gotDown []string
+			for _, res := range report.Checks {
+				if res.Status == StatusDown {
+					gotDown = append(gotDown, res.Name)
+					if res.Error == "" {
+						t.Errorf("%s is down without an error", res.Name)
+					}
+				}
+			}
+			sort.Strings(gotDown)
+			if strings.Join(gotDown, ",") != strings.Join(tt.wantDown, ",") {
+				t.Errorf("down checks = %v, want %v", gotDown, tt.wantDown)
+			}
+		})
+	}
+}
+
+func TestRegistryKeepsCheckOrder(t *testing.T) {
+	r := New().AddLiveness("b", up).AddLiveness("a", down).AddLiveness("c", up)
+	var names []string
+	for _, res := range r.Liveness(context.Background()).Checks {
+		names = append(names, res.Name)
+	}
+	if strings.Join(names, ",") != "b,a,c" {
+		t.Errorf("check order = %v", names)
+	}
+}
+
+func TestProbeSeparation(t *testing.T) {
+	r := New().AddReadiness("db", down).AddLiveness("loop", up)
+	if got := r.Liveness(context.Background()).Status; got != StatusUp {
+		t.Errorf("liveness = %s, readiness checks leaked into it", got)
+	}
+	if got := r.Readiness(context.Background()).Status; got != StatusDown {
+		t.Errorf("readiness = %s", got)
+	}
+}
+
+func TestRegisterResponses(t *testing.T) {
+	router := gin.New()
+	New().AddReadiness("db", down).AddLiveness("loop", up).Register(router)
+
+	tests := []struct {
+		method, path string
+		wantCode     int
+		wantStatus   string
+	}{
+		{http.MethodGet, "/healthz/liveness", http.StatusOK, "up"},
+		{http.MethodGet, "/healthz/readiness", http.StatusServiceUnavailable, "down"},
+		{http.MethodGet, "/healthz/startup", http.StatusOK, "up"},
+		{http.MethodHead, "/healthz/liveness", http.StatusOK, ""},
+		{http.MethodPost, "/healthz/liveness", http.StatusMethodNotAllowed, ""},
+		{http.MethodDelete, "/healthz/readiness", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := serveProbe(router, tt.method, tt.path)
+			if w.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if w.Code == http.StatusMethodNotAllowed {
+				if got := w.Header().Get("Allow"); got != "GET, HEAD" {
+					t.Errorf("Allow = %q", got)
+				}
+				return
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q", got)
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+			body := decodeReport(t, w)
+			if body["status"] != tt.wantStatus {
+				t.Errorf("status = %v, want %s", body["status"], tt.wantStatus)
+			}
+			if _, ok := body["checks"].([]interface{}); !ok {
+				t.Errorf("checks is not a list: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCheckResultJSON(t *testing.T) {
+	w := serveProbe(handlerFor(New().AddReadiness("db", down)), http.MethodGet, "/healthz/readiness")
+	checks := decodeReport(t, w)["checks"].([]interface{})
+	res := checks[0].(map[string]interface{})
+	if res["name"] != "db" || res["status"] != "down" || res["error"] != "connection refused" {
+		t.Errorf("check = %v", res)
+	}
+	if latency, _ := res["latency"].(string); !strings.HasSuffix(latency, "s") {
+		t.Errorf("latency = %v, want a duration string", res["latency"])
+	}
+}
+
+// handlerFor mounts r on a Gin router.
+func handlerFor(r *Registry) http.Handler {
+	router := gin.New()
+	r.Register(router)
+	return router
+}
+
+func TestCheckTimeouts(t *testing.T) {
+	// blocking waits for ctx; ignoring never looks at it.
+	blocking := func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	ignoring := Simple(func() error { <-release; return nil })
+
+	tests := []struct {
+		name      string
+		registry  *Registry
+		fn        HealthCheckFunc
+		opts      []CheckOption
+		wantError string
+	}{
+		{"registry timeout", New().WithTimeout(20 * time.Millisecond), blocking, nil, "timed out after 20ms"},
+		{"check timeout overrides", New().WithTimeout(time.Hour), blocking, []CheckOption{Timeout(10 * time.Millisecond)}, "timed out after 10ms"},
+		{"check ignoring ctx is abandoned", New().WithTimeout(10 * time.Millisecond), ignoring, nil, "timed out after 10ms"},
+		{"fast check", New().WithTimeout(time.Second), up, nil, ""},
+		{"error wrapping a deadline", New(), func(context.Context) error {
+			return fmt.Errorf("dial: %w", context.DeadlineExceeded)
+		}, nil, "dial: context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			report := tt.registry.AddReadiness("dep", tt.fn, tt.opts...).Readiness(context.Background())
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("probe took %s", elapsed)
+			}
+			if got := report.Checks[0].Error; got != tt.wantError {
+				t.Errorf("Error = %q, want %q", got, tt.wantError)
+			}
+			if wantUp := tt.wantError == ""; (report.Status == StatusUp) != wantUp {
+				t.Errorf("Status = %s", report.Status)
+			}
+		})
+	}
+}
+
+func TestCheckContextPropagation(t *testing.T) {
+	type key struct{}
+	var got interface{}
+	var deadline bool
+	r := New().AddReadiness("dep", func(ctx context.Context) error {
+		got = ctx.Value(key{})
+		_, deadline = ctx.Deadline()
+		return nil
+	})
+	r.Readiness(context.WithValue(context.Background(), key{}, "request"))
+	if got != "request" {
+		t.Errorf("check context value = %v, want the probe's", got)
+	}
+	if !deadline {
+		t.Error("check context has no deadline")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := New().AddReadiness("dep", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}).Readiness(ctx)
+	if report.Status != StatusDown || report.Checks[0].Error != context.Canceled.Error() {
+		t.Errorf("canceled probe = %+v", report.Checks[0])
+	}
+}
+
+func TestChecksRunConcurrently(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		return nil
+	}
+	r := New()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		r.AddLiveness(name, slow)
+	}
+	start := time.Now()
+	r.Liveness(context.Background())
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("four 50ms checks took %s", elapsed)
+	}
+}
+
+// counting returns a check that counts its runs and fails while *fail is
+// set.
+func counting(runs *atomic.Int32, fail *atomic.Bool) HealthCheckFunc {
+	return func(context.Context) error {
+		runs.Add(1)
+		if fail != nil && fail.Load() {
+			return errors.New("failing")
+		}
+		return nil
+	}
+}
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStartServesCachedReports(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"interval", time.Hour},
+		{"default interval", 0},
+		{"negative interval", -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int32
+			r := New().AddReadiness("db", counting(&runs, nil))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r.Start(ctx, tt.interval)
+			waitFor(t, "first evaluation", func() bool { return r.readiness.cached.Load() != nil })
+
+			first := r.Readiness(context.Background())
+			for i := 0; i < 5; i++ {
+				if got := r.Readiness(context.Background()); !got.Time.Equal(first.Time) {
+					t.Fatal("probe did not answer from the cache")
+				}
+			}
+			if n := runs.Load(); n != 1 {
+				t.Errorf("check ran %d times, want once per interval", n)
+			}
+
+			cancel()
+			waitFor(t, "cache to be cleared", func() bool { return r.readiness.cached.Load() == nil })
+			r.Readiness(context.Background())
+			if n := runs.Load(); n != 2 {
+				t.Errorf("check ran %d times, want a direct run after Start stopped", n)
+			}
+		})
+	}
+}
+
+func TestStartRefreshes(t *testing.T) {
+	var runs atomic.Int32
+	var fail atomic.Bool
+	r := New().AddReadiness("db", counting(&runs, &fail))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx, 5*time.Millisecond)
+	waitFor(t, "first evaluation", func() bool { return r.readiness.cached.Load() != nil })
+
+	fail.Store(true)
+	waitFor(t, "cached report to go down", func() bool {
+		return r.Readiness(context.Background()).Status == StatusDown
+	})
+	if w := serveProbe(handlerFor(r), http.MethodGet, "/healthz/readiness"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("cached down report answered %d", w.Code)
+	}
+}
+
+func TestStartEvaluatesProbesIndependently(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	r := New().
+		AddReadiness("slow", func(ctx context.Context) error {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return nil
+		}, Timeout(time.Hour)).
+		AddLiveness("fast", up)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Start(ctx, time.Hour)
+	waitFor(t, "liveness evaluation", func() bool { return r.liveness.cached.Load() != nil })
+	if r.readiness.cached.Load() != nil {
+		t.Error("readiness finished before its check was released")
+	}
+}
+
+func TestLatching(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup adds fn to the probe under test and returns its report.
+		setup func(r *Registry, fn HealthCheckFunc) func(context.Context) Report
+		latch bool
+	}{
+		{"startup", func(r *Registry, fn HealthCheckFunc) func(context.Context) Report {
+			r.AddStartup("migrations", fn)
+			return r.Startup
+		}, true},
+		{"readiness", func(r *Registry, fn HealthCheckFunc) func(context.Context) Report {
+			r.AddReadiness("db", fn)
+			return r.Readiness
+		}, false},
+		{"latched readiness", func(r *Registry, fn HealthCheckFunc) func(context.Context) Report {
+			r.LatchReadiness().AddReadiness("db", fn)
+			return r.Readiness
+		}, true},
+		{"liveness", func(r *Registry, fn HealthCheckFunc) func(context.Context) Report {
+			r.AddLiveness("loop", fn)
+			return r.Liveness
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int32
+			var fail atomic.Bool
+			fail.Store(true)
+			report := tt.setup(New(), counting(&runs, &fail))
+			ctx := context.Background()
+
+			if got := report(ctx); got.Status != StatusDown || got.Latched {
+				t.Fatalf("failing probe = %+v, a failure must not latch", got)
+			}
+			fail.Store(false)
+			if got := report(ctx); got.Status != StatusUp || got.Latched {
+				t.Fatalf("first success = %+v", got)
+			}
+			fail.Store(true)
+			got := report(ctx)
+			if tt.latch {
+				if got.Status != StatusUp || !got.Latched || len(got.Checks) != 0 {
+					t.Errorf("report after success = %+v, want latched", got)
+				}
+				if n := runs.Load(); n != 2 {
+					t.Errorf("checks ran %d times after latching", n-2)
+				}
+			} else if got.Status != StatusDown {
+				t.Errorf("report after success = %+v, want the failure", got)
+			}
+		})
+	}
+}
+
+func TestResetReadiness(t *testing.T) {
+	var runs atomic.Int32
+	var fail atomic.Bool
+	r := New().LatchReadiness().AddReadiness("db", counting(&runs, &fail))
+	ctx := context.Background()
+	r.Readiness(ctx)
+
+	fail.Store(true)
+	r.ResetReadiness()
+	if got := r.Readiness(ctx); got.Status != StatusDown || got.Latched {
+		t.Errorf("report after reset = %+v, want the checks' result", got)
+	}
+	fail.Store(false)
+	r.Readiness(ctx)
+	fail.Store(true)
+	if got := r.Readiness(ctx); !got.Latched {
+		t.Errorf("readiness did not latch again: %+v", got)
+	}
+}
+
+func TestStartupEndpoint(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	var runs atomic.Int32
+	h := handlerFor(New().AddStartup("warmup", counting(&runs, &fail)))
+
+	if w := serveProbe(h, http.MethodGet, "/healthz/startup"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unstarted probe answered %d", w.Code)
+	}
+	fail.Store(false)
+	serveProbe(h, http.MethodGet, "/healthz/startup")
+	fail.Store(true)
+	w := serveProbe(h, http.MethodGet, "/healthz/startup")
+	if w.Code != http.StatusOK || decodeReport(t, w)["latched"] != true {
+		t.Errorf("started probe answered %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStdlibRegistration(t *testing.T) {
+	handlers := map[string]func(r *Registry) http.Handler{
+		"gin": handlerFor,
+		"RegisterMux": func(r *Registry) http.Handler {
+			mux := http.NewServeMux()
+			r.RegisterMux(mux)
+			return mux
+		},
+		"Handler": (*Registry).Handler,
+	}
+	tests := []struct {
+		method, path string
+		wantCode     int
+	}{
+		{http.MethodGet, "/healthz/startup", http.StatusOK},
+		{http.MethodGet, "/healthz/readiness", http.StatusServiceUnavailable},
+		{http.MethodGet, "/healthz/liveness", http.StatusOK},
+		{http.MethodHead, "/healthz/readiness", http.StatusServiceUnavailable},
+		{http.MethodPut, "/healthz/liveness", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/healthz/unknown", http.StatusNotFound},
+	}
+	for name, newHandler := range handlers {
+		h := newHandler(New().AddReadiness("db", down).AddLiveness("loop", up))
+		for _, tt := range tests {
+			t.Run(name+" "+tt.method+" "+tt.path, func(t *testing.T) {
+				w := serveProbe(h, tt.method, tt.path)
+				if w.Code != tt.wantCode {
+					t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+				}
+				if tt.method == http.MethodGet && w.Code != http.StatusNotFound {
+					if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+						t.Errorf("Content-Type = %q", ct)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package httputil
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETag returns a strong entity tag for content.
+func ETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns a weak entity tag for content, for representations
+// that are equivalent but not byte-identical, e.g. after compression.
+func WeakETag(content []byte) string {
+	return "W/" + ETag(content)
+}
+
+// ETagMatch reports whether the If-None-Match header value header lists
+// etag or is "*". Tags are compared weakly, ignoring W/.
+func ETagMatch(header, etag string) bool {
+	return etagMatch(header, etag, false)
+}
+
+// ETagMatchStrong reports whether the If-Match header value header lists
+// etag or is "*". Tags are compared strongly, so weak tags never match.
+func ETagMatchStrong(header, etag string) bool {
+	return etagMatch(header, etag, true)
+}
+
+func etagMatch(header, etag string, strong bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || etag == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strong && strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(tag, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheControl describes a Cache-Control response policy.
+type CacheControl struct {
+	Public         bool
+	Private        bool
+	NoCache        bool `yaml:"noCache"`
+	NoStore        bool `yaml:"noStore"`
+	MustRevalidate bool `yaml:"mustRevalidate"`
+	Immutable      bool
+	// MaxAge is rounded down to whole seconds; zero omits max-age unless
+	// MustRevalidate is set.
+	MaxAge time.Duration `yaml:"maxAge"`
+	// SharedMaxAge sets s-maxage for shared caches such as CDNs.
+	SharedMaxAge         time.Duration `yaml:"sharedMaxAge"`
+	StaleWhileRevalidate time.Duration `yaml:"staleWhileRevalidate"`
+	StaleIfError         time.Duration `yaml:"staleIfError"`
+}
+
+// String returns the Cache-Control header value.
+func (cc CacheControl) String() string {
+	var parts []string
+	flag := func(set bool, name string) {
+		if set {
+			parts = append(parts, name)
+		}
+	}
+	seconds := func(d time.Duration, name string) {
+		if d > 0 {
+			parts = append(parts, name+"="+strconv.FormatInt(int64(d/time.Second), 10))
+		}
+	}
+	flag(cc.Public, "public")
+	flag(cc.Private, "private")
+	flag(cc.NoCache, "no-cache")
+	flag(cc.NoStore, "no-store")
+	if cc.MaxAge > 0 || cc.MustRevalidate {
+		parts = append(parts, "max-age="+strconv.FormatInt(int64(cc.MaxAge/time.Second), 10))
+	}
+	seconds(cc.SharedMaxAge, "s-maxage")
+	flag(cc.MustRevalidate, "must-revalidate")
+	flag(cc.Immutable, "immutable")
+	seconds(cc.StaleWhileRevalidate, "stale-while-revalidate")
+	seconds(cc.StaleIfError, "stale-if-error")
+	return strings.Join(parts, ", ")
+}
+
+// Apply sets the Cache-Control header of w.
+func (cc CacheControl) Apply(w http.ResponseWriter) {
+	if v := cc.String(); v != "" {
+		w.Header().Set("Cache-Control", v)
+	}
+}
+
+// CheckNotModified sets the ETag and Last-Modified headers of w, either of
+// which may be empty, and evaluates the conditional headers of r against
+// them. It returns true after answering 304 Not Modified or 412
+// Precondition Failed, in which case the handler must not write a body:
+//
+//	if httputil.CheckNotModified(w, r, etag, order.UpdatedAt) {
+//		return
+//	}
+//
+// The headers are evaluated in the order RFC 9110 prescribes: If-Match,
+// compared strongly, or else If-Unmodified-Since answer 412 when they fail;
+// a matching If-None-Match answers 304 for GET and HEAD and 412 otherwise;
+// without If-None-Match, If-Modified-Since answers 304 for GET and HEAD.
+// An empty etag is taken to mean the resource does not exist.
+func CheckNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	h := w.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		h.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !ETagMatchStrong(im, etag) {
+			WriteProblem(w, NewProblem(http.StatusPreconditionFailed, "resource does not match If-Match"))
+			return true
+		}
+	} else if modifiedSince(r.Header.Get("If-Unmodified-Since"), lastModified) {
+		WriteProblem(w, NewProblem(http.StatusPreconditionFailed, "resource modified since If-Unmodified-Since"))
+		return true
+	}
+
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !ETagMatch(inm, etag) {
+			return false
+		}
+		if !safe {
+			WriteProblem(w, NewProblem(http.StatusPreconditionFailed, "resource matches If-None-Match"))
+			return true
+		}
+		writeNotModified(w)
+		return true
+	}
+	if !safe {
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(ims) {
+		return false
+	}
+	writeNotModified(w)
+	return true
+}
+
+// modifiedSince reports whether lastModified is after the HTTP date header.
+// Missing or invalid dates and an unknown lastModified report false.
+func modifiedSince(header string, lastModified time.Time) bool {
+	if header == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(header)
+	return err == nil && lastModified.Truncate(time.Second).After(since)
+}
+
+// writeNotModified answers 304 without the headers describing a body.
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	delete(h, "Content-Type")
+	delete(h, "Content-Length")
+	delete(h, "Content-Encoding")
+	w.WriteHeader(http.StatusNotModified)
+}
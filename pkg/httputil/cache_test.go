@@ -0,0 +1,168 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	a, b := ETag([]byte("a")), ETag([]byte("b"))
+	if a == b || a != ETag([]byte("a")) {
+		t.Errorf("ETag is not a stable digest: %s %s", a, b)
+	}
+	if !strings.HasPrefix(a, `"`) || !strings.HasSuffix(a, `"`) {
+		t.Errorf("ETag %s is not quoted", a)
+	}
+	if w := WeakETag([]byte("a")); w != "W/"+a {
+		t.Errorf("WeakETag = %s", w)
+	}
+}
+
+func TestETagMatch(t *testing.T) {
+	tests := []struct {
+		header, etag string
+		weak, strong bool
+	}{
+		{`"a"`, `"a"`, true, true},
+		{`"b", "a"`, `"a"`, true, true},
+		{` "b" ,"a" `, `"a"`, true, true},
+		{`*`, `"a"`, true, true},
+		{`*`, ``, false, false},
+		{`W/"a"`, `"a"`, true, false},
+		{`"a"`, `W/"a"`, true, false},
+		{`W/"a"`, `W/"a"`, true, false},
+		{`"b"`, `"a"`, false, false},
+		{`"a"`, ``, false, false},
+		{``, `"a"`, false, false},
+		{`a`, `"a"`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header+" "+tt.etag, func(t *testing.T) {
+			if got := ETagMatch(tt.header, tt.etag); got != tt.weak {
+				t.Errorf("ETagMatch() = %v, want %v", got, tt.weak)
+			}
+			if got := ETagMatchStrong(tt.header, tt.etag); got != tt.strong {
+				t.Errorf("ETagMatchStrong() = %v, want %v", got, tt.strong)
+			}
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		name string
+		cc   CacheControl
+		want string
+	}{
+		{"empty", CacheControl{}, ""},
+		{"public max-age", CacheControl{Public: true, MaxAge: 90 * time.Second}, "public, max-age=90"},
+		{"rounded down", CacheControl{MaxAge: 1500 * time.Millisecond}, "max-age=1"},
+		{"must revalidate without max-age", CacheControl{Private: true, MustRevalidate: true}, "private, max-age=0, must-revalidate"},
+		{"no-store", CacheControl{NoCache: true, NoStore: true}, "no-cache, no-store"},
+		{"cdn", CacheControl{Public: true, MaxAge: time.Minute, SharedMaxAge: time.Hour, StaleWhileRevalidate: 30 * time.Second, StaleIfError: time.Hour},
+			"public, max-age=60, s-maxage=3600, stale-while-revalidate=30, stale-if-error=3600"},
+		{"immutable", CacheControl{Public: true, MaxAge: 365 * 24 * time.Hour, Immutable: true}, "public, max-age=31536000, immutable"},
+		{"negative durations omitted", CacheControl{MaxAge: -time.Second, SharedMaxAge: -time.Second}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cc.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			w := httptest.NewRecorder()
+			w.Header().Set("Cache-Control", "previous")
+			tt.cc.Apply(w)
+			want := tt.want
+			if want == "" {
+				want = "previous"
+			}
+			if got := w.Header().Get("Cache-Control"); got != want {
+				t.Errorf("Apply() set %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckNotModified(t *testing.T) {
+	const etag = `"v2"`
+	modified := time.Date(2024, 3, 1, 12, 0, 30, 500, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	at := modified.Format(http.TimeFormat)
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		etag     string
+		wantDone bool
+		wantCode int
+	}{
+		{"unconditional", http.MethodGet, nil, etag, false, 0},
+		{"if-none-match hit", http.MethodGet, map[string]string{"If-None-Match": etag}, etag, true, http.StatusNotModified},
+		{"if-none-match weak hit", http.MethodHead, map[string]string{"If-None-Match": `W/"v2"`}, etag, true, http.StatusNotModified},
+		{"if-none-match miss", http.MethodGet, map[string]string{"If-None-Match": `"v1"`}, etag, false, 0},
+		{"if-none-match hit on write", http.MethodPut, map[string]string{"If-None-Match": "*"}, etag, true, http.StatusPreconditionFailed},
+		{"create only when missing", http.MethodPut, map[string]string{"If-None-Match": "*"}, "", false, 0},
+		{"if-none-match overrides if-modified-since", http.MethodGet, map[string]string{"If-None-Match": `"v1"`, "If-Modified-Since": at}, etag, false, 0},
+		{"if-modified-since unchanged", http.MethodGet, map[string]string{"If-Modified-Since": at}, etag, true, http.StatusNotModified},
+		{"if-modified-since changed", http.MethodGet, map[string]string{"If-Modified-Since": before}, etag, false, 0},
+		{"if-modified-since on write", http.MethodPost, map[string]string{"If-Modified-Since": at}, etag, false, 0},
+		{"if-modified-since invalid", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, etag, false, 0},
+		{"if-match hit", http.MethodPut, map[string]string{"If-Match": etag}, etag, false, 0},
+		{"if-match stale", http.MethodPut, map[string]string{"If-Match": `"v1"`}, etag, true, http.StatusPreconditionFailed},
+		{"if-match weak", http.MethodPut, map[string]string{"If-Match": `W/"v2"`}, etag, true, http.StatusPreconditionFailed},
+		{"if-match missing resource", http.MethodPut, map[string]string{"If-Match": "*"}, "", true, http.StatusPreconditionFailed},
+		{"if-match overrides if-unmodified-since", http.MethodPut, map[string]string{"If-Match": etag, "If-Unmodified-Since": before}, etag, false, 0},
+		{"if-unmodified-since unchanged", http.MethodDelete, map[string]string{"If-Unmodified-Since": at}, etag, false, 0},
+		{"if-unmodified-since changed", http.MethodDelete, map[string]string{"If-Unmodified-Since": before}, etag, true, http.StatusPreconditionFailed},
+		{"if-unmodified-since invalid", http.MethodDelete, map[string]string{"If-Unmodified-Since": "soon"}, etag, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/orders/1", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			w.Header().Set("Content-Type", "application/json")
+			done := CheckNotModified(w, req, tt.etag, modified)
+			if done != tt.wantDone {
+				t.Fatalf("CheckNotModified() = %v, want %v", done, tt.wantDone)
+			}
+			if !done {
+				if w.Body.Len() != 0 || w.Code != http.StatusOK {
+					t.Errorf("response written: %d %q", w.Code, w.Body.String())
+				}
+			} else if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if w.Code == http.StatusNotModified && (w.Body.Len() != 0 || w.Header().Get("Content-Type") != "") {
+				t.Errorf("304 carries a body or Content-Type: %q", w.Header().Get("Content-Type"))
+			}
+			if w.Code == http.StatusPreconditionFailed && w.Header().Get("Content-Type") != ProblemContentType {
+				t.Errorf("412 is not problem details: %q", w.Header().Get("Content-Type"))
+			}
+			if got := w.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %q, want %q", got, tt.etag)
+			}
+			if got := w.Header().Get("Last-Modified"); got != at {
+				t.Errorf("Last-Modified = %q, want %q", got, at)
+			}
+		})
+	}
+}
+
+func TestCheckNotModifiedWithoutLastModified(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	req.Header.Set("If-Modified-Since", time.Now().Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	if CheckNotModified(w, req, "", time.Time{}) {
+		t.Error("answered 304 without a modification time")
+	}
+	if len(w.Header()) != 0 {
+		t.Errorf("headers set: %v", w.Header())
+	}
+}
@@ -0,0 +1,151 @@
+package httputil
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/requestid"
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+)
+
+// ClientConfig configures NewClient. Zero values select the defaults noted
+// on each field.
+type ClientConfig struct {
+	// Timeout bounds a whole request including retries. Defaults to 30s.
+	Timeout time.Duration
+	// DialTimeout defaults to 5s.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout defaults to 5s.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for response headers of each
+	// attempt. Defaults to 10s.
+	ResponseHeaderTimeout time.Duration
+	// IdleConnTimeout defaults to 90s.
+	IdleConnTimeout time.Duration
+	// MaxIdleConns defaults to 100.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost defaults to 10; net/http's default of 2 causes
+	// connection churn under load.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost is unlimited when zero.
+	MaxConnsPerHost int
+
+	// Retry retries idempotent requests, and requests carrying an
+	// Idempotency-Key header, on connection errors, 5xx and 429, and other
+	// requests only when no connection could be made; see
+	// resilience.RetryTransport. Nil disables retries.
+	Retry *resilience.RetryPolicy
+	// Breaker enables a circuit breaker per host, named after the host.
+	// Nil disables circuit breaking. Requests to a host whose breaker is
+	// open fail with resilience.ErrOpen.
+	Breaker *resilience.BreakerConfig
+	// DisableRequestID stops forwarding the request ID of the request
+	// context, as stored by middleware.RequestID or requestid.NewContext,
+	// as X-Request-ID.
+	DisableRequestID bool
+	// WrapTransport wraps the finished transport, e.g. with
+	// otelhttp.NewTransport for tracing.
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+}
+
+// NewClient returns an *http.Client for calls to other services, with
+// bounded timeouts, a tuned connection pool and the optional retries,
+// circuit breaking and request ID propagation of cfg. The oauth2 packages
+// accept it as their HTTP client.
+func NewClient(cfg ClientConfig) *http.Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	var rt http.RoundTripper = newTransport(cfg)
+	if cfg.Retry != nil {
+		rt = &resilience.RetryTransport{Base: rt, Policy: *cfg.Retry}
+	}
+	// The breaker sees the outcome after retries, and an open breaker
+	// fails fast without retrying.
+	if cfg.Breaker != nil {
+		rt = &hostBreakers{cfg: *cfg.Breaker, base: rt, breakers: map[string]*resilience.Breaker{}}
+	}
+	if !cfg.DisableRequestID {
+		rt = requestIDTransport{base: rt}
+	}
+	if cfg.WrapTransport != nil {
+		rt = cfg.WrapTransport(rt)
+	}
+	return &http.Client{Transport: rt, Timeout: cfg.Timeout}
+}
+
+func newTransport(cfg ClientConfig) *http.Transport {
+	orDefault := func(d, def time.Duration) time.Duration {
+		if d <= 0 {
+			return def
+		}
+		return d
+	}
+	maxIdle, maxIdlePerHost := cfg.MaxIdleConns, cfg.MaxIdleConnsPerHost
+	if maxIdle <= 0 {
+		maxIdle = 100
+	}
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = 10
+	}
+	dialer := &net.Dialer{
+		Timeout:   orDefault(cfg.DialTimeout, 5*time.Second),
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   orDefault(cfg.TLSHandshakeTimeout, 5*time.Second),
+		ResponseHeaderTimeout: orDefault(cfg.ResponseHeaderTimeout, 10*time.Second),
+		IdleConnTimeout:       orDefault(cfg.IdleConnTimeout, 90*time.Second),
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          maxIdle,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+	}
+}
+
+// hostBreakers keeps a circuit breaker per host.
+type hostBreakers struct {
+	cfg  resilience.BreakerConfig
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	breakers map[string]*resilience.Breaker
+}
+
+func (t *hostBreakers) RoundTrip(req *http.Request) (*http.Response, error) {
+	return resilience.NewTransport(t.breaker(req.URL.Host), t.base).RoundTrip(req)
+}
+
+func (t *hostBreakers) breaker(host string) *resilience.Breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[host]
+	if !ok {
+		cfg := t.cfg
+		cfg.Name = host
+		b = resilience.NewBreaker(cfg)
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// requestIDTransport forwards the request ID of the request context.
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := requestid.FromContext(req.Context())
+	if id == "" || req.Header.Get(requestid.Header) != "" {
+		return t.base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(req.Context())
+	req.Header.Set(requestid.Header, id)
+	return t.base.RoundTrip(req)
+}
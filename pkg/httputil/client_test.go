@@ -0,0 +1,232 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/requestid"
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+)
+
+func TestClientForwardsRequestID(t *testing.T) {
+	var got atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get(requestid.Header))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		cfg     ClientConfig
+		ctxID   string
+		header  string
+		wantHdr string
+	}{
+		{"forwarded", ClientConfig{}, "abc", "", "abc"},
+		{"explicit header wins", ClientConfig{}, "abc", "mine", "mine"},
+		{"disabled", ClientConfig{DisableRequestID: true}, "abc", "", ""},
+		{"no ID", ClientConfig{}, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := requestid.NewContext(context.Background(), tt.ctxID)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			resp, err := NewClient(tt.cfg).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got.Load() != tt.wantHdr {
+				t.Errorf("X-Request-ID = %q, want %q", got.Load(), tt.wantHdr)
+			}
+			if tt.header == "" && req.Header.Get(requestid.Header) != "" {
+				t.Error("the caller's request was modified")
+			}
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		method string
+		key    string
+		want   int32
+	}{
+		{http.MethodGet, "", 3},
+		{http.MethodPut, "", 3},
+		{http.MethodPost, "", 1},
+		{http.MethodPost, "k1", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.key, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			client := NewClient(ClientConfig{Retry: &resilience.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond}})
+			req, _ := http.NewRequest(tt.method, srv.URL, nil)
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if calls.Load() != tt.want {
+				t.Errorf("calls = %d, want %d", calls.Load(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	captureLog(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client := NewClient(ClientConfig{Breaker: &resilience.BreakerConfig{MinRequests: 2, FailureRate: 0.5, OpenTimeout: time.Hour}})
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, resilience.ErrOpen) {
+		t.Errorf("err = %v, want ErrOpen", err)
+	}
+}
+
+func TestClientBreakerPerHost(t *testing.T) {
+	captureLog(t)
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+
+	client := NewClient(ClientConfig{Breaker: &resilience.BreakerConfig{MinRequests: 1, FailureRate: 0.5, OpenTimeout: time.Hour}})
+	resp, err := client.Get(failing.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, err := client.Get(failing.URL); !errors.Is(err, resilience.ErrOpen) {
+		t.Fatalf("err = %v, want ErrOpen", err)
+	}
+	resp, err = client.Get(healthy.URL)
+	if err != nil {
+		t.Fatalf("an open breaker of another host rejected the request: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ClientConfig
+		want func(c *http.Client, tr *http.Transport) bool
+	}{
+		{"timeout", ClientConfig{}, func(c *http.Client, _ *http.Transport) bool { return c.Timeout == 30*time.Second }},
+		{"custom timeout", ClientConfig{Timeout: time.Second}, func(c *http.Client, _ *http.Transport) bool { return c.Timeout == time.Second }},
+		{"negative timeout", ClientConfig{Timeout: -1}, func(c *http.Client, _ *http.Transport) bool { return c.Timeout == 30*time.Second }},
+		{"pool", ClientConfig{}, func(_ *http.Client, tr *http.Transport) bool {
+			return tr.MaxIdleConns == 100 && tr.MaxIdleConnsPerHost == 10 && tr.MaxConnsPerHost == 0
+		}},
+		{"custom pool", ClientConfig{MaxIdleConns: 5, MaxIdleConnsPerHost: 2, MaxConnsPerHost: 4}, func(_ *http.Client, tr *http.Transport) bool {
+			return tr.MaxIdleConns == 5 && tr.MaxIdleConnsPerHost == 2 && tr.MaxConnsPerHost == 4
+		}},
+		{"transport timeouts", ClientConfig{}, func(_ *http.Client, tr *http.Transport) bool {
+			return tr.TLSHandshakeTimeout == 5*time.Second && tr.ResponseHeaderTimeout == 10*time.Second && tr.IdleConnTimeout == 90*time.Second
+		}},
+		{"custom transport timeouts", ClientConfig{TLSHandshakeTimeout: time.Second, ResponseHeaderTimeout: 2 * time.Second, IdleConnTimeout: 3 * time.Second},
+			func(_ *http.Client, tr *http.Transport) bool {
+				return tr.TLSHandshakeTimeout == time.Second && tr.ResponseHeaderTimeout == 2*time.Second && tr.IdleConnTimeout == 3*time.Second
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(tt.cfg)
+			if !tt.want(c, newTransport(tt.cfg)) {
+				t.Errorf("unexpected configuration for %+v", tt.cfg)
+			}
+		})
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestClientWrapTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen", r.Header.Get("X-Wrapped"))
+	}))
+	defer srv.Close()
+
+	client := NewClient(ClientConfig{WrapTransport: func(base http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set("X-Wrapped", "yes")
+			return base.RoundTrip(req)
+		})
+	}})
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Seen") != "yes" {
+		t.Error("the wrapping transport was not used")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	tests := []struct {
+		name string
+		cfg  ClientConfig
+	}{
+		{"whole request", ClientConfig{Timeout: 20 * time.Millisecond}},
+		{"response headers", ClientConfig{ResponseHeaderTimeout: 20 * time.Millisecond}},
+		{"including retries", ClientConfig{Timeout: 50 * time.Millisecond, ResponseHeaderTimeout: 20 * time.Millisecond,
+			Retry: &resilience.RetryPolicy{Attempts: 10, BaseDelay: time.Millisecond}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := NewClient(tt.cfg).Get(srv.URL)
+			if err == nil {
+				t.Fatal("request to a hanging server succeeded")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("request took %s", elapsed)
+			}
+		})
+	}
+}
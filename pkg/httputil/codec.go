@@ -0,0 +1,116 @@
+package httputil
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes response bodies of one media type.
+type Codec interface {
+	// MediaTypes lists the media types the codec serves. The first one is
+	// written as Content-Type.
+	MediaTypes() []string
+	// Supports reports whether v can be encoded.
+	Supports(v interface{}) bool
+	Encode(w io.Writer, v interface{}) error
+}
+
+// JSONCodec encodes JSON, using protojson for protobuf messages.
+type JSONCodec struct{}
+
+// MediaTypes implements Codec.
+func (JSONCodec) MediaTypes() []string {
+	return []string{"application/json"}
+}
+
+// Supports implements Codec.
+func (JSONCodec) Supports(interface{}) bool { return true }
+
+// Encode implements Codec.
+func (JSONCodec) Encode(w io.Writer, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// XMLCodec encodes XML with encoding/xml.
+type XMLCodec struct{}
+
+// MediaTypes implements Codec.
+func (XMLCodec) MediaTypes() []string {
+	return []string{"application/xml", "text/xml"}
+}
+
+// Supports implements Codec. Protobuf messages are not supported.
+func (XMLCodec) Supports(v interface{}) bool {
+	_, isProto := v.(proto.Message)
+	return !isProto
+}
+
+// Encode implements Codec.
+func (XMLCodec) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// ProtobufCodec encodes protobuf messages in the binary wire format.
+type ProtobufCodec struct{}
+
+// MediaTypes implements Codec.
+func (ProtobufCodec) MediaTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf"}
+}
+
+// Supports implements Codec. Only proto.Message values are supported.
+func (ProtobufCodec) Supports(v interface{}) bool {
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+// Encode implements Codec.
+func (ProtobufCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.New("value is not a protobuf message")
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// MsgpackCodec encodes MessagePack, like Gin's MsgPack renderer.
+type MsgpackCodec struct{}
+
+// MediaTypes implements Codec.
+func (MsgpackCodec) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack"}
+}
+
+// Supports implements Codec. Protobuf messages are not supported.
+func (MsgpackCodec) Supports(v interface{}) bool {
+	_, isProto := v.(proto.Message)
+	return !isProto
+}
+
+// Encode implements Codec.
+func (MsgpackCodec) Encode(w io.Writer, v interface{}) error {
+	var mh codec.MsgpackHandle
+	return codec.NewEncoder(w, &mh).Encode(v)
+}
@@ -0,0 +1,170 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize is the request body limit of DecodeJSON.
+const DefaultMaxBodySize = 1 << 20
+
+// DecodeError is returned for requests the client got wrong. Status is
+// 400, 413 or 415.
+type DecodeError struct {
+	Status int
+	Detail string
+	Fields []FieldError
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Detail
+	}
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return e.Detail + ": " + strings.Join(msgs, ", ")
+}
+
+// Problem returns the problem details body describing e.
+func (e *DecodeError) Problem() Problem {
+	p := NewProblem(e.Status, e.Detail)
+	p.Errors = e.Fields
+	return p
+}
+
+// WriteDecodeError writes the problem details of a DecodeError, or a 500
+// for any other error. WriteErr handles both and more.
+func WriteDecodeError(w http.ResponseWriter, err error) {
+	WriteErr(w, err)
+}
+
+// Decoder decodes and validates JSON request bodies.
+type Decoder struct {
+	// MaxBodySize defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+	// AllowUnknownFields accepts fields without a matching struct field,
+	// which are rejected by default.
+	AllowUnknownFields bool
+	// Validator checks the decoded value. Defaults to DefaultValidator;
+	// set NoValidation to skip validation.
+	Validator Validator
+}
+
+// DefaultDecoder is used by DecodeJSON.
+var DefaultDecoder = &Decoder{}
+
+// DecodeJSON decodes the JSON body of r into v with DefaultDecoder.
+func DecodeJSON(r *http.Request, v interface{}) error {
+	return DefaultDecoder.DecodeJSON(r, v)
+}
+
+// DecodeJSON checks the Content-Type of r, decodes its body into v and
+// validates the result. Client mistakes are returned as *DecodeError.
+func (d *Decoder) DecodeJSON(r *http.Request, v interface{}) error {
+	if ct := r.Header.Get("Content-Type"); !isJSON(ct) {
+		return &DecodeError{
+			Status: http.StatusUnsupportedMediaType,
+			Detail: fmt.Sprintf("content type %q is not JSON", ct),
+		}
+	}
+	limit := d.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	// Read one byte past the limit to tell a body of exactly limit bytes
+	// from a larger one.
+	body := &io.LimitedReader{R: r.Body, N: limit + 1}
+	dec := json.NewDecoder(body)
+	if !d.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(v)
+	// More would miss trailing data starting with a closing delimiter.
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("request body must contain a single JSON value")
+	}
+	if body.N <= 0 {
+		return &DecodeError{
+			Status: http.StatusRequestEntityTooLarge,
+			Detail: fmt.Sprintf("request body exceeds %d bytes", limit),
+		}
+	}
+	if err != nil {
+		return jsonError(err)
+	}
+
+	validator := d.Validator
+	if validator == nil {
+		validator = DefaultValidator
+	}
+	return validator.Validate(v)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// jsonError turns a decoding error into a DecodeError.
+func jsonError(err error) *DecodeError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return &DecodeError{Status: http.StatusBadRequest, Detail: "request body is empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Status: http.StatusBadRequest, Detail: "request body is truncated JSON"}
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset),
+		}
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "(body)"
+		}
+		return &DecodeError{
+			Status: http.StatusBadRequest,
+			Detail: "invalid request body",
+			Fields: []FieldError{{Field: field, Rule: "type", Message: "must be " + jsonKind(typeErr.Type.Kind().String())}},
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &DecodeError{
+			Status: http.StatusBadRequest,
+			Detail: "invalid request body",
+			Fields: []FieldError{{Field: field, Rule: "unknown", Message: "is not a known field"}},
+		}
+	}
+	return &DecodeError{Status: http.StatusBadRequest, Detail: err.Error()}
+}
+
+// jsonKind names a Go kind the way a JSON client would think of it.
+func jsonKind(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "string":
+		return "a string"
+	case kind == "bool":
+		return "a boolean"
+	case kind == "slice", kind == "array":
+		return "an array"
+	case kind == "struct", kind == "map":
+		return "an object"
+	}
+	return "a " + kind
+}
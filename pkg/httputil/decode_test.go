@@ -0,0 +1,122 @@
+package httputil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type createUser struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"omitempty,email"`
+	Age   int    `json:"age" validate:"gte=0"`
+}
+
+func jsonRequest(contentType, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		decoder     *Decoder
+		contentType string
+		body        string
+		wantStatus  int
+		wantField   string
+	}{
+		{"valid", nil, "application/json", `{"name":"ada","age":36}`, 0, ""},
+		{"json suffix with charset", nil, "application/vnd.api+json; charset=utf-8", `{"name":"ada"}`, 0, ""},
+		{"missing content type", nil, "", `{"name":"ada"}`, http.StatusUnsupportedMediaType, ""},
+		{"form content type", nil, "application/x-www-form-urlencoded", `name=ada`, http.StatusUnsupportedMediaType, ""},
+		{"empty body", nil, "application/json", ``, http.StatusBadRequest, ""},
+		{"truncated", nil, "application/json", `{"name":"ada"`, http.StatusBadRequest, ""},
+		{"malformed", nil, "application/json", `{"name":}`, http.StatusBadRequest, ""},
+		{"wrong type", nil, "application/json", `{"name":"ada","age":"old"}`, http.StatusBadRequest, "age"},
+		{"unknown field", nil, "application/json", `{"name":"ada","admin":true}`, http.StatusBadRequest, "admin"},
+		{"unknown field allowed", &Decoder{AllowUnknownFields: true}, "application/json", `{"name":"ada","admin":true}`, 0, ""},
+		{"second value", nil, "application/json", `{"name":"ada"} {"name":"bob"}`, http.StatusBadRequest, ""},
+		{"trailing closing bracket", nil, "application/json", `{"name":"ada"}]`, http.StatusBadRequest, ""},
+		{"trailing closing brace", nil, "application/json", `{"name":"ada"}}`, http.StatusBadRequest, ""},
+		{"trailing garbage", nil, "application/json", `{"name":"ada"} x`, http.StatusBadRequest, ""},
+		{"trailing whitespace", nil, "application/json", "{\"name\":\"ada\"}\n\t ", 0, ""},
+		{"validation failure", nil, "application/json", `{"email":"nope"}`, http.StatusBadRequest, "name"},
+		{"validation skipped", &Decoder{Validator: NoValidation}, "application/json", `{}`, 0, ""},
+		{"oversized", &Decoder{MaxBodySize: 16}, "application/json", `{"name":"` + strings.Repeat("a", 32) + `"}`, http.StatusRequestEntityTooLarge, ""},
+		{"oversized trailing data", &Decoder{MaxBodySize: 16}, "application/json", `{"name":"ada"}` + strings.Repeat(" ", 32), http.StatusRequestEntityTooLarge, ""},
+		{"exactly at limit", &Decoder{MaxBodySize: 14}, "application/json", `{"name":"ada"}`, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.decoder
+			if d == nil {
+				d = DefaultDecoder
+			}
+			var v createUser
+			err := d.DecodeJSON(jsonRequest(tt.contentType, tt.body), &v)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("DecodeJSON = %v", err)
+				}
+				return
+			}
+			var decErr *DecodeError
+			if !errors.As(err, &decErr) {
+				t.Fatalf("DecodeJSON = %v, want *DecodeError", err)
+			}
+			if decErr.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%v)", decErr.Status, tt.wantStatus, err)
+			}
+			if tt.wantField != "" && (len(decErr.Fields) == 0 || decErr.Fields[0].Field != tt.wantField) {
+				t.Errorf("fields = %+v, want %s", decErr.Fields, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestWriteDecodeError(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteDecodeError(w, &DecodeError{Status: http.StatusBadRequest, Detail: "validation failed", Fields: []FieldError{{Field: "name", Rule: "required", Message: "is required"}}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `"name"`) {
+		t.Errorf("body = %s", w.Body.String())
+	}
+}
+
+func TestTagValidatorMessages(t *testing.T) {
+	type item struct {
+		Tags []string `json:"tags" validate:"min=1"`
+		Code string   `json:"code" validate:"len=3"`
+		Kind string   `json:"kind" validate:"oneof=a b"`
+	}
+	err := DefaultValidator.Validate(&item{Code: "toolong", Kind: "c"})
+	var decErr *DecodeError
+	if !errors.As(err, &decErr) {
+		t.Fatalf("Validate = %v", err)
+	}
+	want := map[string]string{
+		"tags": "must have at least 1 elements",
+		"code": "must have exactly 3 characters",
+		"kind": "must be one of a, b",
+	}
+	for _, f := range decErr.Fields {
+		if want[f.Field] != f.Message {
+			t.Errorf("%s: message = %q, want %q", f.Field, f.Message, want[f.Field])
+		}
+		delete(want, f.Field)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing fields %v", want)
+	}
+}
@@ -0,0 +1,163 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/PhilipKram/gms-foundation/pkg/logger"
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+)
+
+// Error codes used by WriteErr for errors it maps itself.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRequestTooLarge      = "request_too_large"
+	CodeNotAcceptable        = "not_acceptable"
+	CodeUpstreamError        = "upstream_error"
+	CodeUpstreamUnavailable  = "upstream_unavailable"
+	CodeTimeout              = "timeout"
+	CodeInternal             = "internal_error"
+)
+
+// Error is an error with an HTTP status and a machine-readable code.
+// Message is shown to clients; Wrapped is only logged.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	// Details is rendered as the details member of the problem body.
+	Details interface{}
+	Wrapped error
+}
+
+// NewError returns an Error without a cause.
+func NewError(status int, code, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+// WrapError returns an Error caused by err.
+func WrapError(err error, status int, code, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message, Wrapped: err}
+}
+
+func (e *Error) Error() string {
+	if e.Wrapped == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Wrapped.Error()
+}
+
+// Unwrap returns the wrapped cause.
+func (e *Error) Unwrap() error {
+	return e.Wrapped
+}
+
+// ErrorMapper turns errors of another package into an *Error, returning
+// nil for errors it does not know.
+type ErrorMapper func(err error) *Error
+
+var (
+	mappersMu sync.RWMutex
+	mappers   []ErrorMapper
+)
+
+// RegisterErrorMapper adds m to the mappers consulted by WriteErr before
+// its built-in mappings, e.g. to answer 404 for a driver's not-found
+// error.
+func RegisterErrorMapper(m ErrorMapper) {
+	mappersMu.Lock()
+	defer mappersMu.Unlock()
+	mappers = append(mappers, m)
+}
+
+// WriteErr writes err as problem details with the status and code of the
+// matching *Error, a registered ErrorMapper or one of the built-in
+// mappings, and 500 internal_error otherwise. Causes of 5xx errors are
+// logged and never sent to the client. A nil err writes nothing.
+func WriteErr(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+	WriteProblem(w, errorProblem(err))
+}
+
+// errorProblem maps err to problem details, logging the causes of 5xx
+// errors.
+func errorProblem(err error) Problem {
+	e := MapError(err)
+	p := NewProblem(e.Status, e.Message)
+	p.Code = e.Code
+	p.Details = e.Details
+	var de *DecodeError
+	if errors.As(err, &de) {
+		p.Errors = de.Fields
+	}
+	if e.Status >= http.StatusInternalServerError {
+		logger.Err(log.Error(), err).Int("status", e.Status).Str("code", e.Code).Msg("Request failed")
+	}
+	return p
+}
+
+// MapError returns the *Error WriteErr would write for err. Errors
+// without a Status get 500.
+func MapError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return withStatus(e)
+	}
+	mappersMu.RLock()
+	registered := mappers
+	mappersMu.RUnlock()
+	for _, m := range registered {
+		if e := m(err); e != nil {
+			return withStatus(e)
+		}
+	}
+
+	var de *DecodeError
+	var oauthErr *oauth2.OAuthError
+	switch {
+	case errors.As(err, &de):
+		code := CodeInvalidRequest
+		switch de.Status {
+		case http.StatusUnsupportedMediaType:
+			code = CodeUnsupportedMediaType
+		case http.StatusRequestEntityTooLarge:
+			code = CodeRequestTooLarge
+		}
+		return WrapError(err, de.Status, code, de.Detail)
+	case errors.Is(err, ErrNotAcceptable):
+		return WrapError(err, http.StatusNotAcceptable, CodeNotAcceptable, err.Error())
+	case errors.As(err, &oauthErr):
+		// A failing identity provider is a dependency failure of ours, not
+		// a client error.
+		if oauthErr.Retryable() {
+			return WrapError(err, http.StatusServiceUnavailable, CodeUpstreamUnavailable, "identity provider unavailable")
+		}
+		return WrapError(err, http.StatusBadGateway, CodeUpstreamError, "identity provider error")
+	case errors.Is(err, resilience.ErrOpen):
+		return WrapError(err, http.StatusServiceUnavailable, CodeUpstreamUnavailable, "dependency unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return WrapError(err, http.StatusGatewayTimeout, CodeTimeout, "request timed out")
+	}
+	return WrapError(err, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// withStatus returns e, or a copy of it with status 500 when it has none.
+func withStatus(e *Error) *Error {
+	if e.Status != 0 {
+		return e
+	}
+	c := *e
+	c.Status = http.StatusInternalServerError
+	if c.Code == "" {
+		c.Code = CodeInternal
+	}
+	return &c
+}
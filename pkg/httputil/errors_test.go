@@ -0,0 +1,150 @@
+package httputil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+)
+
+// errMapped is answered 404 by the mapper registered in init.
+var errMapped = errors.New("row not found")
+
+func init() {
+	RegisterErrorMapper(func(err error) *Error {
+		if errors.Is(err, errMapped) {
+			return WrapError(err, http.StatusNotFound, "not_found", "no such item")
+		}
+		return nil
+	})
+}
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"error", NewError(http.StatusConflict, "duplicate", "name taken"), http.StatusConflict, "duplicate", "name taken"},
+		{"wrapped error", fmt.Errorf("create: %w", NewError(http.StatusConflict, "duplicate", "name taken")), http.StatusConflict, "duplicate", "name taken"},
+		{"error without status", &Error{Code: "quota", Message: "over quota"}, http.StatusInternalServerError, "quota", "over quota"},
+		{"error without status or code", &Error{Message: "broken"}, http.StatusInternalServerError, CodeInternal, "broken"},
+		{"registered mapper", fmt.Errorf("get: %w", errMapped), http.StatusNotFound, "not_found", "no such item"},
+		{"malformed body", &DecodeError{Status: http.StatusBadRequest, Detail: "malformed JSON"}, http.StatusBadRequest, CodeInvalidRequest, "malformed JSON"},
+		{"unsupported media type", &DecodeError{Status: http.StatusUnsupportedMediaType, Detail: "not JSON"}, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "not JSON"},
+		{"oversized body", &DecodeError{Status: http.StatusRequestEntityTooLarge, Detail: "too large"}, http.StatusRequestEntityTooLarge, CodeRequestTooLarge, "too large"},
+		{"not acceptable", ErrNotAcceptable, http.StatusNotAcceptable, CodeNotAcceptable, ErrNotAcceptable.Error()},
+		{"provider rejected", &oauth2.OAuthError{StatusCode: http.StatusBadRequest, Code: "invalid_grant"}, http.StatusBadGateway, CodeUpstreamError, "identity provider error"},
+		{"provider down", &oauth2.OAuthError{StatusCode: http.StatusServiceUnavailable}, http.StatusServiceUnavailable, CodeUpstreamUnavailable, "identity provider unavailable"},
+		{"open breaker", fmt.Errorf("call: %w", resilience.ErrOpen), http.StatusServiceUnavailable, CodeUpstreamUnavailable, "dependency unavailable"},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout, "request timed out"},
+		{"unknown", errors.New("disk on fire"), http.StatusInternalServerError, CodeInternal, "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := MapError(tt.err)
+			if e.Status != tt.wantStatus || e.Code != tt.wantCode || e.Message != tt.wantMessage {
+				t.Errorf("MapError() = %d %s %q, want %d %s %q", e.Status, e.Code, e.Message, tt.wantStatus, tt.wantCode, tt.wantMessage)
+			}
+			var own *Error
+			if !errors.As(tt.err, &own) && !errors.Is(e, tt.err) {
+				t.Errorf("mapped error lost its cause %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestMapErrorDoesNotModifyError(t *testing.T) {
+	e := &Error{Message: "broken"}
+	MapError(e)
+	if e.Status != 0 || e.Code != "" {
+		t.Errorf("MapError modified its argument: %+v", e)
+	}
+}
+
+func TestWriteErr(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantLogged  bool
+		wantMissing string
+		check       func(t *testing.T, p Problem)
+	}{
+		{"client error", NewError(http.StatusConflict, "duplicate", "name taken"), http.StatusConflict, false, "", nil},
+		{"hidden cause", WrapError(errors.New("pq: password authentication failed"), http.StatusServiceUnavailable, "db", "database unavailable"),
+			http.StatusServiceUnavailable, true, "password", nil},
+		{"internal error", errors.New("secret stack detail"), http.StatusInternalServerError, true, "secret", nil},
+		{"field errors", &DecodeError{Status: http.StatusBadRequest, Detail: "invalid request", Fields: []FieldError{{Field: "name", Rule: "required", Message: "is required"}}},
+			http.StatusBadRequest, false, "", func(t *testing.T, p Problem) {
+				if len(p.Errors) != 1 || p.Errors[0].Field != "name" {
+					t.Errorf("errors = %+v", p.Errors)
+				}
+			}},
+		{"details", &Error{Status: http.StatusTooManyRequests, Code: "rate_limited", Message: "slow down", Details: map[string]int{"retry_after": 3}},
+			http.StatusTooManyRequests, false, "", func(t *testing.T, p Problem) {
+				if d, _ := p.Details.(map[string]interface{}); d["retry_after"] != 3.0 {
+					t.Errorf("details = %v", p.Details)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := captureLog(t)
+			w := httptest.NewRecorder()
+			WriteErr(w, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var p Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Status != tt.wantStatus || p.Code != MapError(tt.err).Code {
+				t.Errorf("problem = %+v", p)
+			}
+			if tt.wantMissing != "" && strings.Contains(w.Body.String(), tt.wantMissing) {
+				t.Errorf("cause leaked to the client: %s", w.Body.String())
+			}
+			if (logged.Len() > 0) != tt.wantLogged {
+				t.Errorf("logged = %q, want logged %v", logged.String(), tt.wantLogged)
+			}
+			if tt.wantLogged && !strings.Contains(logged.String(), tt.err.Error()) {
+				t.Errorf("log misses the cause: %s", logged.String())
+			}
+			if tt.check != nil {
+				tt.check(t, p)
+			}
+		})
+	}
+}
+
+func TestWriteErrNil(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteErr(w, nil)
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Errorf("WriteErr(nil) wrote %q", w.Body.String())
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	if got := NewError(http.StatusNotFound, "not_found", "no such item").Error(); got != "no such item" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := WrapError(errMapped, http.StatusNotFound, "not_found", "no such item").Error(); got != "no such item: row not found" {
+		t.Errorf("Error() = %q", got)
+	}
+}
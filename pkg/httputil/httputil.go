@@ -0,0 +1,47 @@
+// Package httputil provides request and response helpers for plain
+// net/http handlers, independent of Gin.
+package httputil
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details bodies.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code, Details and Errors
+// are extension members: a machine-readable error code, free-form details
+// and the invalid fields of a request.
+type Problem struct {
+	Type    string       `json:"type"`
+	Title   string       `json:"title"`
+	Status  int          `json:"status"`
+	Detail  string       `json:"detail,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Details interface{}  `json:"details,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem returns a Problem for status with the standard title.
+func NewProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// WriteProblem writes p with its status.
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
@@ -0,0 +1,195 @@
+package httputil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotAcceptable is returned by Write when no codec matches the Accept
+// header of the request.
+var ErrNotAcceptable = errors.New("no acceptable representation")
+
+// Codecs is a registry of response codecs. It is safe for concurrent use.
+type Codecs struct {
+	mu     sync.RWMutex
+	codecs []Codec
+}
+
+// NewCodecs returns a registry of codecs. On ties, and for requests
+// without an Accept header, earlier codecs are preferred.
+func NewCodecs(codecs ...Codec) *Codecs {
+	return &Codecs{codecs: codecs}
+}
+
+// Register adds codec with the lowest preference. It replaces a codec
+// serving the same primary media type.
+func (c *Codecs) Register(codec Codec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, existing := range c.codecs {
+		if existing.MediaTypes()[0] == codec.MediaTypes()[0] {
+			c.codecs[i] = codec
+			return
+		}
+	}
+	c.codecs = append(c.codecs, codec)
+}
+
+// DefaultCodecs serves JSON, XML, protobuf and MessagePack, preferring
+// JSON.
+var DefaultCodecs = NewCodecs(JSONCodec{}, XMLCodec{}, ProtobufCodec{}, MsgpackCodec{})
+
+// Write encodes v with the DefaultCodecs codec best matching the Accept
+// header of r.
+func Write(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	return DefaultCodecs.Write(w, r, status, v)
+}
+
+// Write encodes v with the codec best matching the Accept header of r and
+// writes it with status. When no codec matches, it answers 406 and returns
+// ErrNotAcceptable. The body is encoded before anything is written, so an
+// encoding error can still be answered with a 500.
+func (c *Codecs) Write(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	w.Header().Add("Vary", "Accept")
+	codec, mediaType := c.Negotiate(r.Header.Get("Accept"), v)
+	if codec == nil {
+		WriteErr(w, WrapError(ErrNotAcceptable, http.StatusNotAcceptable, CodeNotAcceptable,
+			"supported media types are "+strings.Join(c.mediaTypes(v), ", ")))
+		return ErrNotAcceptable
+	}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, v); err != nil {
+		err = fmt.Errorf("encode %s: %w", mediaType, err)
+		WriteErr(w, err)
+		return err
+	}
+	w.Header().Set("Content-Type", contentType(mediaType))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Negotiate returns the codec able to encode v that best matches accept,
+// and the media type to answer with, or nil.
+func (c *Codecs) Negotiate(accept string, v interface{}) (Codec, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if strings.TrimSpace(accept) == "" {
+		accept = "*/*"
+	}
+	ranges := parseAccept(accept)
+	for _, rng := range ranges {
+		if rng.q == 0 {
+			continue
+		}
+		for _, codec := range c.codecs {
+			if !codec.Supports(v) {
+				continue
+			}
+			primary := codec.MediaTypes()[0]
+			for _, mt := range codec.MediaTypes() {
+				if rng.matches(mt) && !excluded(ranges, mt) {
+					if strings.Contains(rng.mediaType, "*") && !excluded(ranges, primary) {
+						mt = primary
+					}
+					return codec, mt
+				}
+			}
+		}
+	}
+	return nil, ""
+}
+
+func (c *Codecs) mediaTypes(v interface{}) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var types []string
+	for _, codec := range c.codecs {
+		if codec.Supports(v) {
+			types = append(types, codec.MediaTypes()[0])
+		}
+	}
+	return types
+}
+
+// mediaRange is one element of an Accept header.
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	if m.mediaType == "*/*" || m.mediaType == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(m.mediaType, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// specificity ranks exact types above type/* above */*.
+func (m mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// parseAccept returns the media ranges of an Accept header ordered by
+// preference. Malformed ranges are skipped.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qs, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// excluded reports whether the most specific range matching mediaType
+// refuses it with q=0. A refusal such as text/*;q=0 is thus not overridden
+// by a broader */*, only by a more specific range such as text/csv.
+func excluded(ranges []mediaRange, mediaType string) bool {
+	best, q := -1, 1.0
+	for _, rng := range ranges {
+		if rng.matches(mediaType) && rng.specificity() > best {
+			best, q = rng.specificity(), rng.q
+		}
+	}
+	return best >= 0 && q == 0
+}
+
+// contentType adds a charset to textual media types.
+func contentType(mediaType string) string {
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "xml") {
+		return mediaType + "; charset=utf-8"
+	}
+	return mediaType
+}
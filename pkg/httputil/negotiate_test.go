@@ -0,0 +1,195 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type item struct {
+	Name string `json:"name" xml:"name" codec:"name"`
+}
+
+// csvCodec is a custom codec serving text/csv.
+type csvCodec struct{ marker string }
+
+func (csvCodec) MediaTypes() []string        { return []string{"text/csv"} }
+func (csvCodec) Supports(v interface{}) bool { _, ok := v.(item); return ok }
+func (c csvCodec) Encode(w io.Writer, v interface{}) error {
+	_, err := io.WriteString(w, c.marker+v.(item).Name+"\n")
+	return err
+}
+
+func negotiateRequest(accept string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return req
+}
+
+func TestWriteNegotiates(t *testing.T) {
+	msg := wrapperspb.String("ada")
+	tests := []struct {
+		name            string
+		accept          string
+		value           interface{}
+		wantContentType string
+	}{
+		{"no accept", "", item{"ada"}, "application/json; charset=utf-8"},
+		{"any", "*/*", item{"ada"}, "application/json; charset=utf-8"},
+		{"json", "application/json", item{"ada"}, "application/json; charset=utf-8"},
+		{"xml", "application/xml", item{"ada"}, "application/xml; charset=utf-8"},
+		{"secondary xml type", "text/xml", item{"ada"}, "text/xml; charset=utf-8"},
+		{"msgpack", "application/x-msgpack", item{"ada"}, "application/x-msgpack"},
+		{"protobuf", "application/x-protobuf", msg, "application/x-protobuf"},
+		{"protobuf as json", "application/json", msg, "application/json; charset=utf-8"},
+		{"quality order", "application/xml;q=0.5, application/msgpack", item{"ada"}, "application/msgpack"},
+		{"specific before wildcard", "*/*, application/xml", item{"ada"}, "application/xml; charset=utf-8"},
+		{"type wildcard uses primary type", "text/*", item{"ada"}, "application/xml; charset=utf-8"},
+		{"refused type", "application/json;q=0, */*", item{"ada"}, "application/xml; charset=utf-8"},
+		{"refused range keeps specific type", "application/*;q=0, application/msgpack", item{"ada"}, "application/msgpack"},
+		{"malformed quality skipped", "application/xml;q=high, application/msgpack;q=0.1", item{"ada"}, "application/msgpack"},
+		{"out of range quality skipped", "application/xml;q=2, application/msgpack;q=0.1", item{"ada"}, "application/msgpack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := Write(w, negotiateRequest(tt.accept), http.StatusCreated, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusCreated {
+				t.Errorf("code = %d", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.wantContentType {
+				t.Fatalf("Content-Type = %q, want %q", ct, tt.wantContentType)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q", got)
+			}
+			if cl := w.Header().Get("Content-Length"); cl != strconv.Itoa(w.Body.Len()) {
+				t.Errorf("Content-Length = %s for %d bytes", cl, w.Body.Len())
+			}
+			if got := decodeAs(t, strings.SplitN(tt.wantContentType, ";", 2)[0], w.Body.Bytes(), tt.value); got != "ada" {
+				t.Errorf("decoded name = %q from %q", got, w.Body.String())
+			}
+		})
+	}
+}
+
+// decodeAs decodes body in mediaType and returns the encoded name.
+func decodeAs(t *testing.T, mediaType string, body []byte, v interface{}) string {
+	t.Helper()
+	if _, ok := v.(proto.Message); ok {
+		if mediaType == "application/json" {
+			var s string
+			if err := json.Unmarshal(body, &s); err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}
+		var m wrapperspb.StringValue
+		if err := proto.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		return m.Value
+	}
+	var got item
+	var err error
+	switch mediaType {
+	case "application/json":
+		err = json.Unmarshal(body, &got)
+	case "application/xml", "text/xml":
+		if !bytes.HasPrefix(body, []byte("<?xml")) {
+			t.Errorf("XML body has no header: %s", body)
+		}
+		err = xml.Unmarshal(body, &got)
+	default:
+		var mh codec.MsgpackHandle
+		err = codec.NewDecoderBytes(body, &mh).Decode(&got)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got.Name
+}
+
+// captureLog sends the global logger to the returned buffer until the
+// test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	saved := log.Logger
+	t.Cleanup(func() { log.Logger = saved })
+	log.Logger = zerolog.New(&buf)
+	return &buf
+}
+
+func TestWriteRejects(t *testing.T) {
+	captureLog(t)
+	tests := []struct {
+		name     string
+		accept   string
+		value    interface{}
+		wantCode int
+		wantErr  error
+		wantBody string
+	}{
+		{"unsupported type", "image/png", item{"ada"}, http.StatusNotAcceptable, ErrNotAcceptable, "not_acceptable"},
+		{"everything refused", "*/*;q=0", item{"ada"}, http.StatusNotAcceptable, ErrNotAcceptable, "not_acceptable"},
+		{"protobuf for a plain value", "application/x-protobuf", item{"ada"}, http.StatusNotAcceptable, ErrNotAcceptable, "application/json, application/xml, application/msgpack"},
+		{"xml for a protobuf message", "application/xml", wrapperspb.String("ada"), http.StatusNotAcceptable, ErrNotAcceptable, "application/x-protobuf"},
+		{"malformed accept", "application/", item{"ada"}, http.StatusNotAcceptable, ErrNotAcceptable, "not_acceptable"},
+		{"encode failure", "application/xml", map[string]int{"a": 1}, http.StatusInternalServerError, nil, "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := Write(w, negotiateRequest(tt.accept), http.StatusOK, tt.value)
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("Write() error = %v, want %v", err, tt.wantErr)
+			}
+			if w.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", w.Code, tt.wantCode)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body %s misses %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCodecsRegister(t *testing.T) {
+	c := NewCodecs(JSONCodec{})
+	c.Register(csvCodec{marker: "old:"})
+	c.Register(csvCodec{marker: "new:"})
+
+	w := httptest.NewRecorder()
+	if err := c.Write(w, negotiateRequest("text/csv"), http.StatusOK, item{"ada"}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "new:ada\n" || w.Header().Get("Content-Type") != "text/csv" {
+		t.Errorf("got %q as %q, want the replacement codec", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	// Later codecs are only chosen when asked for.
+	if codec, _ := c.Negotiate("", item{"ada"}); codec != (JSONCodec{}) {
+		t.Errorf("default codec = %T, want JSONCodec", codec)
+	}
+}
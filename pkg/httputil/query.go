@@ -0,0 +1,385 @@
+package httputil
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryParser reads query parameters and collects every parse error, so a
+// handler can report all of them in one 400 response.
+//
+//	q := httputil.ParseQuery(r)
+//	limit := q.Int("limit", 20)
+//	order := q.Enum("order", "asc", "asc", "desc")
+//	if err := q.Err(); err != nil {
+//		httputil.WriteErr(w, err)
+//		return
+//	}
+//
+// Missing and empty parameters yield the default.
+type QueryParser struct {
+	values url.Values
+	errs   []FieldError
+}
+
+// ParseQuery returns a QueryParser for the query of r.
+func ParseQuery(r *http.Request) *QueryParser {
+	return &QueryParser{values: r.URL.Query()}
+}
+
+// Err returns a *DecodeError listing every invalid parameter, or nil.
+func (q *QueryParser) Err() error {
+	if len(q.errs) == 0 {
+		return nil
+	}
+	return &DecodeError{Status: http.StatusBadRequest, Detail: "invalid query parameters", Fields: q.errs}
+}
+
+func (q *QueryParser) fail(name, rule, message string) {
+	q.errs = append(q.errs, FieldError{Field: name, Rule: rule, Message: message})
+}
+
+// String returns the parameter or def.
+func (q *QueryParser) String(name, def string) string {
+	if v := q.values.Get(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// Int returns the parameter as an integer, or def.
+func (q *QueryParser) Int(name string, def int) int {
+	v := q.values.Get(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		q.fail(name, "int", "must be an integer")
+		return def
+	}
+	return n
+}
+
+// Bool returns the parameter as a boolean, or def. It accepts the values
+// of strconv.ParseBool.
+func (q *QueryParser) Bool(name string, def bool) bool {
+	v := q.values.Get(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		q.fail(name, "bool", "must be true or false")
+		return def
+	}
+	return b
+}
+
+// Time returns the parameter as an RFC 3339 timestamp or a 2006-01-02
+// date in UTC, or def.
+func (q *QueryParser) Time(name string, def time.Time) time.Time {
+	v := q.values.Get(name)
+	if v == "" {
+		return def
+	}
+	t, err := parseTime(v)
+	if err != nil {
+		q.fail(name, "time", "must be an RFC 3339 timestamp or a date")
+		return def
+	}
+	return t
+}
+
+// Duration returns the parameter as a Go duration such as "90s", or def.
+func (q *QueryParser) Duration(name string, def time.Duration) time.Duration {
+	v := q.values.Get(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		q.fail(name, "duration", "must be a duration such as 90s")
+		return def
+	}
+	return d
+}
+
+// Enum returns the parameter when it is one of allowed, or def.
+func (q *QueryParser) Enum(name, def string, allowed ...string) string {
+	v := q.values.Get(name)
+	if v == "" {
+		return def
+	}
+	for _, a := range allowed {
+		if v == a {
+			return v
+		}
+	}
+	q.fail(name, "oneof", "must be one of "+strings.Join(allowed, ", "))
+	return def
+}
+
+// UUID returns the parameter when it is a UUID in canonical form,
+// lower-cased, or def.
+func (q *QueryParser) UUID(name, def string) string {
+	v := q.values.Get(name)
+	if v == "" {
+		return def
+	}
+	if !isUUID(v) {
+		q.fail(name, "uuid", "must be a UUID")
+		return def
+	}
+	return strings.ToLower(v)
+}
+
+// QueryInt returns the query parameter name of r as an integer, or def.
+func QueryInt(r *http.Request, name string, def int) (int, error) {
+	q := ParseQuery(r)
+	return q.Int(name, def), q.Err()
+}
+
+// QueryBool returns the query parameter name of r as a boolean, or def.
+func QueryBool(r *http.Request, name string, def bool) (bool, error) {
+	q := ParseQuery(r)
+	return q.Bool(name, def), q.Err()
+}
+
+// QueryTime returns the query parameter name of r as a time, or def.
+func QueryTime(r *http.Request, name string, def time.Time) (time.Time, error) {
+	q := ParseQuery(r)
+	return q.Time(name, def), q.Err()
+}
+
+// QueryEnum returns the query parameter name of r when it is one of
+// allowed, or def.
+func QueryEnum(r *http.Request, name, def string, allowed ...string) (string, error) {
+	q := ParseQuery(r)
+	return q.Enum(name, def, allowed...), q.Err()
+}
+
+// QueryUUID returns the query parameter name of r when it is a UUID, or
+// def.
+func QueryUUID(r *http.Request, name, def string) (string, error) {
+	q := ParseQuery(r)
+	return q.UUID(name, def), q.Err()
+}
+
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+func isUUID(v string) bool {
+	if len(v) != 36 {
+		return false
+	}
+	for i, c := range v {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// BindQuery fills the struct pointed to by dst from the query of r and
+// validates it with DefaultValidator. Fields are matched by their `query`
+// tag, skipped when the tag is "-" or absent, and set to their `default`
+// tag when the parameter is missing. Supported field types are strings,
+// booleans, integers, floats, time.Time, time.Duration, types
+// implementing encoding.TextUnmarshaler, pointers to these and slices of
+// these, which take repeated parameters. All parse and validation errors
+// are returned together as a *DecodeError; a field of another type is a
+// programming error and returned as a plain error.
+//
+//	var params struct {
+//		Limit int      `query:"limit" default:"20" validate:"min=1,max=100"`
+//		Order string   `query:"order" default:"asc" validate:"oneof=asc desc"`
+//		Tags  []string `query:"tag"`
+//	}
+func BindQuery(r *http.Request, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("BindQuery needs a pointer to a struct, got %T", dst)
+	}
+	values := r.URL.Query()
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var errs []FieldError
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("query"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		raw := nonEmpty(values[name])
+		if len(raw) == 0 {
+			def, ok := f.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			raw = []string{def}
+		}
+		if err := setField(rv.Field(i), raw); err != nil {
+			var ute *unsupportedTypeError
+			if errors.As(err, &ute) {
+				return fmt.Errorf("BindQuery: field %s: %w", f.Name, err)
+			}
+			errs = append(errs, FieldError{Field: name, Rule: "type", Message: err.Error()})
+		}
+	}
+
+	err := queryValidator.Validate(dst)
+	de, ok := err.(*DecodeError)
+	if err != nil && !ok {
+		return err
+	}
+	if ok {
+		// Fields that failed to parse hold their zero value; only report
+		// why they failed to parse.
+		for _, fe := range de.Fields {
+			if !hasField(errs, fe.Field) {
+				errs = append(errs, fe)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &DecodeError{Status: http.StatusBadRequest, Detail: "invalid query parameters", Fields: errs}
+	}
+	return nil
+}
+
+func hasField(errs []FieldError, field string) bool {
+	for _, fe := range errs {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(vs []string) []string {
+	out := vs[:0:0]
+	for _, v := range vs {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// queryValidator reports fields by their query parameter names.
+var queryValidator = newTagValidator(nil, "query")
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	textType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setField parses raw into v, using every value for slices and the first
+// one otherwise.
+func setField(v reflect.Value, raw []string) error {
+	if v.Kind() == reflect.Slice && v.Type() != reflect.TypeOf([]byte(nil)) && !reflect.PointerTo(v.Type()).Implements(textType) {
+		s := reflect.MakeSlice(v.Type(), len(raw), len(raw))
+		for i, r := range raw {
+			if err := setValue(s.Index(i), r); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setValue(v, raw[0])
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok && v.Type() != timeType {
+		if err := u.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("is invalid: %v", err)
+		}
+		return nil
+	}
+	switch {
+	case v.Type() == timeType:
+		t, err := parseTime(raw)
+		if err != nil {
+			return errors.New("must be an RFC 3339 timestamp or a date")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("must be a duration such as 90s")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		return &unsupportedTypeError{v.Type()}
+	}
+	return nil
+}
+
+// unsupportedTypeError reports a field type setValue cannot parse into.
+type unsupportedTypeError struct {
+	t reflect.Type
+}
+
+func (e *unsupportedTypeError) Error() string {
+	return "unsupported type " + e.t.String()
+}
@@ -0,0 +1,186 @@
+package httputil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func queryRequest(query string) *http.Request {
+	return httptest.NewRequest(http.MethodGet, "/items?"+query, nil)
+}
+
+// fieldNames returns the sorted fields of a *DecodeError, or nil.
+func fieldNames(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Status != http.StatusBadRequest {
+		t.Fatalf("error = %v, want a 400 *DecodeError", err)
+	}
+	var names []string
+	for _, f := range de.Fields {
+		names = append(names, f.Field)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestQueryParser(t *testing.T) {
+	const id = "0B7C6A5E-95B4-4A39-9B3A-6E8F0D3C2A11"
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	type result struct {
+		Name   string
+		Limit  int
+		Active bool
+		Since  time.Time
+		Window time.Duration
+		Order  string
+		ID     string
+	}
+	defaults := result{"all", 20, true, day, time.Minute, "asc", ""}
+
+	tests := []struct {
+		name       string
+		query      string
+		want       result
+		wantFields []string
+	}{
+		{"defaults", "", defaults, nil},
+		{"empty values use defaults", "name=&limit=&order=", defaults, nil},
+		{"all set", "name=ada&limit=5&active=false&since=2024-03-02T10:00:00Z&window=90s&order=desc&id=" + id,
+			result{"ada", 5, false, time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), 90 * time.Second, "desc", "0b7c6a5e-95b4-4a39-9b3a-6e8f0d3c2a11"}, nil},
+		{"date", "since=2024-04-05", func() result { r := defaults; r.Since = time.Date(2024, 4, 5, 0, 0, 0, 0, time.UTC); return r }(), nil},
+		{"first of repeated values", "limit=1&limit=2", func() result { r := defaults; r.Limit = 1; return r }(), nil},
+		{"every error collected", "limit=ten&active=maybe&since=yesterday&window=long&order=random&id=42",
+			defaults, []string{"active", "id", "limit", "order", "since", "window"}},
+		{"float limit", "limit=1.5", defaults, []string{"limit"}},
+		{"uuid without dashes", "id=0b7c6a5e95b44a399b3a6e8f0d3c2a11xxxx", defaults, []string{"id"}},
+		{"uuid with bad character", "id=0b7c6a5e-95b4-4a39-9b3a-6e8f0d3c2a1g", defaults, []string{"id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := ParseQuery(queryRequest(tt.query))
+			got := result{
+				Name:   q.String("name", "all"),
+				Limit:  q.Int("limit", 20),
+				Active: q.Bool("active", true),
+				Since:  q.Time("since", day),
+				Window: q.Duration("window", time.Minute),
+				Order:  q.Enum("order", "asc", "asc", "desc"),
+				ID:     q.UUID("id", ""),
+			}
+			if !got.Since.Equal(tt.want.Since) {
+				t.Errorf("Since = %s, want %s", got.Since, tt.want.Since)
+			}
+			got.Since = tt.want.Since
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if names := fieldNames(t, q.Err()); !reflect.DeepEqual(names, tt.wantFields) {
+				t.Errorf("invalid fields = %v, want %v", names, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestQueryHelpers(t *testing.T) {
+	r := queryRequest("limit=x&active=1&since=2024-03-01&order=up&id=nope")
+	if n, err := QueryInt(r, "limit", 7); n != 7 || err == nil {
+		t.Errorf("QueryInt() = %d, %v", n, err)
+	}
+	if b, err := QueryBool(r, "active", false); !b || err != nil {
+		t.Errorf("QueryBool() = %v, %v", b, err)
+	}
+	if ts, err := QueryTime(r, "since", time.Time{}); ts.IsZero() || err != nil {
+		t.Errorf("QueryTime() = %v, %v", ts, err)
+	}
+	if s, err := QueryEnum(r, "order", "asc", "asc", "desc"); s != "asc" || err == nil {
+		t.Errorf("QueryEnum() = %q, %v", s, err)
+	}
+	if s, err := QueryUUID(r, "id", ""); s != "" || err == nil {
+		t.Errorf("QueryUUID() = %q, %v", s, err)
+	}
+}
+
+type listParams struct {
+	Limit    int           `query:"limit" default:"20" validate:"min=1,max=100"`
+	Order    string        `query:"order" default:"asc" validate:"oneof=asc desc"`
+	Tags     []string      `query:"tag"`
+	IDs      []int64       `query:"id"`
+	Since    *time.Time    `query:"since"`
+	Timeout  time.Duration `query:"timeout"`
+	Score    float64       `query:"score"`
+	Page     uint          `query:"page"`
+	Draft    bool          `query:"draft"`
+	Addr     netip.Addr    `query:"addr"`
+	Skipped  string        `query:"-"`
+	Untagged string
+	hidden   string `query:"hidden"`
+}
+
+func TestBindQuery(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		query      string
+		want       listParams
+		wantFields []string
+	}{
+		{"defaults", "", listParams{Limit: 20, Order: "asc"}, nil},
+		{"all set", "limit=5&order=desc&tag=a&tag=b&id=1&id=2&since=2024-03-01&timeout=2s&score=0.5&page=3&draft=true&addr=10.0.0.1",
+			listParams{Limit: 5, Order: "desc", Tags: []string{"a", "b"}, IDs: []int64{1, 2}, Since: &since, Timeout: 2 * time.Second,
+				Score: 0.5, Page: 3, Draft: true, Addr: netip.MustParseAddr("10.0.0.1")}, nil},
+		{"empty values ignored", "limit=&tag=&tag=a", listParams{Limit: 20, Order: "asc", Tags: []string{"a"}}, nil},
+		{"untagged fields ignored", "Skipped=x&Untagged=y&hidden=z", listParams{Limit: 20, Order: "asc"}, nil},
+		{"parse errors", "limit=many&id=1&id=two&since=soon&timeout=long&score=high&page=-1&draft=perhaps&addr=localhost",
+			listParams{}, []string{"addr", "draft", "id", "limit", "page", "score", "since", "timeout"}},
+		{"validation errors", "limit=500&order=random", listParams{}, []string{"limit", "order"}},
+		{"parse error not repeated by validation", "limit=0x10&order=random", listParams{}, []string{"limit", "order"}},
+		{"overflow", "limit=99999999999999999999", listParams{}, []string{"limit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got listParams
+			err := BindQuery(queryRequest(tt.query), &got)
+			if names := fieldNames(t, err); !reflect.DeepEqual(names, tt.wantFields) {
+				t.Fatalf("invalid fields = %v, want %v (%v)", names, tt.wantFields, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindQueryProgrammingErrors(t *testing.T) {
+	var unsupported struct {
+		Filter map[string]string `query:"filter"`
+	}
+	var s listParams
+	tests := []struct {
+		name string
+		dst  interface{}
+	}{
+		{"unsupported field type", &unsupported},
+		{"not a pointer", s},
+		{"pointer to a non-struct", new(int)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := BindQuery(queryRequest("filter=x"), tt.dst)
+			var de *DecodeError
+			if err == nil || errors.As(err, &de) {
+				t.Errorf("error = %v, want a plain error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package httputil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Streams flush once this many bytes are buffered, or on the first write
+// after flushInterval, so slow producers still reach the client promptly.
+const (
+	flushSize     = 32 << 10
+	flushInterval = time.Second
+)
+
+// streamWriter buffers a streamed response and flushes it incrementally.
+type streamWriter struct {
+	ctx       context.Context
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	buf       *bufio.Writer
+	lastFlush time.Time
+}
+
+func newStreamWriter(w http.ResponseWriter, r *http.Request, contentType string) *streamWriter {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	return &streamWriter{
+		ctx:       r.Context(),
+		w:         w,
+		rc:        http.NewResponseController(w),
+		buf:       bufio.NewWriterSize(w, flushSize),
+		lastFlush: time.Now(),
+	}
+}
+
+// maybeFlush flushes when enough data is buffered or enough time passed.
+func (s *streamWriter) maybeFlush() error {
+	if s.buf.Buffered() >= flushSize/2 || time.Since(s.lastFlush) >= flushInterval {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *streamWriter) flush() error {
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	s.lastFlush = time.Now()
+	// Writers that cannot flush are still written to, just not early.
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// NDJSONWriter writes newline-delimited JSON, one value per line. It stops
+// with the request context's error once the client disconnects.
+type NDJSONWriter struct {
+	s   *streamWriter
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter sets the application/x-ndjson Content-Type and returns
+// a writer for the response. The status is sent with the first flush;
+// call w.WriteHeader beforehand for anything other than 200.
+func NewNDJSONWriter(w http.ResponseWriter, r *http.Request) *NDJSONWriter {
+	s := newStreamWriter(w, r, "application/x-ndjson")
+	return &NDJSONWriter{s: s, enc: json.NewEncoder(s.buf)}
+}
+
+// Write encodes v as one line.
+func (n *NDJSONWriter) Write(v interface{}) error {
+	if err := n.s.ctx.Err(); err != nil {
+		return err
+	}
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+	return n.s.maybeFlush()
+}
+
+// Flush sends buffered lines to the client. Call it when done.
+func (n *NDJSONWriter) Flush() error {
+	return n.s.flush()
+}
+
+// StreamNDJSON writes every item of next as newline-delimited JSON. next
+// returns false when there are no more items.
+//
+// Nothing is written until the first item is available, so when next fails
+// right away the error is returned with the response untouched and can be
+// answered with WriteErr. When next fails later, the stream ends with a
+// final line holding the problem details WriteErr would have sent, which
+// clients must check for, and the error is returned.
+func StreamNDJSON[T any](w http.ResponseWriter, r *http.Request, next func(ctx context.Context) (T, bool, error)) error {
+	item, ok, err := next(r.Context())
+	if err != nil {
+		return err
+	}
+	n := NewNDJSONWriter(w, r)
+	for ok {
+		if err := n.Write(item); err != nil {
+			return err
+		}
+		if item, ok, err = next(r.Context()); err != nil {
+			if werr := n.enc.Encode(errorProblem(err)); werr != nil {
+				return werr
+			}
+			n.Flush()
+			return err
+		}
+	}
+	return n.Flush()
+}
+
+// StreamJSONArray writes every item of next as a single JSON array without
+// holding all items in memory. next returns false when there are no more
+// items.
+//
+// Nothing is written until the first item is available, so when next fails
+// right away the error is returned with the response untouched and can be
+// answered with WriteErr. Once streaming has started the status can no
+// longer change, so when next fails later or the client disconnects the
+// array is left unterminated; clients see invalid JSON rather than a
+// silently truncated result.
+func StreamJSONArray[T any](w http.ResponseWriter, r *http.Request, next func(ctx context.Context) (T, bool, error)) error {
+	item, ok, err := next(r.Context())
+	if err != nil {
+		return err
+	}
+	s := newStreamWriter(w, r, "application/json; charset=utf-8")
+	if err := s.buf.WriteByte('['); err != nil {
+		return err
+	}
+	for first := true; ok; first = false {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		if !first {
+			if err := s.buf.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if _, err := s.buf.Write(b); err != nil {
+			return err
+		}
+		if err := s.maybeFlush(); err != nil {
+			return err
+		}
+		if item, ok, err = next(r.Context()); err != nil {
+			return err
+		}
+	}
+	if _, err := s.buf.WriteString("]\n"); err != nil {
+		return err
+	}
+	return s.flush()
+}
@@ -0,0 +1,151 @@
+package httputil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sequence returns a next function yielding values, then failing with err
+// when it is set. cancel, when set, is called after the first item.
+func sequence(values []int, err error, cancel context.CancelFunc) func(context.Context) (int, bool, error) {
+	i := 0
+	return func(context.Context) (int, bool, error) {
+		if i == 1 && cancel != nil {
+			cancel()
+		}
+		if i < len(values) {
+			i++
+			return values[i-1], true, nil
+		}
+		return 0, false, err
+	}
+}
+
+var errUpstream = errors.New("upstream failed")
+
+func TestStream(t *testing.T) {
+	type streamFunc func(w http.ResponseWriter, r *http.Request, next func(context.Context) (int, bool, error)) error
+	ndjson, array := streamFunc(StreamNDJSON[int]), streamFunc(StreamJSONArray[int])
+
+	tests := []struct {
+		name            string
+		stream          streamFunc
+		values          []int
+		err             error
+		cancel          bool
+		wantErr         error
+		wantContentType string
+		wantBody        string
+	}{
+		{"ndjson", ndjson, []int{1, 2, 3}, nil, false, nil, "application/x-ndjson", "1\n2\n3\n"},
+		{"ndjson empty", ndjson, nil, nil, false, nil, "application/x-ndjson", ""},
+		{"ndjson immediate failure", ndjson, nil, errUpstream, false, errUpstream, "", ""},
+		{"ndjson late failure", ndjson, []int{1}, errUpstream, false, errUpstream, "application/x-ndjson",
+			"1\n" + `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}` + "\n"},
+		{"ndjson client gone", ndjson, []int{1, 2, 3}, nil, true, context.Canceled, "application/x-ndjson", ""},
+		{"array", array, []int{1, 2, 3}, nil, false, nil, "application/json; charset=utf-8", "[1,2,3]\n"},
+		{"array empty", array, nil, nil, false, nil, "application/json; charset=utf-8", "[]\n"},
+		{"array immediate failure", array, nil, errUpstream, false, errUpstream, "", ""},
+		{"array late failure", array, []int{1, 2}, errUpstream, false, errUpstream, "application/json; charset=utf-8", ""},
+		{"array client gone", array, []int{1, 2, 3}, nil, true, context.Canceled, "application/json; charset=utf-8", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureLog(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var onFirst context.CancelFunc
+			if tt.cancel {
+				onFirst = cancel
+			}
+			req := httptest.NewRequest(http.MethodGet, "/items", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			err := tt.stream(w, req, sequence(tt.values, tt.err, onFirst))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.wantContentType)
+			}
+			if tt.wantContentType == "" && (w.Body.Len() != 0 || w.Flushed) {
+				t.Errorf("response written before the first item: %q", w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantErr != nil && strings.HasSuffix(w.Body.String(), "]\n") {
+				t.Errorf("failed array was terminated: %q", w.Body.String())
+			}
+		})
+	}
+}
+
+// plainWriter hides the recorder's Flush method.
+type plainWriter struct{ http.ResponseWriter }
+
+func TestNDJSONWriter(t *testing.T) {
+	tests := []struct {
+		name      string
+		flushable bool
+	}{
+		{"flushing writer", true},
+		{"writer without Flush", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			var w http.ResponseWriter = rec
+			if !tt.flushable {
+				w = plainWriter{rec}
+			}
+			n := NewNDJSONWriter(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			big := strings.Repeat("x", flushSize)
+			for i := 0; i < 3; i++ {
+				if err := n.Write(map[string]string{"data": big}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if rec.Body.Len() == 0 {
+				t.Error("nothing was sent before Flush despite a full buffer")
+			}
+			if err := n.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("%d lines, want 3", len(lines))
+			}
+			for _, line := range lines {
+				var v map[string]string
+				if err := json.Unmarshal([]byte(line), &v); err != nil || v["data"] != big {
+					t.Errorf("bad line: %v", err)
+				}
+			}
+			if rec.Flushed != tt.flushable {
+				t.Errorf("Flushed = %v, want %v", rec.Flushed, tt.flushable)
+			}
+			if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q", got)
+			}
+		})
+	}
+}
+
+func TestNDJSONWriterRejects(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	n := NewNDJSONWriter(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if err := n.Write(func() {}); err == nil {
+		t.Error("Write encoded a function")
+	}
+	cancel()
+	if err := n.Write(1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Write after disconnect = %v, want context.Canceled", err)
+	}
+}
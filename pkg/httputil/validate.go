@@ -0,0 +1,152 @@
+package httputil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes an invalid field of a request.
+type FieldError struct {
+	// Field is the dotted JSON path of the field, e.g. "address.zip".
+	Field string `json:"field"`
+	// Rule is the failed rule, such as "required" or "max".
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Validator checks a decoded request value, returning a *DecodeError
+// listing the invalid fields.
+type Validator interface {
+	Validate(v interface{}) error
+}
+
+// ValidatorFunc adapts a function to Validator.
+type ValidatorFunc func(v interface{}) error
+
+// Validate implements Validator.
+func (f ValidatorFunc) Validate(v interface{}) error {
+	return f(v)
+}
+
+// NoValidation is a Validator accepting every value.
+var NoValidation Validator = ValidatorFunc(func(interface{}) error { return nil })
+
+// DefaultValidator validates the `validate` struct tags of
+// github.com/go-playground/validator, reporting fields by their JSON
+// names.
+var DefaultValidator Validator = NewTagValidator(nil)
+
+// TagValidator validates struct tags with a go-playground validator.
+type TagValidator struct {
+	validate *validator.Validate
+}
+
+// NewTagValidator wraps v, which may carry custom rules; nil means a new
+// validator. Field names are taken from json tags.
+func NewTagValidator(v *validator.Validate) *TagValidator {
+	return newTagValidator(v, "json")
+}
+
+// newTagValidator reports fields by their name in the given struct tag.
+func newTagValidator(v *validator.Validate, tag string) *TagValidator {
+	if v == nil {
+		v = validator.New(validator.WithRequiredStructEnabled())
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return f.Name
+		}
+		return name
+	})
+	return &TagValidator{validate: v}
+}
+
+// Validate implements Validator. Values other than structs or pointers to
+// structs are accepted as is.
+func (t *TagValidator) Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	err := t.validate.Struct(v)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	fields := make([]FieldError, len(verrs))
+	for i, fe := range verrs {
+		fields[i] = FieldError{
+			Field:   fieldPath(fe.Namespace()),
+			Rule:    fe.Tag(),
+			Message: ruleMessage(fe),
+		}
+	}
+	return &DecodeError{Status: http.StatusBadRequest, Detail: "validation failed", Fields: fields}
+}
+
+// fieldPath strips the root type name from a validator namespace.
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "url", "http_url":
+		return "must be a URL"
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min", "gte":
+		if unit := sizeUnit(fe.Kind()); unit != "" {
+			return "must have at least " + fe.Param() + unit
+		}
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		if unit := sizeUnit(fe.Kind()); unit != "" {
+			return "must have at most " + fe.Param() + unit
+		}
+		return "must be at most " + fe.Param()
+	case "len":
+		return "must have exactly " + fe.Param() + sizeUnit(fe.Kind())
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("must satisfy %s=%s", fe.Tag(), fe.Param())
+	}
+	return "must satisfy " + fe.Tag()
+}
+
+// sizeUnit returns the unit of length-based rules for kind, or an empty
+// string when the rules compare values.
+func sizeUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " elements"
+	}
+	return ""
+}
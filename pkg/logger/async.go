@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/diode"
+)
+
+// AsyncConfig buffers log entries in a lock-free ring buffer written by a
+// background goroutine, so slow outputs never block the caller. Entries
+// are dropped when the buffer is full.
+type AsyncConfig struct {
+	// BufferSize is the number of buffered entries. Defaults to 1000.
+	BufferSize int `yaml:"bufferSize"`
+	// PollInterval is how often the buffer is drained. Defaults to 10ms.
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+var droppedMessages = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "log_messages_dropped_total",
+	Help: "Log entries dropped because the async log buffer was full.",
+})
+
+var dropped atomic.Uint64
+
+// Dropped returns how many entries async writers have dropped.
+func Dropped() uint64 {
+	return dropped.Load()
+}
+
+func newAsyncWriter(out io.Writer, cfg AsyncConfig) io.Writer {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Millisecond
+	}
+	// diode closes its output on Close; hide Close so stdout, stderr or a
+	// caller's writer stay open. Files are closed by their own closer.
+	w := diode.NewWriter(writerOnly{out}, cfg.BufferSize, cfg.PollInterval, func(missed int) {
+		dropped.Add(uint64(missed))
+		droppedMessages.Add(float64(missed))
+	})
+	registerCloser(w)
+	return w
+}
+
+// writerOnly hides any Close method of the wrapped writer.
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter holds every write until release is closed and records
+// whether it was closed.
+type blockingWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	closed  bool
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func TestAsyncFlushesOnClose(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	close(w.release)
+	l, err := New(Config{Logstash: true, Writer: w, Async: &AsyncConfig{PollInterval: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		l.Info().Int("n", i).Msg("buffered")
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if n := strings.Count(w.buf.String(), "buffered"); n != 10 {
+		t.Errorf("flushed %d of 10 entries", n)
+	}
+	if w.closed {
+		t.Error("Close closed the caller's writer")
+	}
+}
+
+func TestAsyncDoesNotBlock(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	before := Dropped()
+	l, err := New(Config{Logstash: true, Writer: w, Async: &AsyncConfig{BufferSize: 4, PollInterval: time.Millisecond}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			l.Info().Msg("burst")
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("logging blocked on a stalled output")
+	}
+
+	close(w.release)
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	if Dropped() == before {
+		t.Error("overflowing entries were not counted as dropped")
+	}
+}
+
+func TestAsyncFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := New(Config{Logstash: true, File: &FileConfig{Path: path}, Async: &AsyncConfig{PollInterval: time.Hour}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info().Msg("async to file")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "async to file") {
+		t.Errorf("entry lost before the file was closed: %q", data)
+	}
+}
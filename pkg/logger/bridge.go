@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// lineWriter turns standard library log output into zerolog events.
+type lineWriter struct {
+	// logger is nil to use the global log.Logger at write time, so bridges
+	// installed before SetupLogger follow it.
+	logger *zerolog.Logger
+	level  zerolog.Level
+	source string
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	l := w.logger
+	if l == nil {
+		l = &log.Logger
+	}
+	event := l.WithLevel(w.level)
+	if w.source != "" {
+		event = event.Str("source", w.source)
+	}
+	event.Msg(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// StdLogger returns a standard library logger writing to l at level, e.g.
+// for http.Server.ErrorLog. A nil l uses the global logger.
+func StdLogger(l *zerolog.Logger, level zerolog.Level, source string) *stdlog.Logger {
+	return stdlog.New(lineWriter{logger: l, level: level, source: source}, "", 0)
+}
+
+// RedirectStdLog routes the standard library's global logger, and with it
+// log.Printf calls in dependencies, to the global zerolog logger at level.
+func RedirectStdLog(level zerolog.Level) {
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(lineWriter{level: level, source: "stdlog"})
+}
+
+// PrintLogger adapts zerolog to loggers with a Print method, such as the
+// MySQL driver's: mysql.SetLogger(logger.PrintLogger{Level: zerolog.ErrorLevel}).
+type PrintLogger struct {
+	// Logger defaults to the global logger.
+	Logger *zerolog.Logger
+	Level  zerolog.Level
+	Source string
+}
+
+// Print logs its arguments like fmt.Sprint.
+func (p PrintLogger) Print(v ...interface{}) {
+	lineWriter{logger: p.Logger, level: p.Level, source: p.Source}.Write([]byte(fmt.Sprint(v...)))
+}
+
+// ContextPrintfLogger adapts zerolog to loggers with a context-aware
+// Printf, such as go-redis's internal logger:
+// redis.SetLogger(logger.ContextPrintfLogger{Level: zerolog.WarnLevel}).
+// Entries go to the request's logger when ctx carries one.
+type ContextPrintfLogger struct {
+	Level  zerolog.Level
+	Source string
+}
+
+// Printf logs a formatted message.
+func (p ContextPrintfLogger) Printf(ctx context.Context, format string, v ...interface{}) {
+	var l *zerolog.Logger
+	if ctx != nil {
+		l = FromContext(ctx)
+	}
+	lineWriter{logger: l, level: p.Level, source: p.Source}.Write([]byte(fmt.Sprintf(format, v...)))
+}
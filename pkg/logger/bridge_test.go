@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stdlog "log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// lastEntry decodes the single entry written to buf.
+func lastEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	buf.Reset()
+	return entry
+}
+
+func TestBridges(t *testing.T) {
+	var global, own bytes.Buffer
+	saved := log.Logger
+	t.Cleanup(func() { log.Logger = saved })
+	log.Logger = zerolog.New(&global)
+	ownLogger := zerolog.New(&own)
+	ctxLogger := zerolog.New(&own).With().Str("request_id", "r1").Logger()
+
+	tests := []struct {
+		name       string
+		out        *bytes.Buffer
+		log        func()
+		wantLevel  string
+		wantSource string
+		wantMsg    string
+		// wantRequestID is set when the entry must come from ctxLogger.
+		wantRequestID string
+	}{
+		{"StdLogger", &own, func() { StdLogger(&ownLogger, zerolog.ErrorLevel, "http").Println("tls handshake error") },
+			"error", "http", "tls handshake error", ""},
+		{"StdLogger global", &global, func() { StdLogger(nil, zerolog.WarnLevel, "").Printf("n=%d", 3) },
+			"warn", "", "n=3", ""},
+		{"PrintLogger", &global, func() { PrintLogger{Level: zerolog.ErrorLevel, Source: "mysql"}.Print("bad conn ", 1) },
+			"error", "mysql", "bad conn 1", ""},
+		{"ContextPrintfLogger with request logger", &own, func() {
+			ContextPrintfLogger{Level: zerolog.WarnLevel, Source: "redis"}.Printf(WithContext(context.Background(), ctxLogger), "pool %s", "exhausted")
+		}, "warn", "redis", "pool exhausted", "r1"},
+		{"ContextPrintfLogger without request logger", &global, func() {
+			ContextPrintfLogger{Level: zerolog.InfoLevel}.Printf(context.Background(), "x")
+		}, "info", "", "x", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.log()
+			entry := lastEntry(t, tt.out)
+			if level, _ := entry["level"].(string); !strings.EqualFold(level, tt.wantLevel) || entry["message"] != tt.wantMsg {
+				t.Errorf("entry = %v", entry)
+			}
+			if source, _ := entry["source"].(string); source != tt.wantSource {
+				t.Errorf("source = %q, want %q", source, tt.wantSource)
+			}
+			if requestID, _ := entry["request_id"].(string); requestID != tt.wantRequestID {
+				t.Errorf("request_id = %q, want %q", requestID, tt.wantRequestID)
+			}
+		})
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	saved := log.Logger
+	t.Cleanup(func() {
+		log.Logger = saved
+		stdlog.SetOutput(os.Stderr)
+		stdlog.SetFlags(stdlog.LstdFlags)
+	})
+	log.Logger = zerolog.New(&buf)
+
+	RedirectStdLog(zerolog.WarnLevel)
+	stdlog.Printf("deprecated option %q", "x")
+	entry := lastEntry(t, &buf)
+	if level, _ := entry["level"].(string); !strings.EqualFold(level, "warn") || entry["source"] != "stdlog" || entry["message"] != `deprecated option "x"` {
+		t.Errorf("entry = %v", entry)
+	}
+
+	// The bridge follows later replacements of the global logger.
+	var replaced bytes.Buffer
+	log.Logger = zerolog.New(&replaced)
+	stdlog.Print("after")
+	if !bytes.Contains(replaced.Bytes(), []byte("after")) {
+		t.Errorf("bridge kept the old global logger")
+	}
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying l.
+func WithContext(ctx context.Context, l zerolog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, &l)
+}
+
+// FromContext returns the logger stored by WithContext, or a copy of the
+// global logger. To add fields, derive a logger with With and store it in
+// a new context with WithContext rather than modifying the returned one.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*zerolog.Logger); ok {
+		return l
+	}
+	l := log.Logger
+	return &l
+}
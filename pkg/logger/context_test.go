@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	stored := zerolog.New(&buf).With().Str("request_id", "r1").Logger()
+	ctx := WithContext(context.Background(), stored)
+
+	FromContext(ctx).Info().Msg("hello")
+	if !strings.Contains(buf.String(), `"request_id":"r1"`) {
+		t.Errorf("stored logger not used: %s", buf.String())
+	}
+}
+
+func TestFromContextDoesNotExposeGlobalLogger(t *testing.T) {
+	var buf bytes.Buffer
+	saved := log.Logger
+	defer func() { log.Logger = saved }()
+	log.Logger = zerolog.New(&buf)
+
+	l := FromContext(context.Background())
+	l.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str("user_id", "u1")
+	})
+	log.Info().Msg("unrelated")
+	if strings.Contains(buf.String(), "user_id") {
+		t.Errorf("enriching the fallback logger changed the global logger: %s", buf.String())
+	}
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+)
+
+func init() {
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+}
+
+// Err adds err to e as structured fields: error.message, error.kind (the
+// type of the innermost error), error.cause (the messages of the wrapped
+// errors, outermost first) and error.stack when the chain holds a
+// github.com/pkg/errors stack trace.
+//
+//	logger.Err(log.Error(), err).Msg("Failed to save order")
+func Err(e *zerolog.Event, err error) *zerolog.Event {
+	if err == nil {
+		return e
+	}
+	root, causes := unwrapChain(err)
+	e = e.Str("error.message", err.Error()).Str("error.kind", fmt.Sprintf("%T", root))
+	if len(causes) > 0 {
+		e = e.Strs("error.cause", causes)
+	}
+	if stack := pkgerrors.MarshalStack(err); stack != nil {
+		e = e.Interface("error.stack", stack)
+	}
+	return e
+}
+
+// unwrapChain returns the innermost error of err and the messages of the
+// errors it wraps, outermost first.
+func unwrapChain(err error) (root error, causes []string) {
+	root = err
+	for {
+		if joined, ok := root.(interface{ Unwrap() []error }); ok {
+			// Joined errors have no single root; list each branch.
+			for _, next := range joined.Unwrap() {
+				if next != nil {
+					causes = append(causes, next.Error())
+				}
+			}
+			return root, causes
+		}
+		next := errors.Unwrap(root)
+		if next == nil {
+			return root, causes
+		}
+		// Skip wrappers that only add a stack trace.
+		if msg := next.Error(); msg != root.Error() {
+			causes = append(causes, msg)
+		}
+		root = next
+	}
+}
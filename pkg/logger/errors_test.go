@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+func TestErr(t *testing.T) {
+	root := &codeError{code: 7}
+	tests := []struct {
+		name      string
+		err       error
+		wantKind  string
+		wantCause []string
+		wantStack bool
+	}{
+		{"plain", errors.New("boom"), "*errors.errorString", nil, false},
+		{"wrapped twice", fmt.Errorf("save order: %w", fmt.Errorf("insert: %w", root)),
+			"*logger.codeError", []string{"insert: code 7", "code 7"}, false},
+		{"sentinel", fmt.Errorf("open config: %w", fs.ErrNotExist), "*errors.errorString", []string{"file does not exist"}, false},
+		{"joined", errors.Join(errors.New("a"), nil, root), "*errors.joinError", []string{"a", "code 7"}, false},
+		{"pkg/errors stack", pkgerrors.Wrap(root, "load"), "*logger.codeError", []string{"code 7"}, true},
+		{"pkg/errors new", pkgerrors.New("bare"), "*errors.fundamental", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := zerolog.New(&buf)
+			Err(l.Error(), tt.err).Msg("failed")
+
+			var entry struct {
+				Message string        `json:"error.message"`
+				Kind    string        `json:"error.kind"`
+				Cause   []string      `json:"error.cause"`
+				Stack   []interface{} `json:"error.stack"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("%v: %s", err, buf.String())
+			}
+			if entry.Message != tt.err.Error() || entry.Kind != tt.wantKind {
+				t.Errorf("message = %q, kind = %q; want %q, %q", entry.Message, entry.Kind, tt.err.Error(), tt.wantKind)
+			}
+			if fmt.Sprint(entry.Cause) != fmt.Sprint(tt.wantCause) {
+				t.Errorf("cause = %q, want %q", entry.Cause, tt.wantCause)
+			}
+			if (len(entry.Stack) > 0) != tt.wantStack {
+				t.Errorf("stack present = %v, want %v", len(entry.Stack) > 0, tt.wantStack)
+			}
+		})
+	}
+}
+
+func TestErrNil(t *testing.T) {
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+	Err(l.Info(), nil).Msg("ok")
+	if bytes.Contains(buf.Bytes(), []byte("error")) {
+		t.Errorf("nil error logged fields: %s", buf.String())
+	}
+}
+
+func TestConfigErrorStack(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		var buf bytes.Buffer
+		l, err := New(Config{Logstash: true, Writer: &buf, ErrorStack: enabled})
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Error().Err(pkgerrors.New("boom")).Msg("failed")
+		if got := bytes.Contains(buf.Bytes(), []byte(`"stack":[`)); got != enabled {
+			t.Errorf("ErrorStack %v: stack logged = %v: %s", enabled, got, buf.String())
+		}
+	}
+}
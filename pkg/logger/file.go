@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileConfig writes logs to a file that is rotated by size.
+type FileConfig struct {
+	Path string
+	// MaxSizeMB rotates the file once it reaches this size. Defaults to
+	// 100.
+	MaxSizeMB int `yaml:"maxSizeMB"`
+	// MaxAgeDays removes rotated files older than this; zero keeps them.
+	MaxAgeDays int `yaml:"maxAgeDays"`
+	// MaxBackups caps the number of rotated files; zero keeps them all.
+	MaxBackups int `yaml:"maxBackups"`
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+func newFileWriter(cfg FileConfig) io.WriteCloser {
+	w := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     cfg.MaxAgeDays,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}
+	registerCloser(w)
+	return w
+}
+
+var (
+	closersMu sync.Mutex
+	closers   []io.Closer
+)
+
+// registerCloser tracks writers opened from a Config so Close can release
+// them.
+func registerCloser(c io.Closer) {
+	closersMu.Lock()
+	closers = append(closers, c)
+	closersMu.Unlock()
+}
+
+// Close flushes and closes every file and buffer opened by SetupLogger and
+// New. Call it once during shutdown, after the last log entry.
+func Close() error {
+	closersMu.Lock()
+	defer closersMu.Unlock()
+	var errs []error
+	// Close in reverse order so buffers flush into files before those
+	// are closed.
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	closers = nil
+	return errors.Join(errs...)
+}
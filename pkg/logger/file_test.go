@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestFileOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		has     []string
+		missing []string
+	}{
+		{"json", FormatLogstash, []string{`"message":"to file"`}, nil},
+		{"console without colors", FormatConsole, []string{"to file"}, []string{"\x1b["}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			l, err := New(Config{Format: tt.format, File: &FileConfig{Path: path}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Info().Msg("to file")
+			if err := Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.has {
+				if !strings.Contains(string(data), s) {
+					t.Errorf("file misses %q: %q", s, data)
+				}
+			}
+			for _, s := range tt.missing {
+				if strings.Contains(string(data), s) {
+					t.Errorf("file has %q: %q", s, data)
+				}
+			}
+		})
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(Config{
+		Level:            int8(zerolog.InfoLevel),
+		Logstash:         true,
+		DisableTimestamp: true,
+		File:             &FileConfig{Path: filepath.Join(dir, "app.log"), MaxSizeMB: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.Repeat("x", 1024)
+	for i := 0; i < 3*1024; i++ {
+		l.Info().Msg(line)
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("no rotation: %v", entries)
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 1<<20 {
+			t.Errorf("%s is %d bytes, above MaxSizeMB", e.Name(), info.Size())
+		}
+	}
+}
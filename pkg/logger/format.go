@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ecsVersion is the Elastic Common Schema version the ECS format follows.
+const ecsVersion = "8.11.0"
+
+// Names of the trace correlation fields, set by SetupLogger according to
+// the format. Middleware enriching loggers with trace context should use
+// them, together with TraceValue.
+var (
+	TraceIDField = "trace_id"
+	SpanIDField  = "span_id"
+)
+
+// traceProject is the GCP project trace IDs are qualified with, set by
+// SetupLogger for the FormatGCP format.
+var traceProject string
+
+// TraceValue returns the value to log under TraceIDField for traceID: the
+// full resource name projects/<project>/traces/<trace ID> in the GCP format
+// with Config.GCPProject set, and traceID otherwise.
+func TraceValue(traceID string) string {
+	if traceProject == "" {
+		return traceID
+	}
+	return "projects/" + traceProject + "/traces/" + traceID
+}
+
+// applyFormat sets zerolog's process-wide field names and level values for
+// format. It must only be called by SetupLogger, since loggers read these
+// globals on every entry.
+func applyFormat(format Format, gcpProject string) {
+	traceProject = ""
+	switch format {
+	case FormatECS:
+		zerolog.TimeFieldFormat = time.RFC3339Nano
+		zerolog.TimestampFieldName = "@timestamp"
+		zerolog.LevelFieldName = "log.level"
+		zerolog.MessageFieldName = "message"
+		zerolog.ErrorFieldName = "error.message"
+		zerolog.CallerFieldName = "log.origin.file.name"
+		setLevelValues("trace", "debug", "info", "warn", "error", "fatal", "panic")
+		TraceIDField, SpanIDField = "trace.id", "span.id"
+	case FormatGCP:
+		zerolog.TimeFieldFormat = time.RFC3339Nano
+		zerolog.TimestampFieldName = "time"
+		zerolog.LevelFieldName = "severity"
+		zerolog.MessageFieldName = "message"
+		zerolog.ErrorFieldName = "error"
+		zerolog.CallerFieldName = "caller"
+		setLevelValues("DEBUG", "DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL", "ALERT")
+		TraceIDField, SpanIDField = "logging.googleapis.com/trace", "logging.googleapis.com/spanId"
+		traceProject = gcpProject
+	default:
+		zerolog.TimeFieldFormat = time.RFC3339
+		logsStructureUpdate()
+		zerolog.LevelPanicValue = "panic"
+		zerolog.ErrorFieldName = "error"
+		zerolog.CallerFieldName = "caller"
+		TraceIDField, SpanIDField = "trace_id", "span_id"
+	}
+}
+
+func setLevelValues(trace, debug, info, warn, err, fatal, panic string) {
+	zerolog.LevelTraceValue = trace
+	zerolog.LevelDebugValue = debug
+	zerolog.LevelInfoValue = info
+	zerolog.LevelWarnValue = warn
+	zerolog.LevelErrorValue = err
+	zerolog.LevelFatalValue = fatal
+	zerolog.LevelPanicValue = panic
+}
+
+func logsStructureUpdate() {
+	zerolog.TimestampFieldName = "@timestamp"
+	zerolog.LevelTraceValue = "TRACE"
+	zerolog.LevelDebugValue = "DEBUG"
+	zerolog.LevelInfoValue = "INFO"
+	zerolog.LevelWarnValue = "WARN"
+	zerolog.LevelErrorValue = "ERROR"
+	zerolog.LevelFatalValue = "FATAL"
+	zerolog.LevelFieldName = "level"
+	zerolog.MessageFieldName = "message"
+}
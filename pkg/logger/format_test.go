@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// setupForTest installs cfg as the global configuration and restores the
+// previous logger and level and the default format when the test ends.
+func setupForTest(t *testing.T, cfg Config) {
+	t.Helper()
+	saved, savedLevel := log.Logger, zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		log.Logger = saved
+		setBaseLevel(savedLevel)
+		applyFormat(FormatLogstash, "")
+	})
+	if err := SetupLogger(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   map[string]interface{}
+		absent []string
+	}{
+		{FormatLogstash, map[string]interface{}{"level": "WARN", "message": "m", "level_value": 30000.0, "service": "svc"}, []string{"severity"}},
+		{FormatECS, map[string]interface{}{"log.level": "warn", "message": "m", "ecs.version": ecsVersion, "service.name": "svc"}, []string{"level"}},
+		{FormatGCP, map[string]interface{}{"severity": "WARNING", "message": "m"}, []string{"level", "level_value"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			setupForTest(t, Config{Format: tt.format, Writer: &buf, DisableCaller: true, ServiceName: "svc"})
+			log.Warn().Msg("m")
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("%v: %s", err, buf.String())
+			}
+			for k, v := range tt.want {
+				if entry[k] != v {
+					t.Errorf("%s = %v, want %v", k, entry[k], v)
+				}
+			}
+			for _, k := range tt.absent {
+				if _, ok := entry[k]; ok {
+					t.Errorf("unexpected field %s", k)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLeavesGlobalFormat(t *testing.T) {
+	setupForTest(t, Config{Logstash: true, Writer: io.Discard})
+	if _, err := New(Config{Format: FormatGCP, Writer: io.Discard}); err != nil {
+		t.Fatal(err)
+	}
+	if zerolog.LevelFieldName != "level" || TraceIDField != "trace_id" {
+		t.Errorf("New changed the global format: level field %q, trace field %q", zerolog.LevelFieldName, TraceIDField)
+	}
+}
+
+func TestTraceValue(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"logstash", Config{Logstash: true, GCPProject: "my-project"}, traceID},
+		{"gcp without project", Config{Format: FormatGCP}, traceID},
+		{"gcp", Config{Format: FormatGCP, GCPProject: "my-project"}, "projects/my-project/traces/" + traceID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Writer = io.Discard
+			setupForTest(t, tt.cfg)
+			if got := TraceValue(traceID); got != tt.want {
+				t.Errorf("TraceValue = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
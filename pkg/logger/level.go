@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	levelMu sync.Mutex
+	// baseLevel is the configured level that temporary changes revert to.
+	baseLevel = zerolog.DebugLevel
+	revert    *time.Timer
+	// generation counts level changes so that a revert timer which fired
+	// while a newer change held levelMu does nothing.
+	generation uint64
+)
+
+func setBaseLevel(level zerolog.Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	baseLevel = level
+	generation++
+	if revert != nil {
+		revert.Stop()
+		revert = nil
+	}
+	zerolog.SetGlobalLevel(level)
+}
+
+// Level returns the current global level.
+func Level() zerolog.Level {
+	return zerolog.GlobalLevel()
+}
+
+// SetLevel changes the global level until the next change.
+func SetLevel(level zerolog.Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	setLevelLocked(level)
+}
+
+// SetLevelFor changes the global level and restores the configured level
+// after d, e.g. to debug a production service for a few minutes.
+func SetLevelFor(level zerolog.Level, d time.Duration) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	setLevelLocked(level)
+	gen := generation
+	revert = time.AfterFunc(d, func() {
+		levelMu.Lock()
+		defer levelMu.Unlock()
+		if generation == gen {
+			setLevelLocked(baseLevel)
+		}
+	})
+}
+
+// ResetLevel restores the level configured by SetupLogger.
+func ResetLevel() {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	setLevelLocked(baseLevel)
+}
+
+// setLevelLocked sets the level and cancels a pending revert. Callers must
+// hold levelMu.
+func setLevelLocked(level zerolog.Level) {
+	generation++
+	if revert != nil {
+		revert.Stop()
+		revert = nil
+	}
+	if old := zerolog.GlobalLevel(); old != level {
+		zerolog.SetGlobalLevel(level)
+		log.WithLevel(zerolog.NoLevel).Str("from", old.String()).Str("to", level.String()).Msg("Log level changed")
+	}
+}
+
+type levelState struct {
+	Level string `json:"level"`
+	// Duration, e.g. "15m", reverts the change after that long.
+	Duration string `json:"duration,omitempty"`
+}
+
+// LevelHandler serves the global level for an admin server: GET returns
+// it, other methods take {"level": "debug", "duration": "15m"} where
+// duration is optional. Mount it on Gin with gin.WrapF.
+func LevelHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			var state levelState
+			if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+				writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": `expected {"level": string, "duration": string}`})
+				return
+			}
+			level, err := parseLevel(state.Level)
+			if err != nil {
+				writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			if state.Duration == "" {
+				SetLevel(level)
+			} else {
+				d, err := time.ParseDuration(state.Duration)
+				if err != nil || d <= 0 {
+					writeLevelJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid duration"})
+					return
+				}
+				SetLevelFor(level, d)
+			}
+		}
+		writeLevelJSON(w, http.StatusOK, levelState{Level: Level().String()})
+	}
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// levelNames are accepted whatever level values the format uses.
+var levelNames = map[string]zerolog.Level{
+	"trace":   zerolog.TraceLevel,
+	"debug":   zerolog.DebugLevel,
+	"info":    zerolog.InfoLevel,
+	"warn":    zerolog.WarnLevel,
+	"warning": zerolog.WarnLevel,
+	"error":   zerolog.ErrorLevel,
+	"fatal":   zerolog.FatalLevel,
+	"panic":   zerolog.PanicLevel,
+}
+
+func parseLevel(s string) (zerolog.Level, error) {
+	if s == "" {
+		return zerolog.NoLevel, errors.New("level is required")
+	}
+	if level, ok := levelNames[strings.ToLower(s)]; ok {
+		return level, nil
+	}
+	level, err := zerolog.ParseLevel(s)
+	if err != nil || level == zerolog.NoLevel {
+		return zerolog.NoLevel, errors.New("unknown level " + s)
+	}
+	return level, nil
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    zerolog.Level
+		wantErr bool
+	}{
+		{"debug", zerolog.DebugLevel, false},
+		{"WARNING", zerolog.WarnLevel, false},
+		{"Error", zerolog.ErrorLevel, false},
+		{"-1", zerolog.TraceLevel, false},
+		{"", zerolog.NoLevel, true},
+		{"verbose", zerolog.NoLevel, true},
+	}
+	for _, tt := range tests {
+		got, err := parseLevel(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseLevel(%q) = %v, %v; want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetLevelFor(t *testing.T) {
+	setupForTest(t, Config{Level: int8(zerolog.InfoLevel), Logstash: true, Writer: io.Discard})
+
+	SetLevelFor(zerolog.DebugLevel, 10*time.Millisecond)
+	if Level() != zerolog.DebugLevel {
+		t.Fatalf("level = %v, want debug", Level())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if Level() != zerolog.InfoLevel {
+		t.Errorf("level after revert = %v, want info", Level())
+	}
+
+	// A later change cancels the pending revert.
+	SetLevelFor(zerolog.DebugLevel, 10*time.Millisecond)
+	SetLevel(zerolog.ErrorLevel)
+	time.Sleep(50 * time.Millisecond)
+	if Level() != zerolog.ErrorLevel {
+		t.Errorf("stale revert applied: level = %v, want error", Level())
+	}
+	ResetLevel()
+	if Level() != zerolog.InfoLevel {
+		t.Errorf("level after ResetLevel = %v, want info", Level())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	setupForTest(t, Config{Level: int8(zerolog.InfoLevel), Logstash: true, Writer: io.Discard})
+	h := LevelHandler()
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		wantCode  int
+		wantLevel string
+	}{
+		{"get", http.MethodGet, "", http.StatusOK, "info"},
+		{"set", http.MethodPut, `{"level":"debug"}`, http.StatusOK, "debug"},
+		{"set for duration", http.MethodPost, `{"level":"warn","duration":"1h"}`, http.StatusOK, "warn"},
+		{"unknown level", http.MethodPut, `{"level":"loud"}`, http.StatusBadRequest, "warn"},
+		{"missing level", http.MethodPut, `{}`, http.StatusBadRequest, "warn"},
+		{"bad duration", http.MethodPut, `{"level":"debug","duration":"soon"}`, http.StatusBadRequest, "warn"},
+		{"negative duration", http.MethodPut, `{"level":"debug","duration":"-1m"}`, http.StatusBadRequest, "warn"},
+		{"not JSON", http.MethodPut, `level=debug`, http.StatusBadRequest, "warn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest(tt.method, "/log/level", strings.NewReader(tt.body)))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if got := Level().String(); !strings.EqualFold(got, tt.wantLevel) {
+				t.Errorf("level = %s, want %s", got, tt.wantLevel)
+			}
+			if tt.wantCode == http.StatusOK {
+				var state levelState
+				if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil || !strings.EqualFold(state.Level, tt.wantLevel) {
+					t.Errorf("body = %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
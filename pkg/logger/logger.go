@@ -1,56 +1,231 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
-type ConfigSchema struct {
-	Level    int8
+// Format selects how log entries are encoded.
+type Format string
+
+const (
+	// FormatConsole writes human-readable lines for local development.
+	FormatConsole Format = "console"
+	// FormatLogstash writes JSON with @timestamp and level_value fields.
+	FormatLogstash Format = "logstash"
+	// FormatECS writes Elastic Common Schema fields such as log.level and
+	// trace.id.
+	FormatECS Format = "ecs"
+	// FormatGCP follows the Cloud Logging structured logging conventions:
+	// severity, time and logging.googleapis.com/trace.
+	FormatGCP Format = "gcp"
+)
+
+// Config configures both the global logger and instance loggers.
+type Config struct {
+	Level int8
+	// Logstash is shorthand for Format: FormatLogstash.
 	Logstash bool
+	// Format defaults to FormatConsole, or FormatLogstash when Logstash
+	// is set. Field names are process-wide in zerolog and set by
+	// SetupLogger, so loggers built with New should use the same format.
+	Format Format
+	// GCPProject qualifies trace IDs in the FormatGCP format as
+	// projects/<GCPProject>/traces/<trace ID>, which Cloud Logging needs
+	// to link entries to traces.
+	GCPProject string `yaml:"gcpProject"`
+	// Writer defaults to os.Stdout.
+	Writer io.Writer `yaml:"-"`
+	// File writes to a rotating file instead of Writer.
+	File *FileConfig
+	// Async decouples logging from the output; call Close on shutdown to
+	// flush it.
+	Async *AsyncConfig
+	// Outputs writes to several destinations at once, each optionally
+	// restricted to a range of levels. When set, Writer, File and Async
+	// are ignored.
+	Outputs          []Output
+	DisableCaller    bool `yaml:"disableCaller"`
+	DisableTimestamp bool `yaml:"disableTimestamp"`
+	// ServiceName, Version and Environment are added to every entry, as
+	// service.name, service.version and service.environment in the ECS
+	// format, in serviceContext for GCP and as service, version and
+	// environment otherwise.
+	ServiceName string `yaml:"serviceName"`
+	Version     string `yaml:"version"`
+	Environment string `yaml:"environment"`
+	// Fields are added to every entry. Keys used by the logger itself,
+	// such as level, time and message, are rejected.
+	Fields map[string]string `yaml:"fields"`
+	// ErrorStack adds the stack trace of github.com/pkg/errors errors to
+	// every event logged with Err.
+	ErrorStack bool `yaml:"errorStack"`
 }
 
-func SetupLogger(loggingConfig ConfigSchema) {
-	zerolog.SetGlobalLevel(zerolog.Level(loggingConfig.Level))
+// ConfigSchema is the previous name of Config.
+type ConfigSchema = Config
 
-	log.Logger = createBaseLogger(loggingConfig)
-	if loggingConfig.Logstash {
-		log.Logger = log.Logger.Hook(NewLevelValueHook())
+// SetupLogger configures the global level and replaces log.Logger. The
+// level can later be changed at runtime with SetLevel. An invalid Config
+// is reported without changing anything.
+func SetupLogger(loggingConfig Config) error {
+	l, err := newLogger(loggingConfig)
+	if err != nil {
+		return err
 	}
+	applyFormat(loggingConfig.format(), loggingConfig.GCPProject)
+	setBaseLevel(zerolog.Level(loggingConfig.Level))
+	log.Logger = l
+	return nil
 }
 
-func createBaseLogger(loggingConfig ConfigSchema) zerolog.Logger {
-	var loggerWriter io.Writer
-	if loggingConfig.Logstash {
-		loggerWriter = os.Stdout
-	} else {
-		loggerWriter = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.StampNano}
+// New returns a logger configured like SetupLogger would configure the
+// global one, without touching global state. It uses the field names set
+// by SetupLogger, so it is safe to call while other goroutines log.
+func New(cfg Config) (zerolog.Logger, error) {
+	l, err := newLogger(cfg)
+	if err != nil {
+		return zerolog.Nop(), err
 	}
+	return l.Level(zerolog.Level(cfg.Level)), nil
+}
+
+// Validate reports configuration mistakes such as unknown output levels
+// or reserved field keys. SetupLogger and New return its error.
+func (cfg Config) Validate() error {
+	for i, o := range cfg.Outputs {
+		if _, _, err := o.levels(); err != nil {
+			return fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+	}
+	for k := range cfg.Fields {
+		if reservedFields[k] || strings.HasPrefix(k, "service.") {
+			return fmt.Errorf("field %q is reserved", k)
+		}
+	}
+	return nil
+}
+
+// reservedFields are written by the logger in at least one format, so a
+// custom field of that name would produce a duplicate key.
+var reservedFields = map[string]bool{
+	"level": true, "log.level": true, "severity": true, "level_value": true,
+	"time": true, "@timestamp": true, "message": true,
+	"error": true, "error.message": true, "error.kind": true, "error.cause": true,
+	"error.stack": true, "stack": true, "caller": true, "log.origin.file.name": true,
+	"ecs.version": true, "service": true, "version": true, "environment": true,
+	"serviceContext": true,
+}
 
-	zerolog.TimeFieldFormat = time.RFC3339
-	logsStructureUpdate()
+// newLogger builds a logger whose level is only gated by the global level.
+func newLogger(cfg Config) (zerolog.Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return zerolog.Nop(), fmt.Errorf("invalid logger configuration: %w", err)
+	}
 
-	return zerolog.New(loggerWriter).
-		With().
-		Timestamp().
-		Caller().
-		Logger()
+	ctx := zerolog.New(cfg.writer()).With()
+	if !cfg.DisableTimestamp {
+		ctx = ctx.Timestamp()
+	}
+	if !cfg.DisableCaller {
+		ctx = ctx.Caller()
+	}
+	if cfg.ErrorStack {
+		ctx = ctx.Stack()
+	}
+	if cfg.format() == FormatECS {
+		ctx = ctx.Str("ecs.version", ecsVersion)
+	}
+	ctx = cfg.serviceFields(ctx)
+	for k, v := range cfg.Fields {
+		ctx = ctx.Str(k, v)
+	}
+	logger := ctx.Logger()
+	if cfg.format() == FormatLogstash {
+		logger = logger.Hook(NewLevelValueHook())
+	}
+	return logger, nil
 }
 
-func logsStructureUpdate() {
-	zerolog.TimestampFieldName = "@timestamp"
-	zerolog.LevelTraceValue = "TRACE"
-	zerolog.LevelDebugValue = "DEBUG"
-	zerolog.LevelInfoValue = "INFO"
-	zerolog.LevelWarnValue = "WARN"
-	zerolog.LevelErrorValue = "ERROR"
-	zerolog.LevelFatalValue = "FATAL"
-	zerolog.LevelFieldName = "level"
-	zerolog.MessageFieldName = "message"
+func (cfg Config) serviceFields(ctx zerolog.Context) zerolog.Context {
+	switch cfg.format() {
+	case FormatECS:
+		ctx = optionalStr(ctx, "service.name", cfg.ServiceName)
+		ctx = optionalStr(ctx, "service.version", cfg.Version)
+		return optionalStr(ctx, "service.environment", cfg.Environment)
+	case FormatGCP:
+		// Error Reporting groups entries by serviceContext.
+		if cfg.ServiceName != "" {
+			ctx = ctx.Dict("serviceContext", zerolog.Dict().
+				Str("service", cfg.ServiceName).
+				Str("version", cfg.Version))
+		}
+		return optionalStr(ctx, "environment", cfg.Environment)
+	}
+	ctx = optionalStr(ctx, "service", cfg.ServiceName)
+	ctx = optionalStr(ctx, "version", cfg.Version)
+	return optionalStr(ctx, "environment", cfg.Environment)
+}
+
+func optionalStr(ctx zerolog.Context, key, value string) zerolog.Context {
+	if value == "" {
+		return ctx
+	}
+	return ctx.Str(key, value)
+}
+
+func (cfg Config) format() Format {
+	if cfg.Format != "" {
+		return cfg.Format
+	}
+	if cfg.Logstash {
+		return FormatLogstash
+	}
+	return FormatConsole
+}
+
+func (cfg Config) writer() io.Writer {
+	if len(cfg.Outputs) == 0 {
+		return cfg.output(Output{Writer: cfg.Writer, File: cfg.File, Async: cfg.Async})
+	}
+	writers := make([]io.Writer, 0, len(cfg.Outputs))
+	for _, o := range cfg.Outputs {
+		writers = append(writers, newLevelFilter(cfg.output(o), o))
+	}
+	return zerolog.MultiLevelWriter(writers...)
+}
+
+func (cfg Config) output(o Output) io.Writer {
+	out := o.Writer
+	switch {
+	case o.File != nil:
+		out = newFileWriter(*o.File)
+	case out == nil && o.Target == TargetStderr:
+		out = os.Stderr
+	case out == nil:
+		out = os.Stdout
+	}
+	format := o.Format
+	if format == "" {
+		format = cfg.format()
+	}
+	if format == FormatConsole {
+		cw := zerolog.ConsoleWriter{Out: out, TimeFormat: time.StampNano, NoColor: o.File != nil}
+		if cfg.DisableTimestamp {
+			cw.PartsExclude = []string{zerolog.TimestampFieldName}
+		}
+		out = cw
+	}
+	if o.Async != nil {
+		out = newAsyncWriter(out, *o.Async)
+	}
+	return out
 }
 
 type LevelValueHook struct {
@@ -0,0 +1,238 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"empty", Config{}, false},
+		{"output levels", Config{Outputs: []Output{{MinLevel: "debug", MaxLevel: "warn"}, {MinLevel: "error"}}}, false},
+		{"warning alias", Config{Outputs: []Output{{MinLevel: "warning"}}}, false},
+		{"unknown min level", Config{Outputs: []Output{{MinLevel: "verbose"}}}, true},
+		{"unknown max level", Config{Outputs: []Output{{MaxLevel: "loud"}}}, true},
+		{"inverted range", Config{Outputs: []Output{{MinLevel: "error", MaxLevel: "info"}}}, true},
+		{"custom field", Config{Fields: map[string]string{"team": "payments"}}, false},
+		{"reserved field", Config{Fields: map[string]string{"level": "x"}}, true},
+		{"reserved ECS field", Config{Fields: map[string]string{"@timestamp": "x"}}, true},
+		{"service prefix", Config{Fields: map[string]string{"service.owner": "x"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetupLoggerRejectsInvalidConfig(t *testing.T) {
+	var buf bytes.Buffer
+	saved := log.Logger
+	defer func() { log.Logger = saved }()
+	log.Logger = zerolog.New(&buf)
+
+	err := SetupLogger(Config{Logstash: true, Outputs: []Output{{MinLevel: "verbose"}}})
+	if err == nil {
+		t.Fatal("SetupLogger succeeded with an invalid output level")
+	}
+	log.Info().Msg("still here")
+	if !strings.Contains(buf.String(), "still here") {
+		t.Error("SetupLogger replaced the global logger despite the error")
+	}
+	if _, err := New(Config{Logstash: true, Fields: map[string]string{"message": "x"}}); err == nil {
+		t.Error("New succeeded with a reserved field")
+	}
+}
+
+func TestOutputsRouteByLevel(t *testing.T) {
+	var stdout, errors bytes.Buffer
+	l, err := New(Config{
+		Level:         int8(zerolog.DebugLevel),
+		Logstash:      true,
+		DisableCaller: true,
+		Outputs: []Output{
+			{Writer: &stdout, MinLevel: "debug", MaxLevel: "warn"},
+			{Writer: &errors, MinLevel: "error"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug().Msg("debug entry")
+	l.Warn().Msg("warn entry")
+	l.Error().Msg("error entry")
+
+	for _, tt := range []struct {
+		out      *bytes.Buffer
+		has, not []string
+	}{
+		{&stdout, []string{"debug entry", "warn entry"}, []string{"error entry"}},
+		{&errors, []string{"error entry"}, []string{"debug entry", "warn entry"}},
+	} {
+		for _, s := range tt.has {
+			if !strings.Contains(tt.out.String(), s) {
+				t.Errorf("output misses %q: %s", s, tt.out)
+			}
+		}
+		for _, s := range tt.not {
+			if strings.Contains(tt.out.String(), s) {
+				t.Errorf("output has %q: %s", s, tt.out)
+			}
+		}
+	}
+}
+
+func TestNewMatchesSetupLogger(t *testing.T) {
+	var global, instance bytes.Buffer
+	base := Config{Logstash: true, DisableTimestamp: true, DisableCaller: true, ServiceName: "svc", Fields: map[string]string{"team": "core"}}
+
+	cfg := base
+	cfg.Writer = &global
+	setupForTest(t, cfg)
+	log.Warn().Msg("m")
+
+	cfg.Writer = &instance
+	l, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Warn().Msg("m")
+
+	if global.String() != instance.String() {
+		t.Errorf("New and SetupLogger differ:\n%s%s", global.String(), instance.String())
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		log     func(l zerolog.Logger)
+		has     []string
+		missing []string
+	}{
+		{"level filters", Config{Level: int8(zerolog.WarnLevel), Logstash: true},
+			func(l zerolog.Logger) { l.Info().Msg("quiet"); l.Warn().Msg("loud") },
+			[]string{"loud"}, []string{"quiet"}},
+		{"timestamp and caller", Config{Logstash: true},
+			func(l zerolog.Logger) { l.Info().Msg("m") },
+			[]string{`"@timestamp"`, `"caller"`}, nil},
+		{"disabled timestamp and caller", Config{Logstash: true, DisableTimestamp: true, DisableCaller: true},
+			func(l zerolog.Logger) { l.Info().Msg("m") },
+			nil, []string{`"@timestamp"`, `"caller"`}},
+		{"console", Config{DisableTimestamp: true},
+			func(l zerolog.Logger) { l.Info().Str("k", "v").Msg("hello") },
+			[]string{"hello", "INF"}, []string{"{"}},
+		{"service metadata", Config{Logstash: true, ServiceName: "svc", Version: "1.2.3", Environment: "prod"},
+			func(l zerolog.Logger) { l.Info().Msg("m") },
+			[]string{`"service":"svc"`, `"version":"1.2.3"`, `"environment":"prod"`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupForTest(t, Config{Logstash: true, Writer: io.Discard})
+			var buf bytes.Buffer
+			tt.cfg.Writer = &buf
+			l, err := New(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.log(l)
+			for _, s := range tt.has {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("output misses %s: %s", s, buf.String())
+				}
+			}
+			for _, s := range tt.missing {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("output has %s: %s", s, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServiceFields(t *testing.T) {
+	cfg := Config{DisableCaller: true, DisableTimestamp: true, ServiceName: "svc", Version: "1.2.3", Environment: "prod",
+		Fields: map[string]string{"team": "core"}}
+	tests := []struct {
+		format Format
+		want   map[string]interface{}
+		absent []string
+	}{
+		{FormatLogstash,
+			map[string]interface{}{"service": "svc", "version": "1.2.3", "environment": "prod", "team": "core"},
+			[]string{"service.name", "serviceContext"}},
+		{FormatECS,
+			map[string]interface{}{"service.name": "svc", "service.version": "1.2.3", "service.environment": "prod", "team": "core"},
+			[]string{"service", "version", "environment"}},
+		{FormatGCP,
+			map[string]interface{}{
+				"serviceContext": map[string]interface{}{"service": "svc", "version": "1.2.3"},
+				"environment":    "prod", "team": "core",
+			},
+			[]string{"service", "service.name"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := cfg
+			cfg.Format, cfg.Writer = tt.format, &buf
+			setupForTest(t, cfg)
+			log.Info().Msg("m")
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("%v: %s", err, buf.String())
+			}
+			for k, v := range tt.want {
+				if !reflect.DeepEqual(entry[k], v) {
+					t.Errorf("%s = %v, want %v", k, entry[k], v)
+				}
+			}
+			for _, k := range tt.absent {
+				if _, ok := entry[k]; ok {
+					t.Errorf("unexpected field %s", k)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceFieldsOmittedWhenEmpty(t *testing.T) {
+	for _, format := range []Format{FormatLogstash, FormatECS, FormatGCP} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			setupForTest(t, Config{Format: format, Writer: &buf, DisableCaller: true})
+			log.Info().Msg("m")
+			for _, k := range []string{"service", "service.name", "serviceContext", "version", "environment"} {
+				if strings.Contains(buf.String(), `"`+k+`"`) {
+					t.Errorf("unexpected field %s: %s", k, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestReservedFieldsRejected(t *testing.T) {
+	for k := range reservedFields {
+		t.Run(k, func(t *testing.T) {
+			_, err := New(Config{Logstash: true, Writer: io.Discard, Fields: map[string]string{k: "x"}})
+			if err == nil || !strings.Contains(err.Error(), "reserved") {
+				t.Errorf("New() error = %v, want reserved field error", err)
+			}
+		})
+	}
+}
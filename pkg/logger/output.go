@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog"
+)
+
+// Output targets.
+const (
+	TargetStdout = "stdout"
+	TargetStderr = "stderr"
+)
+
+// Output is one log destination of Config.Outputs.
+type Output struct {
+	// Target is TargetStdout (the default) or TargetStderr.
+	Target string
+	// Writer overrides Target.
+	Writer io.Writer `yaml:"-"`
+	// File writes to a rotating file instead of Target.
+	File *FileConfig
+	// Format overrides Config.Format for this output. Only the choice
+	// between FormatConsole and JSON can differ per output, since JSON
+	// field names are shared.
+	Format Format
+	// MinLevel and MaxLevel restrict the output to a range of levels,
+	// e.g. "error" for an error file or "debug" to "warn" for stdout.
+	// Empty means unbounded.
+	MinLevel string `yaml:"minLevel"`
+	MaxLevel string `yaml:"maxLevel"`
+	Async    *AsyncConfig
+}
+
+// levelFilter passes entries within [min, max] to w.
+type levelFilter struct {
+	w        io.Writer
+	min, max zerolog.Level
+}
+
+func newLevelFilter(w io.Writer, o Output) zerolog.LevelWriter {
+	// Levels were checked by Config.Validate.
+	min, max, _ := o.levels()
+	return levelFilter{w: w, min: min, max: max}
+}
+
+// levels parses MinLevel and MaxLevel, defaulting to an unbounded range.
+func (o Output) levels() (min, max zerolog.Level, err error) {
+	min, max = zerolog.TraceLevel, zerolog.PanicLevel
+	if o.MinLevel != "" {
+		if min, err = parseLevel(o.MinLevel); err != nil {
+			return min, max, fmt.Errorf("output minLevel: %w", err)
+		}
+	}
+	if o.MaxLevel != "" {
+		if max, err = parseLevel(o.MaxLevel); err != nil {
+			return min, max, fmt.Errorf("output maxLevel: %w", err)
+		}
+	}
+	if min > max {
+		return min, max, fmt.Errorf("output minLevel %s is above maxLevel %s", o.MinLevel, o.MaxLevel)
+	}
+	return min, max, nil
+}
+
+func (f levelFilter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+// WriteLevel drops entries outside the range. Entries without a level,
+// such as level change notices, always pass.
+func (f levelFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level != zerolog.NoLevel && (level < f.min || level > f.max) {
+		return len(p), nil
+	}
+	return f.w.Write(p)
+}
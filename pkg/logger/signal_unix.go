@@ -0,0 +1,35 @@
+//go:build !windows
+
+package logger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+)
+
+// WatchSignals toggles debug logging on SIGUSR1 and restores the
+// configured level on SIGHUP, until ctx is done.
+func WatchSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				if sig == syscall.SIGHUP || Level() == zerolog.DebugLevel {
+					ResetLevel()
+				} else {
+					SetLevel(zerolog.DebugLevel)
+				}
+			}
+		}
+	}()
+}
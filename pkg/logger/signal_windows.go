@@ -0,0 +1,6 @@
+package logger
+
+import "context"
+
+// WatchSignals is a no-op on Windows, which has no SIGUSR1 or SIGHUP.
+func WatchSignals(ctx context.Context) {}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+)
+
+// APIKeyContextKey is the Gin context key under which the authenticated
+// KeyInfo is stored.
+const APIKeyContextKey = "middleware.apiKey"
+
+// DefaultAPIKeyHeader is the header inspected when APIKeyConfig.Header is empty.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// ErrKeyNotFound is returned by a KeyStore when the presented key is unknown.
+var ErrKeyNotFound = errors.New("api key not found")
+
+var apiKeyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_api_key_requests_total",
+	Help: "Requests authenticated by API key, partitioned by key ID and result.",
+}, []string{"key_id", "result"})
+
+// KeyInfo describes an API key known to a KeyStore.
+type KeyInfo struct {
+	ID     string
+	Scopes []string
+}
+
+// HasScope reports whether the key has been granted scope.
+func (k *KeyInfo) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyStore resolves a presented API key to its KeyInfo.
+type KeyStore interface {
+	Lookup(ctx context.Context, key string) (*KeyInfo, error)
+}
+
+// StaticKeyStore is a KeyStore backed by an in-memory map of key to KeyInfo.
+type StaticKeyStore map[string]KeyInfo
+
+// Lookup compares key against every configured key in constant time.
+func (s StaticKeyStore) Lookup(_ context.Context, key string) (*KeyInfo, error) {
+	var found *KeyInfo
+	for k, info := range s {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			info := info
+			found = &info
+		}
+	}
+	if found == nil {
+		return nil, ErrKeyNotFound
+	}
+	return found, nil
+}
+
+// DefaultSQLKeyQuery selects the ID and comma separated scopes of a key by
+// the hex encoded SHA-256 hash of its value.
+const DefaultSQLKeyQuery = "SELECT id, scopes FROM api_keys WHERE key_hash = ?"
+
+// SQLKeyStore is a KeyStore backed by a database table. Keys are never
+// stored in plain text; the query receives the hex encoded SHA-256 hash.
+type SQLKeyStore struct {
+	DB    *sql.DB
+	Query string
+}
+
+// NewSQLKeyStore returns a SQLKeyStore using DefaultSQLKeyQuery.
+func NewSQLKeyStore(db *sql.DB) *SQLKeyStore {
+	return &SQLKeyStore{DB: db, Query: DefaultSQLKeyQuery}
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of key as stored by SQLKeyStore.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lookup queries the database for the hashed key.
+func (s *SQLKeyStore) Lookup(ctx context.Context, key string) (*KeyInfo, error) {
+	query := s.Query
+	if query == "" {
+		query = DefaultSQLKeyQuery
+	}
+
+	var id, scopes string
+	err := s.DB.QueryRowContext(ctx, query, HashAPIKey(key)).Scan(&id, &scopes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := &KeyInfo{ID: id}
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			info.Scopes = append(info.Scopes, scope)
+		}
+	}
+	return info, nil
+}
+
+// APIKeyConfig configures the APIKey middleware.
+type APIKeyConfig struct {
+	// Header carrying the key. Defaults to DefaultAPIKeyHeader.
+	Header string
+	// QueryParam is consulted when the header is absent. Disabled when empty.
+	QueryParam string
+	// Store resolves presented keys.
+	Store KeyStore
+	// Scopes that every key must hold to pass.
+	Scopes []string
+}
+
+// APIKey authenticates requests against the configured KeyStore, enforces
+// the required scopes and stores the resolved KeyInfo in the context. A
+// Store is required.
+func APIKey(cfg APIKeyConfig) (gin.HandlerFunc, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("API key authentication requires a Store")
+	}
+	header := cfg.Header
+	if header == "" {
+		header = DefaultAPIKeyHeader
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(header)
+		if key == "" && cfg.QueryParam != "" {
+			key = c.Query(cfg.QueryParam)
+		}
+		if key == "" {
+			apiKeyRequests.WithLabelValues("", "missing").Inc()
+			abortWithProblem(c, http.StatusUnauthorized, "missing API key")
+			return
+		}
+
+		info, err := cfg.Store.Lookup(c.Request.Context(), key)
+		if errors.Is(err, ErrKeyNotFound) {
+			apiKeyRequests.WithLabelValues("", "invalid").Inc()
+			abortWithProblem(c, http.StatusUnauthorized, "invalid API key")
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to look up API key")
+			apiKeyRequests.WithLabelValues("", "error").Inc()
+			abortWithProblem(c, http.StatusServiceUnavailable, "API key store unavailable")
+			return
+		}
+
+		c.Set(APIKeyContextKey, info)
+		if !hasScopes(info, cfg.Scopes) {
+			apiKeyRequests.WithLabelValues(info.ID, "forbidden").Inc()
+			abortWithProblem(c, http.StatusForbidden, "API key lacks required scope")
+			return
+		}
+
+		apiKeyRequests.WithLabelValues(info.ID, "ok").Inc()
+		c.Next()
+	}, nil
+}
+
+// RequireScopes rejects requests whose API key lacks any of scopes. It must
+// run after APIKey and allows per-route scope requirements.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, ok := APIKeyFromContext(c)
+		if !ok {
+			abortWithProblem(c, http.StatusUnauthorized, "missing API key")
+			return
+		}
+		if !hasScopes(info, scopes) {
+			abortWithProblem(c, http.StatusForbidden, "API key lacks required scope")
+			return
+		}
+		c.Next()
+	}
+}
+
+// APIKeyFromContext returns the KeyInfo stored by APIKey.
+func APIKeyFromContext(c *gin.Context) (*KeyInfo, bool) {
+	v, ok := c.Get(APIKeyContextKey)
+	if !ok {
+		return nil, false
+	}
+	info, ok := v.(*KeyInfo)
+	return info, ok
+}
+
+func hasScopes(info *KeyInfo, scopes []string) bool {
+	for _, scope := range scopes {
+		if !info.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
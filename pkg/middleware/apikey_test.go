@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type failingKeyStore struct{}
+
+func (failingKeyStore) Lookup(context.Context, string) (*KeyInfo, error) {
+	return nil, errors.New("database down")
+}
+
+func TestAPIKey(t *testing.T) {
+	store := StaticKeyStore{
+		"secret-admin":  {ID: "admin", Scopes: []string{"read", "write"}},
+		"secret-reader": {ID: "reader", Scopes: []string{"read"}},
+	}
+	tests := []struct {
+		name    string
+		cfg     APIKeyConfig
+		target  string
+		headers []string
+		want    int
+		wantID  string
+	}{
+		{"valid header", APIKeyConfig{Store: store}, "/", []string{"X-API-Key", "secret-reader"}, http.StatusOK, "reader"},
+		{"custom header", APIKeyConfig{Store: store, Header: "Api-Key"}, "/", []string{"Api-Key", "secret-reader"}, http.StatusOK, "reader"},
+		{"missing", APIKeyConfig{Store: store}, "/", nil, http.StatusUnauthorized, ""},
+		{"unknown key", APIKeyConfig{Store: store}, "/", []string{"X-API-Key", "secret-guess"}, http.StatusUnauthorized, ""},
+		{"query param disabled", APIKeyConfig{Store: store}, "/?api_key=secret-reader", nil, http.StatusUnauthorized, ""},
+		{"query param", APIKeyConfig{Store: store, QueryParam: "api_key"}, "/?api_key=secret-reader", nil, http.StatusOK, "reader"},
+		{"scope granted", APIKeyConfig{Store: store, Scopes: []string{"write"}}, "/", []string{"X-API-Key", "secret-admin"}, http.StatusOK, "admin"},
+		{"scope missing", APIKeyConfig{Store: store, Scopes: []string{"write"}}, "/", []string{"X-API-Key", "secret-reader"}, http.StatusForbidden, ""},
+		{"store unavailable", APIKeyConfig{Store: failingKeyStore{}}, "/", []string{"X-API-Key", "secret-reader"}, http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := APIKey(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotID string
+			w := serve(request(http.MethodGet, tt.target, "192.0.2.1:1234", tt.headers...), handler, func(c *gin.Context) {
+				if info, ok := APIKeyFromContext(c); ok {
+					gotID = info.ID
+				}
+			})
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if gotID != tt.wantID {
+				t.Errorf("key ID = %q, want %q", gotID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRequiresStore(t *testing.T) {
+	if _, err := APIKey(APIKeyConfig{}); err == nil {
+		t.Error("nil Store accepted")
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	handler, _ := APIKey(APIKeyConfig{Store: StaticKeyStore{"k": {ID: "k", Scopes: []string{"read"}}}})
+	if w := serve(request(http.MethodGet, "/", "192.0.2.1:1234", "X-API-Key", "k"), handler, RequireScopes("read")); w.Code != http.StatusOK {
+		t.Errorf("granted scope: status = %d", w.Code)
+	}
+	if w := serve(request(http.MethodGet, "/", "192.0.2.1:1234", "X-API-Key", "k"), handler, RequireScopes("admin")); w.Code != http.StatusForbidden {
+		t.Errorf("missing scope: status = %d", w.Code)
+	}
+	if w := serve(request(http.MethodGet, "/", "192.0.2.1:1234"), RequireScopes("read")); w.Code != http.StatusUnauthorized {
+		t.Errorf("without APIKey: status = %d", w.Code)
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	const want = "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	if got := HashAPIKey("secret"); got != want {
+		t.Errorf("HashAPIKey = %s", got)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BasicAuth protects routes with HTTP Basic authentication against a map of
+// username to password. Credentials are compared in constant time and the
+// authenticated user is stored under gin.AuthUserKey.
+func BasicAuth(users map[string]string) gin.HandlerFunc {
+	type credential struct {
+		user     string
+		userHash [sha256.Size]byte
+		passHash [sha256.Size]byte
+	}
+	credentials := make([]credential, 0, len(users))
+	for user, pass := range users {
+		credentials = append(credentials, credential{
+			user:     user,
+			userHash: sha256.Sum256([]byte(user)),
+			passHash: sha256.Sum256([]byte(pass)),
+		})
+	}
+
+	return func(c *gin.Context) {
+		user, pass, ok := c.Request.BasicAuth()
+		if ok {
+			userHash := sha256.Sum256([]byte(user))
+			passHash := sha256.Sum256([]byte(pass))
+			matched := ""
+			for _, cred := range credentials {
+				userMatch := subtle.ConstantTimeCompare(userHash[:], cred.userHash[:])
+				passMatch := subtle.ConstantTimeCompare(passHash[:], cred.passHash[:])
+				if userMatch&passMatch == 1 {
+					matched = cred.user
+				}
+			}
+			if matched != "" {
+				c.Set(gin.AuthUserKey, matched)
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("WWW-Authenticate", `Basic realm="Restricted", charset="UTF-8"`)
+		abortWithProblem(c, http.StatusUnauthorized, "invalid credentials")
+	}
+}
+
+// StaticBearer protects routes with a single shared bearer token compared
+// in constant time.
+func StaticBearer(token string) gin.HandlerFunc {
+	expected := sha256.Sum256([]byte(token))
+
+	return func(c *gin.Context) {
+		scheme, presented, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, "Bearer") && token != "" {
+			actual := sha256.Sum256([]byte(strings.TrimSpace(presented)))
+			if subtle.ConstantTimeCompare(actual[:], expected[:]) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("WWW-Authenticate", `Bearer realm="Restricted"`)
+		abortWithProblem(c, http.StatusUnauthorized, "invalid bearer token")
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func basic(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestBasicAuth(t *testing.T) {
+	users := map[string]string{"alice": "s3cret", "bob": "hunter2"}
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+		wantUser string
+	}{
+		{"valid", basic("alice", "s3cret"), http.StatusOK, "alice"},
+		{"other user", basic("bob", "hunter2"), http.StatusOK, "bob"},
+		{"wrong password", basic("alice", "hunter2"), http.StatusUnauthorized, ""},
+		{"unknown user", basic("mallory", "s3cret"), http.StatusUnauthorized, ""},
+		{"empty credentials", basic("", ""), http.StatusUnauthorized, ""},
+		{"bearer scheme", "Bearer s3cret", http.StatusUnauthorized, ""},
+		{"malformed", "Basic !!!", http.StatusUnauthorized, ""},
+		{"missing", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user string
+			capture := func(c *gin.Context) { user = c.GetString(gin.AuthUserKey) }
+			var headers []string
+			if tt.header != "" {
+				headers = []string{"Authorization", tt.header}
+			}
+			w := serve(request(http.MethodGet, "/", "192.0.2.1:1234", headers...), BasicAuth(users), capture)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if user != tt.wantUser {
+				t.Errorf("user = %q, want %q", user, tt.wantUser)
+			}
+			if tt.wantCode == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate missing")
+			}
+		})
+	}
+}
+
+func TestStaticBearer(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		header   string
+		wantCode int
+	}{
+		{"valid", "t0ken", "Bearer t0ken", http.StatusOK},
+		{"scheme case-insensitive", "t0ken", "bearer t0ken", http.StatusOK},
+		{"wrong token", "t0ken", "Bearer t0ke", http.StatusUnauthorized},
+		{"prefix of token", "t0ken", "Bearer t0kenX", http.StatusUnauthorized},
+		{"basic scheme", "t0ken", "Basic t0ken", http.StatusUnauthorized},
+		{"no scheme", "t0ken", "t0ken", http.StatusUnauthorized},
+		{"missing", "t0ken", "", http.StatusUnauthorized},
+		{"empty configured token", "", "Bearer ", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.header != "" {
+				headers = []string{"Authorization", tt.header}
+			}
+			w := serve(request(http.MethodGet, "/", "192.0.2.1:1234", headers...), StaticBearer(tt.token))
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
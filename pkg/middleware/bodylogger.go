@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultBodyLogMaxSize is the number of bytes captured per body when
+// BodyLoggerConfig.MaxSize is zero.
+const DefaultBodyLogMaxSize = 4096
+
+// redactedValue replaces the values of redacted fields.
+const redactedValue = "[REDACTED]"
+
+// DefaultBodyLogContentTypes lists the media types captured by default.
+// Entries ending in "/" match any subtype.
+var DefaultBodyLogContentTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/x-www-form-urlencoded",
+	"text/",
+}
+
+// DefaultRedactedFields lists the fields redacted by default.
+var DefaultRedactedFields = []string{"password", "token", "access_token", "refresh_token", "secret", "client_secret"}
+
+// BodyLoggerConfig configures the BodyLogger middleware.
+type BodyLoggerConfig struct {
+	// MaxSize is the maximum number of bytes captured per body.
+	MaxSize int
+	// ContentTypes eligible for capture.
+	ContentTypes []string
+	// RedactFields are JSON object keys, matched case-insensitively at any
+	// depth, or form fields whose values are replaced before logging.
+	RedactFields []string
+}
+
+// BodyLogger captures truncated request and response bodies and logs them
+// at debug level. It is a no-op unless debug logging is enabled, so it is
+// safe to install permanently and switch on when troubleshooting.
+func BodyLogger(cfg BodyLoggerConfig) gin.HandlerFunc {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultBodyLogMaxSize
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultBodyLogContentTypes
+	}
+	if len(cfg.RedactFields) == 0 {
+		cfg.RedactFields = DefaultRedactedFields
+	}
+	redactor := newRedactor(cfg.RedactFields)
+
+	return func(c *gin.Context) {
+		event := log.Debug()
+		if !event.Enabled() {
+			c.Next()
+			return
+		}
+
+		var reqBody []byte
+		reqTruncated := false
+		if c.Request.Body != nil && matchContentType(c.GetHeader("Content-Type"), cfg.ContentTypes) {
+			buf := make([]byte, cfg.MaxSize+1)
+			n, _ := io.ReadFull(c.Request.Body, buf)
+			reqBody, reqTruncated = buf[:n], n > cfg.MaxSize
+			if reqTruncated {
+				reqBody = reqBody[:cfg.MaxSize]
+			}
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(buf[:n]), c.Request.Body), c.Request.Body}
+		}
+
+		bw := &bodyCaptureWriter{ResponseWriter: c.Writer, max: cfg.MaxSize}
+		c.Writer = bw
+		c.Next()
+		c.Writer = bw.ResponseWriter
+
+		event = event.
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", bw.Status())
+		if reqBody != nil {
+			event = event.Str("request_body", redactor.redact(reqBody)).Bool("request_truncated", reqTruncated)
+		}
+		if matchContentType(bw.Header().Get("Content-Type"), cfg.ContentTypes) {
+			event = event.Str("response_body", redactor.redact(bw.buf.Bytes())).Bool("response_truncated", bw.truncated)
+		}
+		event.Msg("HTTP body capture")
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	max       int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (w *bodyCaptureWriter) Write(p []byte) (int, error) {
+	w.capture(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *bodyCaptureWriter) capture(p []byte) {
+	room := w.max - w.buf.Len()
+	if len(p) > room {
+		p = p[:room]
+		w.truncated = true
+	}
+	w.buf.Write(p)
+}
+
+func matchContentType(header string, allowed []string) bool {
+	if header == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if mediaType == a || (strings.HasSuffix(a, "/") && strings.HasPrefix(mediaType, a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactor masks configured fields in JSON and form bodies. Bodies that do
+// not parse as JSON, such as forms or truncated documents, fall back to
+// pattern matching.
+type redactor struct {
+	fields      map[string]bool
+	jsonPattern *regexp.Regexp
+	formPattern *regexp.Regexp
+}
+
+func newRedactor(fields []string) *redactor {
+	r := &redactor{fields: make(map[string]bool, len(fields))}
+	quoted := make([]string, 0, len(fields))
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = true
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+	names := strings.Join(quoted, "|")
+	r.jsonPattern = regexp.MustCompile(`(?i)("(?:` + names + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	r.formPattern = regexp.MustCompile(`(?i)((?:^|&)(?:` + names + `)=)[^&]*`)
+	return r
+}
+
+func (r *redactor) redact(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		masked := r.jsonPattern.ReplaceAllString(string(body), `${1}"`+redactedValue+`"`)
+		return r.formPattern.ReplaceAllString(masked, "${1}"+redactedValue)
+	}
+	out, err := json.Marshal(r.walk(v))
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+func (r *redactor) walk(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if r.fields[strings.ToLower(k)] {
+				t[k] = redactedValue
+			} else {
+				t[k] = r.walk(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = r.walk(val)
+		}
+	}
+	return v
+}
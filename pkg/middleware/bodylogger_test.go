@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestRedactor(t *testing.T) {
+	r := newRedactor(DefaultRedactedFields)
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		notWant []string
+	}{
+		{"json top level", `{"user":"alice","password":"hunter2"}`, []string{`"user":"alice"`, redactedValue}, []string{"hunter2"}},
+		{"json nested and case", `{"creds":[{"Client_Secret":"abc"}]}`, []string{redactedValue}, []string{"abc"}},
+		{"json non-string value", `{"token":12345}`, []string{redactedValue}, []string{"12345"}},
+		{"truncated json", `{"refresh_token":"xyz`, []string{redactedValue}, []string{"xyz"}},
+		{"form", `grant_type=password&password=hunter2&user=bob`, []string{"user=bob", "password=" + redactedValue}, []string{"hunter2"}},
+		{"form field suffix not redacted", `oldpassword=keep`, []string{"oldpassword=keep"}, nil},
+		{"plain text", `nothing to hide`, []string{"nothing to hide"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.redact([]byte(tt.body))
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("redact(%s) = %s, missing %q", tt.body, got, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("redact(%s) = %s, leaks %q", tt.body, got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchContentType(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"text/plain", true},
+		{"application/octet-stream", false},
+		{"image/png", false},
+		{"", false},
+		{"not a media type;;", false},
+	}
+	for _, tt := range tests {
+		if got := matchContentType(tt.header, DefaultBodyLogContentTypes); got != tt.want {
+			t.Errorf("matchContentType(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// captureLog points the global logger at a buffer for the test.
+func captureLog(t *testing.T, level zerolog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := log.Logger
+	log.Logger = zerolog.New(&buf).Level(level)
+	t.Cleanup(func() { log.Logger = prev })
+	return &buf
+}
+
+func TestBodyLogger(t *testing.T) {
+	echo := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "application/json", body)
+	}
+
+	tests := []struct {
+		name          string
+		level         zerolog.Level
+		contentType   string
+		body          string
+		wantLogged    bool
+		wantTruncated bool
+	}{
+		{"json captured", zerolog.DebugLevel, "application/json", `{"a":1,"password":"p"}`, true, false},
+		{"oversized body truncated", zerolog.DebugLevel, "application/json", `{"a":"` + strings.Repeat("x", 64) + `"}`, true, true},
+		{"binary skipped", zerolog.DebugLevel, "application/octet-stream", "\x00\x01", true, false},
+		{"disabled above debug", zerolog.InfoLevel, "application/json", `{"a":1}`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t, tt.level)
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := serveLast(req, BodyLogger(BodyLoggerConfig{MaxSize: 32}), echo)
+
+			if w.Body.String() != tt.body {
+				t.Errorf("handler saw body %q, want %q", w.Body.String(), tt.body)
+			}
+			if !tt.wantLogged {
+				if buf.Len() != 0 {
+					t.Errorf("logged %s", buf.String())
+				}
+				return
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("log entry %q: %v", buf.String(), err)
+			}
+			if strings.Contains(buf.String(), `"password":"p"`) {
+				t.Errorf("password not redacted: %s", buf.String())
+			}
+			_, hasReq := entry["request_body"]
+			if wantReq := tt.contentType == "application/json"; hasReq != wantReq {
+				t.Errorf("request_body present = %v, want %v", hasReq, wantReq)
+			}
+			if hasReq && entry["request_truncated"] != tt.wantTruncated {
+				t.Errorf("request_truncated = %v, want %v", entry["request_truncated"], tt.wantTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+	"github.com/gin-gonic/gin"
+)
+
+// CircuitBreaker guards the downstream handlers with breaker. Responses
+// with a 5xx status count as failures; while the breaker is open requests
+// are rejected with 503 and a Retry-After header.
+func CircuitBreaker(breaker *resilience.Breaker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		done, err := breaker.Allow()
+		if err != nil {
+			retry := int(math.Ceil(breaker.RetryAfter().Seconds()))
+			if retry < 1 {
+				retry = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retry))
+			abortWithProblem(c, http.StatusServiceUnavailable, "upstream temporarily unavailable")
+			return
+		}
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done(false)
+				panic(recovered)
+			}
+			done(c.Writer.Status() < http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := resilience.NewBreaker(resilience.BreakerConfig{MinRequests: 2})
+	status := http.StatusInternalServerError
+	handler := func(c *gin.Context) {
+		c.Status(status)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := serveLast(request(http.MethodGet, "/", "192.0.2.1:1234"), CircuitBreaker(breaker), handler); w.Code != http.StatusInternalServerError {
+			t.Fatalf("request %d: status = %d, want 500", i+1, w.Code)
+		}
+	}
+
+	status = http.StatusOK
+	w := serveLast(request(http.MethodGet, "/", "192.0.2.1:1234"), CircuitBreaker(breaker), handler)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status while open = %d, want 503", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After missing while open")
+	}
+}
+
+func TestCircuitBreakerCountsPanics(t *testing.T) {
+	breaker := resilience.NewBreaker(resilience.BreakerConfig{MinRequests: 1})
+	serveLast(request(http.MethodGet, "/", "192.0.2.1:1234"), gin.Recovery(), CircuitBreaker(breaker), func(c *gin.Context) {
+		panic("boom")
+	})
+	if got := breaker.State(); got != resilience.StateOpen {
+		t.Errorf("state = %s after panic, want open", got)
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIPContextKey is the Gin context key holding the resolved client IP.
+const ClientIPContextKey = "middleware.clientIP"
+
+// DefaultClientIPHeaders are consulted, in order, when the peer is trusted.
+var DefaultClientIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+type clientIPKey struct{}
+
+// ClientIPConfig configures a ClientIPResolver.
+type ClientIPConfig struct {
+	// TrustedProxies are CIDRs or addresses of proxies whose forwarding
+	// headers are believed. Headers from any other peer are ignored.
+	TrustedProxies []string
+	// Headers carrying the client address. Defaults to
+	// DefaultClientIPHeaders.
+	Headers []string
+}
+
+// ClientIPResolver derives the client address from the peer address and,
+// for trusted proxies only, the forwarding headers. It is usable from both
+// Gin and plain net/http stacks.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// NewClientIPResolver returns a resolver for cfg.
+func NewClientIPResolver(cfg ClientIPConfig) (*ClientIPResolver, error) {
+	trusted, err := parsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	headers := cfg.Headers
+	if len(headers) == 0 {
+		headers = DefaultClientIPHeaders
+	}
+	return &ClientIPResolver{trusted: trusted, headers: headers}, nil
+}
+
+// Resolve returns the client IP for r. X-Forwarded-For is walked from the
+// right, skipping trusted proxies, so spoofed leftmost entries are ignored.
+func (res *ClientIPResolver) Resolve(r *http.Request) string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		host = strings.TrimSpace(r.RemoteAddr)
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !containsAddr(res.trusted, peer.Unmap()) {
+		return host
+	}
+
+	for _, header := range res.headers {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		hops := strings.Split(strings.Join(values, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			client = addr.Unmap().String()
+			if !containsAddr(res.trusted, addr.Unmap()) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+	return host
+}
+
+// Handler is the net/http variant of ClientIP.
+func (res *ClientIPResolver) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := res.Resolve(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// ClientIP resolves the client IP with res and stores it in the Gin and
+// request contexts. Middleware in this package that depends on the client
+// address prefers this value over gin.Context.ClientIP.
+func ClientIP(res *ClientIPResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := res.Resolve(c.Request)
+		c.Set(ClientIPContextKey, ip)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), clientIPKey{}, ip))
+		c.Next()
+	}
+}
+
+// ClientIPFromContext returns the address stored by ClientIP or
+// ClientIPResolver.Handler, or an empty string.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// clientIP returns the address resolved by ClientIP, falling back to the
+// peer address when the middleware is not installed. Forwarding headers are
+// never believed without ClientIP, since gin.Context.ClientIP trusts every
+// proxy unless the engine was configured otherwise.
+func clientIP(c *gin.Context) string {
+	if ip := c.GetString(ClientIPContextKey); ip != "" {
+		return ip
+	}
+	return c.RemoteIP()
+}
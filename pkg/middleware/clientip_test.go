@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	res, err := NewClientIPResolver(ClientIPConfig{TrustedProxies: []string{"10.0.0.0/8", "2001:db8::1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers []string
+		want    string
+	}{
+		{"direct peer", "198.51.100.1:1234", nil, "198.51.100.1"},
+		{"untrusted peer spoofs XFF", "198.51.100.1:1234", []string{"X-Forwarded-For", "203.0.113.9"}, "198.51.100.1"},
+		{"untrusted peer spoofs X-Real-IP", "198.51.100.1:1234", []string{"X-Real-IP", "203.0.113.9"}, "198.51.100.1"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"X-Forwarded-For", "203.0.113.9"}, "203.0.113.9"},
+		{"spoofed leftmost entry", "10.0.0.1:1234", []string{"X-Forwarded-For", "1.2.3.4, 203.0.113.9"}, "203.0.113.9"},
+		{"chain of trusted proxies", "10.0.0.1:1234", []string{"X-Forwarded-For", "1.2.3.4, 203.0.113.9, 10.1.1.1, 10.2.2.2"}, "203.0.113.9"},
+		{"repeated headers", "10.0.0.1:1234", []string{"X-Forwarded-For", "1.2.3.4", "X-Forwarded-For", "203.0.113.9"}, "203.0.113.9"},
+		{"garbage hop stops walk", "10.0.0.1:1234", []string{"X-Forwarded-For", "203.0.113.9, bogus"}, "10.0.0.1"},
+		{"only trusted hops", "10.0.0.1:1234", []string{"X-Forwarded-For", "10.9.9.9"}, "10.9.9.9"},
+		{"X-Real-IP fallback", "10.0.0.1:1234", []string{"X-Real-IP", "203.0.113.9"}, "203.0.113.9"},
+		{"IPv4-mapped peer", "[::ffff:10.0.0.1]:1234", []string{"X-Forwarded-For", "203.0.113.9"}, "203.0.113.9"},
+		{"trusted IPv6 peer", "[2001:db8::1]:1234", []string{"X-Forwarded-For", "2001:db8::99"}, "2001:db8::99"},
+		{"untrusted IPv6 peer", "[2001:db8::2]:1234", []string{"X-Forwarded-For", "203.0.113.9"}, "2001:db8::2"},
+		{"remote without port", "198.51.100.1", []string{"X-Forwarded-For", "203.0.113.9"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := request(http.MethodGet, "/", tt.remote, tt.headers...)
+			if got := res.Resolve(r); got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClientIPResolverInvalid(t *testing.T) {
+	for _, proxies := range [][]string{{"10.0.0.0/33"}, {"not-an-ip"}} {
+		if _, err := NewClientIPResolver(ClientIPConfig{TrustedProxies: proxies}); err == nil {
+			t.Errorf("NewClientIPResolver(%v) succeeded", proxies)
+		}
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	res, err := NewClientIPResolver(ClientIPConfig{TrustedProxies: []string{"10.0.0.1"}, Headers: []string{"CF-Connecting-IP"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fromGin, fromCtx string
+	capture := func(c *gin.Context) {
+		fromGin, fromCtx = clientIP(c), ClientIPFromContext(c.Request.Context())
+	}
+	serve(request(http.MethodGet, "/", "10.0.0.1:1234", "CF-Connecting-IP", "203.0.113.9", "X-Forwarded-For", "1.2.3.4"), ClientIP(res), capture)
+	if fromGin != "203.0.113.9" || fromCtx != "203.0.113.9" {
+		t.Errorf("clientIP = %q, context = %q, want 203.0.113.9", fromGin, fromCtx)
+	}
+
+	serve(request(http.MethodGet, "/", "198.51.100.1:1234", "X-Forwarded-For", "1.2.3.4"), capture)
+	if fromGin != "198.51.100.1" {
+		t.Errorf("clientIP without middleware = %q, want peer address", fromGin)
+	}
+
+	var got string
+	h := res.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIPFromContext(r.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), request(http.MethodGet, "/", "10.0.0.1:1234", "CF-Connecting-IP", "203.0.113.9"))
+	if got != "203.0.113.9" {
+		t.Errorf("Handler stored %q", got)
+	}
+}
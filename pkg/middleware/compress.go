@@ -0,0 +1,301 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// Supported content encodings, in default order of preference.
+const (
+	EncodingBrotli  = "br"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// DefaultCompressMinSize is the response size below which bodies are sent
+// uncompressed.
+const DefaultCompressMinSize = 1024
+
+// DefaultCompressContentTypes lists the media types compressed by default.
+// Entries ending in "/" match any subtype.
+var DefaultCompressContentTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/x-ndjson",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/",
+}
+
+// CompressConfig configures the Compress middleware.
+type CompressConfig struct {
+	// Encodings enabled, in order of server preference. Defaults to br,
+	// gzip and deflate.
+	Encodings []string
+	// Level passed to the encoder. Zero selects each encoder's default.
+	Level int
+	// MinSize below which responses are not compressed. Responses that are
+	// flushed before reaching it are compressed regardless, so streams
+	// start compressing immediately. Defaults to DefaultCompressMinSize.
+	MinSize int
+	// ContentTypes eligible for compression. Defaults to
+	// DefaultCompressContentTypes.
+	ContentTypes []string
+}
+
+// compressor is implemented by the gzip, flate and brotli writers.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress negotiates Accept-Encoding and compresses eligible responses.
+// Bodies are buffered up to MinSize before deciding, and Flush is honored
+// so streaming handlers keep working.
+func Compress(cfg CompressConfig) gin.HandlerFunc {
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+	}
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = DefaultCompressMinSize
+	}
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = DefaultCompressContentTypes
+	}
+
+	pools := make(map[string]*sync.Pool, len(cfg.Encodings))
+	for _, encoding := range cfg.Encodings {
+		encoding := encoding
+		pools[encoding] = &sync.Pool{New: func() interface{} {
+			return newCompressor(encoding, cfg.Level)
+		}}
+	}
+
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"), cfg.Encodings)
+		if encoding == "" || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			cfg:            &cfg,
+			encoding:       encoding,
+			pool:           pools[encoding],
+		}
+		c.Writer = cw
+		defer func() {
+			cw.finish()
+			c.Writer = cw.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+func newCompressor(encoding string, level int) compressor {
+	switch encoding {
+	case EncodingBrotli:
+		if level == 0 {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(io.Discard, level)
+	case EncodingGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		w, err := gzip.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			w = gzip.NewWriter(io.Discard)
+		}
+		return w
+	default:
+		if level == 0 {
+			level = flate.DefaultCompression
+		}
+		w, err := flate.NewWriter(io.Discard, level)
+		if err != nil {
+			w, _ = flate.NewWriter(io.Discard, flate.DefaultCompression)
+		}
+		return w
+	}
+}
+
+// negotiateEncoding picks the first supported encoding the client accepts
+// with a non-zero quality.
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+
+	for _, encoding := range supported {
+		if ok, listed := accepted[encoding]; ok || (!listed && accepted["*"]) {
+			return encoding
+		}
+	}
+	return ""
+}
+
+type compressWriter struct {
+	gin.ResponseWriter
+	cfg      *CompressConfig
+	encoding string
+	pool     *sync.Pool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     compressor
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.ResponseWriter.Written() || len(w.buf) > 0
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.cfg.MinSize {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide commits the response headers, choosing whether to compress, and
+// writes out any buffered body.
+func (w *compressWriter) decide(sizeOK bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if sizeOK && w.compressible() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.enc = w.pool.Get().(compressor)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	if w.status == http.StatusNoContent || w.status == http.StatusNotModified ||
+		(w.status != 0 && w.status < http.StatusOK) {
+		return false
+	}
+	header := w.Header()
+	// Byte ranges refer to the uncompressed representation.
+	if w.status == http.StatusPartialContent || header.Get("Content-Range") != "" {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, allowed := range w.cfg.ContentTypes {
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish flushes buffered output and releases the encoder.
+func (w *compressWriter) finish() {
+	if !w.decided && (len(w.buf) > 0 || w.status != 0) {
+		_ = w.decide(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.enc.Reset(io.Discard)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case EncodingDeflate:
+		r = flate.NewReader(bytes.NewReader(body))
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"key":"value"}`, 200)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		want           string
+	}{
+		{"brotli preferred", "gzip, deflate, br", "application/json", http.StatusOK, large, EncodingBrotli},
+		{"gzip", "gzip", "application/json", http.StatusOK, large, EncodingGzip},
+		{"deflate", "deflate", "application/json", http.StatusOK, large, EncodingDeflate},
+		{"gzip refused with q=0", "br;q=0, gzip", "application/json", http.StatusOK, large, EncodingGzip},
+		{"wildcard", "*", "text/plain; charset=utf-8", http.StatusOK, large, EncodingBrotli},
+		{"no accept-encoding", "", "application/json", http.StatusOK, large, ""},
+		{"identity only", "identity", "application/json", http.StatusOK, large, ""},
+		{"below min size", "gzip", "application/json", http.StatusOK, `{"small":true}`, ""},
+		{"image", "gzip", "image/png", http.StatusOK, large, ""},
+		{"partial content", "gzip", "application/json", http.StatusPartialContent, large, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := request(http.MethodGet, "/", "192.0.2.1:1234")
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := serveLast(req, Compress(CompressConfig{}), func(c *gin.Context) {
+				c.Data(tt.status, tt.contentType, []byte(tt.body))
+			})
+			if got := w.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := decompress(t, tt.want, w.Body.Bytes()); got != tt.body {
+				t.Errorf("body does not round-trip: got %d bytes", len(got))
+			}
+			if tt.want != "" && w.Header().Get("Content-Length") != "" {
+				t.Error("Content-Length kept on compressed response")
+			}
+		})
+	}
+}
+
+func TestCompressFlushStreams(t *testing.T) {
+	req := request(http.MethodGet, "/", "192.0.2.1:1234", "Accept-Encoding", "gzip")
+	w := serveLast(req, Compress(CompressConfig{}), func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString("{\"n\":1}\n")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("{\"n\":2}\n")
+	})
+	if w.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Fatalf("flushed stream not compressed: %v", w.Header())
+	}
+	if got := decompress(t, EncodingGzip, w.Body.Bytes()); got != "{\"n\":1}\n{\"n\":2}\n" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestCompressKeepsStatusOfSmallResponses(t *testing.T) {
+	req := request(http.MethodGet, "/", "192.0.2.1:1234", "Accept-Encoding", "gzip")
+	w := serveLast(req, Compress(CompressConfig{}), func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+	if w.Code != http.StatusCreated || w.Body.String() != `{"id":1}` {
+		t.Errorf("response = %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary = %q", w.Header().Get("Vary"))
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{EncodingBrotli, EncodingGzip}
+	tests := map[string]string{
+		"":                 "",
+		"gzip":             EncodingGzip,
+		"GZIP":             EncodingGzip,
+		"gzip;q=0.5, br":   EncodingBrotli,
+		"*;q=0, gzip":      EncodingGzip,
+		"*;q=0":            "",
+		"br;q=0, *":        EncodingGzip,
+		"compress, x-zstd": "",
+	}
+	for header, want := range tests {
+		if got := negotiateEncoding(header, supported); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/PhilipKram/gms-foundation/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// ContextLoggerConfig configures ContextLogger.
+type ContextLoggerConfig struct {
+	// Logger is the base logger. Defaults to the global log.Logger as of
+	// each request.
+	Logger *zerolog.Logger
+	// UserID returns the authenticated user, if any. By default the
+	// BasicAuth user or the API key ID is used.
+	UserID func(c *gin.Context) string
+}
+
+// ContextLogger stores a per-request logger in the request context,
+// retrievable with logger.FromContext. It is enriched with request_id,
+// the trace and span IDs of the W3C traceparent header under the field
+// names of the log format, and user_id. Install it after RequestID and the
+// authentication middleware.
+func ContextLogger(cfg ContextLoggerConfig) gin.HandlerFunc {
+	if cfg.UserID == nil {
+		cfg.UserID = defaultUserID
+	}
+
+	return func(c *gin.Context) {
+		base := cfg.Logger
+		if base == nil {
+			base = &log.Logger
+		}
+		ctx := base.With()
+		if id := RequestIDFromContext(c.Request.Context()); id != "" {
+			ctx = ctx.Str("request_id", id)
+		}
+		if traceID, spanID, ok := parseTraceparent(c.GetHeader("traceparent")); ok {
+			ctx = ctx.Str(logger.TraceIDField, logger.TraceValue(traceID)).Str(logger.SpanIDField, spanID)
+		}
+		if user := cfg.UserID(c); user != "" {
+			ctx = ctx.Str("user_id", user)
+		}
+
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), ctx.Logger()))
+		c.Next()
+	}
+}
+
+func defaultUserID(c *gin.Context) string {
+	if user := c.GetString(gin.AuthUserKey); user != "" {
+		return user
+	}
+	if info, ok := APIKeyFromContext(c); ok {
+		return info.ID
+	}
+	return ""
+}
+
+// parseTraceparent extracts the trace and parent span IDs of a W3C Trace
+// Context traceparent header.
+func parseTraceparent(h string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	for _, p := range parts[:3] {
+		if _, err := hex.DecodeString(p); err != nil || strings.ToLower(p) != p {
+			return "", "", false
+		}
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+
+	"github.com/PhilipKram/gms-foundation/pkg/logger"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		trace = "4bf92f3577b34da6a3ce929d0e0e4736"
+		span  = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		header string
+		ok     bool
+	}{
+		{"00-" + trace + "-" + span + "-01", true},
+		{" 00-" + trace + "-" + span + "-00 ", true},
+		{"01-" + trace + "-" + span + "-01-extra", true},
+		{"ff-" + trace + "-" + span + "-01", false},
+		{"00-" + strings.ToUpper(trace) + "-" + span + "-01", false},
+		{"00-" + strings.Repeat("0", 32) + "-" + span + "-01", false},
+		{"00-" + trace + "-" + strings.Repeat("0", 16) + "-01", false},
+		{"00-" + trace[:31] + "-" + span + "-01", false},
+		{"00-" + trace[:31] + "g-" + span + "-01", false},
+		{"00-" + trace + "-" + span, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		traceID, spanID, ok := parseTraceparent(tt.header)
+		if ok != tt.ok || (ok && (traceID != trace || spanID != span)) {
+			t.Errorf("parseTraceparent(%q) = %q, %q, %v; want ok %v", tt.header, traceID, spanID, ok, tt.ok)
+		}
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	tests := []struct {
+		name     string
+		handlers []gin.HandlerFunc
+		cfg      ContextLoggerConfig
+		headers  []string
+		has      []string
+		missing  []string
+	}{
+		{
+			name:     "request and trace IDs",
+			handlers: []gin.HandlerFunc{RequestID()},
+			headers:  []string{"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			has:      []string{`"request_id":"`, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`, `"span_id":"00f067aa0ba902b7"`},
+			missing:  []string{"user_id"},
+		},
+		{
+			name:    "invalid traceparent ignored",
+			headers: []string{"traceparent", "garbage"},
+			missing: []string{"trace_id", "request_id"},
+		},
+		{
+			name:     "basic auth user",
+			handlers: []gin.HandlerFunc{func(c *gin.Context) { c.Set(gin.AuthUserKey, "alice") }},
+			has:      []string{`"user_id":"alice"`},
+		},
+		{
+			name: "custom user",
+			cfg:  ContextLoggerConfig{UserID: func(*gin.Context) string { return "u-42" }},
+			has:  []string{`"user_id":"u-42"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			base := zerolog.New(&buf)
+			tt.cfg.Logger = &base
+			emit := func(c *gin.Context) { logger.FromContext(c.Request.Context()).Info().Msg("handled") }
+
+			handlers := append(append(tt.handlers, ContextLogger(tt.cfg)), emit)
+			serve(request(http.MethodGet, "/", "192.0.2.1:1234", tt.headers...), handlers...)
+			for _, s := range append(tt.has, `"message":"handled"`) {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("entry misses %s: %s", s, buf.String())
+				}
+			}
+			for _, s := range tt.missing {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("entry has %s: %s", s, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Defaults used by the CORS middleware when the corresponding list is empty.
+var (
+	DefaultCORSMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost,
+		http.MethodPut, http.MethodPatch, http.MethodDelete,
+	}
+	DefaultCORSHeaders = []string{"Accept", "Authorization", "Content-Type"}
+)
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	// AllowedOrigins lists exact origins, "*" for any origin, or patterns
+	// with a single wildcard such as "https://*.example.com".
+	AllowedOrigins []string
+	// AllowOriginFunc, when set, is consulted for origins not matched by
+	// AllowedOrigins.
+	AllowOriginFunc func(origin string) bool
+	AllowedMethods  []string
+	// AllowedHeaders accepted in preflights. "*" allows any header. The
+	// CSRF token header is always allowed.
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+
+	// OptionsPassthrough forwards OPTIONS requests that are not CORS
+	// preflights to the next handlers instead of answering 204.
+	OptionsPassthrough bool
+	// StrictPreflight answers preflights with a disallowed origin, method
+	// or header with 403 instead of a bare 204.
+	StrictPreflight bool
+}
+
+// CORS implements the Cross-Origin Resource Sharing protocol.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = DefaultCORSMethods
+	}
+	if len(cfg.AllowedHeaders) == 0 {
+		cfg.AllowedHeaders = DefaultCORSHeaders
+	}
+	if !containsFold(cfg.AllowedHeaders, DefaultCSRFHeader) {
+		cfg.AllowedHeaders = append(append([]string(nil), cfg.AllowedHeaders...), DefaultCSRFHeader)
+	}
+
+	allowAnyOrigin := containsFold(cfg.AllowedOrigins, "*")
+	allowAnyHeader := containsFold(cfg.AllowedHeaders, "*")
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge / time.Second))
+
+	originAllowed := func(origin string) bool {
+		if allowAnyOrigin {
+			return true
+		}
+		for _, allowed := range cfg.AllowedOrigins {
+			if matchOrigin(allowed, origin) {
+				return true
+			}
+		}
+		return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin)
+	}
+
+	headersAllowed := func(requested string) bool {
+		if allowAnyHeader || requested == "" {
+			return true
+		}
+		for _, h := range strings.Split(requested, ",") {
+			if !containsFold(cfg.AllowedHeaders, strings.TrimSpace(h)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	setOrigin := func(c *gin.Context, origin string) {
+		if allowAnyOrigin && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions &&
+			origin != "" && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if preflight {
+			requested := c.GetHeader("Access-Control-Request-Headers")
+			if !originAllowed(origin) ||
+				!containsFold(cfg.AllowedMethods, c.GetHeader("Access-Control-Request-Method")) ||
+				!headersAllowed(requested) {
+				if cfg.StrictPreflight {
+					abortWithProblem(c, http.StatusForbidden, "CORS preflight rejected")
+					return
+				}
+				c.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+
+			setOrigin(c, origin)
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", methods)
+			if requested != "" {
+				c.Header("Access-Control-Allow-Headers", requested)
+			}
+			if cfg.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if origin != "" && originAllowed(origin) {
+			setOrigin(c, origin)
+			if exposed != "" {
+				c.Header("Access-Control-Expose-Headers", exposed)
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions && !cfg.OptionsPassthrough {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// matchOrigin compares origin against an allowed entry that may contain a
+// single "*" wildcard.
+func matchOrigin(allowed, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(allowed, "*")
+	if !wildcard {
+		return strings.EqualFold(allowed, origin)
+	}
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+		strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix))
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	base := CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		ExposedHeaders:   []string{"X-Request-ID"},
+		MaxAge:           10 * time.Minute,
+	}
+	strict := base
+	strict.StrictPreflight = true
+	passthrough := base
+	passthrough.OptionsPassthrough = true
+	anyOrigin := CORSConfig{AllowedOrigins: []string{"*"}}
+
+	preflight := func(origin, method, headers string) []string {
+		h := []string{"Origin", origin, "Access-Control-Request-Method", method}
+		if headers != "" {
+			h = append(h, "Access-Control-Request-Headers", headers)
+		}
+		return h
+	}
+
+	tests := []struct {
+		name        string
+		cfg         CORSConfig
+		method      string
+		headers     []string
+		wantStatus  int
+		wantOrigin  string
+		wantMethods bool
+	}{
+		{"simple request from allowed origin", base, http.MethodGet, []string{"Origin", "https://app.example.com"}, http.StatusOK, "https://app.example.com", false},
+		{"wildcard subdomain", base, http.MethodGet, []string{"Origin", "https://tenant.example.org"}, http.StatusOK, "https://tenant.example.org", false},
+		{"wildcard needs a subdomain", base, http.MethodGet, []string{"Origin", "https://.example.org"}, http.StatusOK, "", false},
+		{"suffix lookalike", base, http.MethodGet, []string{"Origin", "https://evil-example.org"}, http.StatusOK, "", false},
+		{"disallowed origin still served", base, http.MethodGet, []string{"Origin", "https://evil.example"}, http.StatusOK, "", false},
+		{"no origin", base, http.MethodGet, nil, http.StatusOK, "", false},
+		{"preflight allowed", base, http.MethodOptions, preflight("https://app.example.com", "PUT", "Content-Type, X-CSRF-Token"), http.StatusNoContent, "https://app.example.com", true},
+		{"preflight bad origin", base, http.MethodOptions, preflight("https://evil.example", "PUT", ""), http.StatusNoContent, "", false},
+		{"preflight bad method", base, http.MethodOptions, preflight("https://app.example.com", "TRACE", ""), http.StatusNoContent, "", false},
+		{"preflight bad header", base, http.MethodOptions, preflight("https://app.example.com", "PUT", "X-Debug"), http.StatusNoContent, "", false},
+		{"strict preflight rejected", strict, http.MethodOptions, preflight("https://evil.example", "PUT", ""), http.StatusForbidden, "", false},
+		{"plain OPTIONS answered", base, http.MethodOptions, nil, http.StatusNoContent, "", false},
+		{"plain OPTIONS passthrough", passthrough, http.MethodOptions, nil, http.StatusOK, "", false},
+		{"any origin without credentials", anyOrigin, http.MethodGet, []string{"Origin", "https://elsewhere.example"}, http.StatusOK, "*", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(request(tt.method, "/", "192.0.2.1:1234", tt.headers...), CORS(tt.cfg))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods") != ""; got != tt.wantMethods {
+				t.Errorf("Allow-Methods present = %v, want %v", got, tt.wantMethods)
+			}
+			if tt.wantOrigin != "" && tt.wantOrigin != "*" {
+				if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+					t.Error("Allow-Credentials missing")
+				}
+				if !strings.Contains(strings.Join(w.Header().Values("Vary"), ","), "Origin") {
+					t.Error("Vary: Origin missing")
+				}
+			}
+		})
+	}
+}
+
+func TestCORSPreflightHeaders(t *testing.T) {
+	cfg := CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, MaxAge: 10 * time.Minute, ExposedHeaders: []string{"X-Request-ID"}}
+	w := serve(request(http.MethodOptions, "/", "192.0.2.1:1234",
+		"Origin", "https://app.example.com", "Access-Control-Request-Method", "PATCH", "Access-Control-Request-Headers", "authorization"), CORS(cfg))
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Max-Age = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "authorization" {
+		t.Errorf("Allow-Headers = %q", got)
+	}
+
+	w = serve(request(http.MethodGet, "/", "192.0.2.1:1234", "Origin", "https://app.example.com"), CORS(cfg))
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+		t.Errorf("Expose-Headers = %q", got)
+	}
+}
+
+func TestCORSAllowOriginFunc(t *testing.T) {
+	cfg := CORSConfig{AllowOriginFunc: func(origin string) bool { return strings.HasSuffix(origin, ".internal") }}
+	w := serve(request(http.MethodGet, "/", "192.0.2.1:1234", "Origin", "http://tool.internal"), CORS(cfg))
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://tool.internal" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Defaults used by the CSRF middleware.
+const (
+	DefaultCSRFCookie    = "csrf_token"
+	DefaultCSRFHeader    = "X-CSRF-Token"
+	DefaultCSRFFormField = "csrf_token"
+)
+
+// CSRFContextKey is the Gin context key holding the request's CSRF token.
+const CSRFContextKey = "middleware.csrfToken"
+
+const csrfTokenBytes = 32
+
+// CSRFConfig configures the CSRF middleware.
+type CSRFConfig struct {
+	CookieName string
+	HeaderName string
+	// FormField is consulted for form posts when the header is absent.
+	FormField    string
+	CookiePath   string
+	CookieDomain string
+	CookieSecure bool
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+	// MaxAge of the token cookie in seconds. Zero makes it a session cookie.
+	MaxAge int
+	// ExemptPaths skip verification. Entries ending in "*" match by prefix.
+	ExemptPaths []string
+}
+
+// CSRF implements double-submit cookie protection. Every response carries a
+// random token cookie readable by scripts; unsafe requests must echo it in
+// the configured header or form field.
+func CSRF(cfg CSRFConfig) gin.HandlerFunc {
+	if cfg.CookieName == "" {
+		cfg.CookieName = DefaultCSRFCookie
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = DefaultCSRFHeader
+	}
+	if cfg.FormField == "" {
+		cfg.FormField = DefaultCSRFFormField
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	return func(c *gin.Context) {
+		token, err := c.Cookie(cfg.CookieName)
+		if err != nil || !validCSRFToken(token) {
+			token = newCSRFToken()
+			c.SetSameSite(cfg.SameSite)
+			c.SetCookie(cfg.CookieName, token, cfg.MaxAge, cfg.CookiePath, cfg.CookieDomain, cfg.CookieSecure, false)
+		}
+		c.Set(CSRFContextKey, token)
+
+		if isSafeMethod(c.Request.Method) || cfg.exempt(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		submitted := c.GetHeader(cfg.HeaderName)
+		if submitted == "" {
+			submitted = c.PostForm(cfg.FormField)
+		}
+		if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			abortWithProblem(c, http.StatusForbidden, "invalid CSRF token")
+			return
+		}
+		c.Next()
+	}
+}
+
+// CSRFToken returns the token issued to the current request, for embedding
+// in rendered forms.
+func CSRFToken(c *gin.Context) string {
+	return c.GetString(CSRFContextKey)
+}
+
+func (cfg CSRFConfig) exempt(path string) bool {
+	for _, p := range cfg.ExemptPaths {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func newCSRFToken() string {
+	b := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func validCSRFToken(token string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(b) == csrfTokenBytes
+}
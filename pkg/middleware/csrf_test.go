@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRF(t *testing.T) {
+	token := newCSRFToken()
+	other := newCSRFToken()
+	form := func(v string) string { return url.Values{DefaultCSRFFormField: {v}}.Encode() }
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cookie string
+		header string
+		form   string
+		want   int
+	}{
+		{"safe method without token", http.MethodGet, "/", "", "", "", http.StatusOK},
+		{"matching header", http.MethodPost, "/", token, token, "", http.StatusOK},
+		{"matching form field", http.MethodPost, "/", token, "", form(token), http.StatusOK},
+		{"missing token", http.MethodPost, "/", token, "", "", http.StatusForbidden},
+		{"token mismatch", http.MethodPost, "/", token, other, "", http.StatusForbidden},
+		{"form mismatch", http.MethodPut, "/", token, "", form(other), http.StatusForbidden},
+		{"no cookie", http.MethodDelete, "/", "", token, "", http.StatusForbidden},
+		{"malformed cookie echoed", http.MethodPost, "/", "forged", "forged", "", http.StatusForbidden},
+		{"exempt exact path", http.MethodPost, "/webhook", "", "", "", http.StatusOK},
+		{"exempt prefix", http.MethodPost, "/hooks/stripe", "", "", "", http.StatusOK},
+		{"exact path is not a prefix", http.MethodPost, "/webhook/other", "", "", "", http.StatusForbidden},
+	}
+	handler := CSRF(CSRFConfig{ExemptPaths: []string{"/webhook", "/hooks/*"}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form))
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tt.form != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: DefaultCSRFCookie, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(DefaultCSRFHeader, tt.header)
+			}
+			if w := serve(req, handler); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFIssuesToken(t *testing.T) {
+	var issued string
+	w := serve(request(http.MethodGet, "/", "192.0.2.1:1234"), CSRF(CSRFConfig{CookieSecure: true}), func(c *gin.Context) {
+		issued = CSRFToken(c)
+	})
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v", cookies)
+	}
+	cookie := cookies[0]
+	if cookie.Name != DefaultCSRFCookie || cookie.Value != issued || !validCSRFToken(issued) {
+		t.Errorf("cookie %s=%q, context token %q", cookie.Name, cookie.Value, issued)
+	}
+	if cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie attributes = %+v", cookie)
+	}
+
+	// A valid cookie is reused rather than rotated.
+	req := request(http.MethodGet, "/", "192.0.2.1:1234")
+	req.AddCookie(cookie)
+	if w := serve(req, CSRF(CSRFConfig{})); len(w.Result().Cookies()) != 0 {
+		t.Error("valid token was reissued")
+	}
+}
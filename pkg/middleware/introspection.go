@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2/introspection"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// IntrospectionContextKey is the Gin context key holding the
+// *introspection.Response of the request's bearer token.
+const IntrospectionContextKey = "middleware.introspection"
+
+// Introspector validates opaque tokens; *introspection.Client implements
+// it.
+type Introspector interface {
+	Introspect(ctx context.Context, token string) (*introspection.Response, error)
+}
+
+// TokenIntrospectionConfig configures TokenIntrospection.
+type TokenIntrospectionConfig struct {
+	Introspector Introspector
+	// CacheTTL caches results so that repeated requests with the same token
+	// do not reach the server. It never extends past the token's expiry.
+	// Defaults to 1m; negative disables caching. Only active tokens are
+	// cached, so random tokens cannot fill the cache.
+	CacheTTL time.Duration
+	// CacheSize caps the number of cached tokens; when full, an arbitrary
+	// entry is evicted. Defaults to 10000.
+	CacheSize int
+	// Scopes lists scopes every token must have been granted.
+	Scopes []string
+}
+
+// TokenIntrospection authenticates bearer tokens against an introspection
+// endpoint, for gateways in front of Keycloak, ORY Hydra and similar. The
+// response is stored under IntrospectionContextKey. Tokens are rejected
+// while the endpoint is unreachable. An Introspector is required.
+func TokenIntrospection(cfg TokenIntrospectionConfig) (gin.HandlerFunc, error) {
+	if cfg.Introspector == nil {
+		return nil, errors.New("token introspection requires an Introspector")
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Minute
+	}
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = 10000
+	}
+	cache := &introspectionCache{size: cfg.CacheSize, entries: make(map[[sha256.Size]byte]introspectionEntry)}
+
+	return func(c *gin.Context) {
+		scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		token = strings.TrimSpace(token)
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithProblem(c, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		key := sha256.Sum256([]byte(token))
+		now := time.Now()
+		resp, ok := cache.get(key, now)
+		if !ok {
+			var err error
+			resp, err = cfg.Introspector.Introspect(c.Request.Context(), token)
+			if err != nil {
+				log.Error().Err(err).Msg("Token introspection failed")
+				abortWithProblem(c, http.StatusServiceUnavailable, "token introspection unavailable")
+				return
+			}
+			if cfg.CacheTTL > 0 && resp.Active {
+				expires := now.Add(cfg.CacheTTL)
+				if exp := resp.ExpiresAt(); !exp.IsZero() && exp.Before(expires) {
+					expires = exp
+				}
+				cache.set(key, introspectionEntry{resp: resp, expires: expires}, now)
+			}
+		}
+
+		if !resp.Active {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			abortWithProblem(c, http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+		for _, scope := range cfg.Scopes {
+			if !resp.HasScope(scope) {
+				c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(cfg.Scopes, " ")+`"`)
+				abortWithProblem(c, http.StatusForbidden, "token lacks required scope")
+				return
+			}
+		}
+
+		c.Set(IntrospectionContextKey, resp)
+		c.Next()
+	}, nil
+}
+
+// IntrospectionFromContext returns the response stored by
+// TokenIntrospection.
+func IntrospectionFromContext(c *gin.Context) (*introspection.Response, bool) {
+	v, ok := c.Get(IntrospectionContextKey)
+	if !ok {
+		return nil, false
+	}
+	resp, ok := v.(*introspection.Response)
+	return resp, ok
+}
+
+type introspectionEntry struct {
+	resp    *introspection.Response
+	expires time.Time
+}
+
+// introspectionCache maps token digests to responses; raw tokens are never
+// kept in memory.
+type introspectionCache struct {
+	size      int
+	mu        sync.Mutex
+	entries   map[[sha256.Size]byte]introspectionEntry
+	lastSweep time.Time
+}
+
+func (c *introspectionCache) get(key [sha256.Size]byte, now time.Time) (*introspection.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return nil, false
+	}
+	return e.resp, true
+}
+
+func (c *introspectionCache) set(key [sha256.Size]byte, e introspectionEntry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Drop expired entries at most once a minute.
+	if now.Sub(c.lastSweep) >= time.Minute {
+		c.lastSweep = now
+		for k, old := range c.entries {
+			if !now.Before(old.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = e
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2/introspection"
+)
+
+type fakeIntrospector struct {
+	responses map[string]*introspection.Response
+	err       error
+	calls     int
+}
+
+func (f *fakeIntrospector) Introspect(_ context.Context, token string) (*introspection.Response, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if resp, ok := f.responses[token]; ok {
+		return resp, nil
+	}
+	return &introspection.Response{}, nil
+}
+
+func TestTokenIntrospection(t *testing.T) {
+	fake := &fakeIntrospector{responses: map[string]*introspection.Response{
+		"good":     {Active: true, Scope: "read write"},
+		"readonly": {Active: true, Scope: "read"},
+	}}
+	handler, err := TokenIntrospection(TokenIntrospectionConfig{Introspector: fake, Scopes: []string{"write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"active token", "Bearer good", http.StatusOK},
+		{"lower-case scheme", "bearer good", http.StatusOK},
+		{"missing header", "", http.StatusUnauthorized},
+		{"basic scheme", "Basic Zm9vOmJhcg==", http.StatusUnauthorized},
+		{"inactive token", "Bearer revoked", http.StatusUnauthorized},
+		{"insufficient scope", "Bearer readonly", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := request(http.MethodGet, "/", "192.0.2.1:1234")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if w := serve(req, handler); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenIntrospectionCachesActiveTokens(t *testing.T) {
+	fake := &fakeIntrospector{responses: map[string]*introspection.Response{"good": {Active: true}}}
+	handler, _ := TokenIntrospection(TokenIntrospectionConfig{Introspector: fake})
+	for i := 0; i < 3; i++ {
+		serve(request(http.MethodGet, "/", "192.0.2.1:1234", "Authorization", "Bearer good"), handler)
+		serve(request(http.MethodGet, "/", "192.0.2.1:1234", "Authorization", "Bearer bad"), handler)
+	}
+	if fake.calls != 4 {
+		t.Errorf("introspection calls = %d, want 1 for the active token and 3 for the inactive one", fake.calls)
+	}
+}
+
+func TestTokenIntrospectionUnavailable(t *testing.T) {
+	handler, _ := TokenIntrospection(TokenIntrospectionConfig{Introspector: &fakeIntrospector{err: errors.New("down")}})
+	if w := serve(request(http.MethodGet, "/", "192.0.2.1:1234", "Authorization", "Bearer good"), handler); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+func TestTokenIntrospectionRequiresIntrospector(t *testing.T) {
+	if _, err := TokenIntrospection(TokenIntrospectionConfig{}); err == nil {
+		t.Error("nil Introspector accepted")
+	}
+}
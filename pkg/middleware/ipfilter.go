@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPFilterConfig configures the IPFilter middleware. Entries are CIDRs or
+// single addresses.
+type IPFilterConfig struct {
+	// Allow, when non-empty, admits only clients inside one of the ranges.
+	Allow []string
+	// Deny rejects clients inside any of the ranges. Deny wins over Allow.
+	Deny []string
+}
+
+// IPFilter rejects clients outside the allow list or inside the deny list
+// with 403. The client IP is the one resolved by the ClientIP middleware,
+// or the peer address when it is not installed; install ClientIP with the
+// load balancer as trusted proxy to filter on the forwarded address.
+func IPFilter(cfg IPFilterConfig) (gin.HandlerFunc, error) {
+	allow, err := parsePrefixes(cfg.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parsePrefixes(cfg.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		addr, err := netip.ParseAddr(clientIP(c))
+		if err != nil {
+			abortWithProblem(c, http.StatusForbidden, "client address could not be determined")
+			return
+		}
+		addr = addr.Unmap()
+
+		if containsAddr(deny, addr) || (len(allow) > 0 && !containsAddr(allow, addr)) {
+			abortWithProblem(c, http.StatusForbidden, "client address not permitted")
+			return
+		}
+		c.Next()
+	}, nil
+}
+
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %q: %w", entry, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIPFilter(t *testing.T) {
+	resolver, err := NewClientIPResolver(ClientIPConfig{TrustedProxies: []string{"10.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		cfg        IPFilterConfig
+		resolve    bool
+		remoteAddr string
+		headers    []string
+		want       int
+	}{
+		{"allowed", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, false, "192.0.2.7:1234", nil, http.StatusOK},
+		{"not allowed", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, false, "198.51.100.1:1234", nil, http.StatusForbidden},
+		{"denied", IPFilterConfig{Deny: []string{"192.0.2.7"}}, false, "192.0.2.7:1234", nil, http.StatusForbidden},
+		{"deny wins", IPFilterConfig{Allow: []string{"192.0.2.0/24"}, Deny: []string{"192.0.2.7"}}, false, "192.0.2.7:1234", nil, http.StatusForbidden},
+		{"mapped IPv4", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, false, "[::ffff:192.0.2.7]:1234", nil, http.StatusOK},
+		{"spoofed header without ClientIP", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, false, "198.51.100.1:1234",
+			[]string{"X-Forwarded-For", "192.0.2.7"}, http.StatusForbidden},
+		{"spoofed header from untrusted peer", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, true, "198.51.100.1:1234",
+			[]string{"X-Forwarded-For", "192.0.2.7"}, http.StatusForbidden},
+		{"forwarded by trusted proxy", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, true, "10.0.0.1:1234",
+			[]string{"X-Forwarded-For", "192.0.2.7"}, http.StatusOK},
+		{"spoofed leftmost entry", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, true, "10.0.0.1:1234",
+			[]string{"X-Forwarded-For", "192.0.2.7, 198.51.100.1"}, http.StatusForbidden},
+		{"unparsable peer", IPFilterConfig{Allow: []string{"192.0.2.0/24"}}, false, "garbage", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := IPFilter(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handlers := []gin.HandlerFunc{filter}
+			if tt.resolve {
+				handlers = append([]gin.HandlerFunc{ClientIP(resolver)}, handlers...)
+			}
+			w := serve(request(http.MethodGet, "/", tt.remoteAddr, tt.headers...), handlers...)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPFilterInvalidConfig(t *testing.T) {
+	for _, cfg := range []IPFilterConfig{
+		{Allow: []string{"not-an-ip"}},
+		{Deny: []string{"192.0.2.0/33"}},
+	} {
+		if _, err := IPFilter(cfg); err == nil {
+			t.Errorf("IPFilter(%+v) succeeded, want error", cfg)
+		}
+	}
+}
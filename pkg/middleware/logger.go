@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var slowRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_slow_requests_total",
+	Help: "Requests that exceeded the request logger's slow threshold.",
+}, []string{"method", "route"})
+
+// LoggerConfig configures the RequestLogger middleware.
+type LoggerConfig struct {
+	// SlowThreshold, when positive, logs requests taking longer at warn
+	// level with slow=true.
+	SlowThreshold time.Duration
+	// SlowMetric additionally counts slow requests in
+	// http_slow_requests_total.
+	SlowMetric bool
+
+	// RequestHeaders and ResponseHeaders to capture, logged under
+	// request_headers and response_headers.
+	RequestHeaders  []string
+	ResponseHeaders []string
+	// UserAgent logs the User-Agent header as user_agent.
+	UserAgent bool
+	// Query logs the raw query string, with the values of RedactQuery
+	// parameters masked.
+	Query       bool
+	RedactQuery []string
+	// Route logs the matched route pattern, e.g. /users/:id.
+	Route bool
+	// RequestID logs the ID assigned by the RequestID middleware.
+	RequestID bool
+	// Fields, when set, is called to add custom fields to every entry.
+	Fields func(c *gin.Context, event *zerolog.Event)
+
+	// SkipPaths are exact paths or path.Match globs, e.g. /healthz/*, that
+	// are not logged.
+	SkipPaths []string
+	// SkipPrefixes skip every path starting with one of the prefixes.
+	SkipPrefixes []string
+	// SkipPatterns skip paths matching any of the regular expressions.
+	SkipPatterns []*regexp.Regexp
+	// SkipFunc, when set, skips requests for which it returns true.
+	SkipFunc func(r *http.Request) bool
+
+	// Sampling, when set, logs only a fraction of requests. Slow requests
+	// are always logged.
+	Sampling *LogSampling
+}
+
+// LogSampling holds the fraction, in [0, 1], of requests logged per status
+// class.
+type LogSampling struct {
+	Success     float64
+	ClientError float64
+	ServerError float64
+	// Routes overrides the rates per Gin route pattern.
+	Routes map[string]LogSampling
+}
+
+// DefaultLogSampling logs 1% of successful requests and every error.
+func DefaultLogSampling() *LogSampling {
+	return &LogSampling{Success: 0.01, ClientError: 1, ServerError: 1}
+}
+
+// sampled reports whether a request with status on route is logged.
+func (s *LogSampling) sampled(route string, status int) bool {
+	rates := *s
+	if override, ok := s.Routes[route]; ok {
+		rates = override
+	}
+
+	rate := rates.Success
+	switch {
+	case status >= 500:
+		rate = rates.ServerError
+	case status >= 400:
+		rate = rates.ClientError
+	}
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
+
+// skip reports whether the request must not be logged.
+func (cfg *LoggerConfig) skip(r *http.Request) bool {
+	p := r.URL.Path
+	for _, pattern := range cfg.SkipPaths {
+		if pattern == p {
+			return true
+		}
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+	}
+	for _, prefix := range cfg.SkipPrefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	for _, re := range cfg.SkipPatterns {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return cfg.SkipFunc != nil && cfg.SkipFunc(r)
+}
+
+// RequestLoggerWithSkip is RequestLogger with default fields that does not
+// log the given paths, which may be exact paths or path.Match globs.
+func RequestLoggerWithSkip(skipPaths ...string) gin.HandlerFunc {
+	return RequestLogger(LoggerConfig{SkipPaths: skipPaths})
+}
+
+// RequestLogger writes one zerolog entry per request. Server errors are
+// logged at error level, slow requests at warn and everything else at info.
+func RequestLogger(cfg LoggerConfig) gin.HandlerFunc {
+	redactQuery := make(map[string]bool, len(cfg.RedactQuery))
+	for _, p := range cfg.RedactQuery {
+		redactQuery[strings.ToLower(p)] = true
+	}
+
+	return func(c *gin.Context) {
+		if cfg.skip(c.Request) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		latency := time.Since(start)
+
+		status := c.Writer.Status()
+		slow := cfg.SlowThreshold > 0 && latency > cfg.SlowThreshold
+		if !slow && cfg.Sampling != nil && !cfg.Sampling.sampled(c.FullPath(), status) {
+			return
+		}
+
+		level := zerolog.InfoLevel
+		switch {
+		case status >= 500:
+			level = zerolog.ErrorLevel
+		case slow:
+			level = zerolog.WarnLevel
+		}
+
+		if slow && cfg.SlowMetric {
+			slowRequests.WithLabelValues(c.Request.Method, c.FullPath()).Inc()
+		}
+
+		event := log.WithLevel(level).
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", latency).
+			Str("ip", clientIP(c)).
+			Int("size", c.Writer.Size())
+		if slow {
+			event = event.Bool("slow", true)
+		}
+		if cfg.Route {
+			event = event.Str("route", c.FullPath())
+		}
+		if cfg.RequestID {
+			event = event.Str("request_id", RequestIDFromContext(c.Request.Context()))
+		}
+		if cfg.UserAgent {
+			event = event.Str("user_agent", c.Request.UserAgent())
+		}
+		if cfg.Query && c.Request.URL.RawQuery != "" {
+			event = event.Str("query", redactQueryString(c.Request.URL.RawQuery, redactQuery))
+		}
+		if len(cfg.RequestHeaders) > 0 {
+			event = event.Dict("request_headers", headerDict(c.Request.Header, cfg.RequestHeaders))
+		}
+		if len(cfg.ResponseHeaders) > 0 {
+			event = event.Dict("response_headers", headerDict(c.Writer.Header(), cfg.ResponseHeaders))
+		}
+		if err := c.Errors.ByType(gin.ErrorTypePrivate).Last(); err != nil {
+			event = event.Err(err)
+		}
+		if cfg.Fields != nil {
+			cfg.Fields(c, event)
+		}
+		event.Msg("HTTP request")
+	}
+}
+
+func headerDict(header http.Header, names []string) *zerolog.Event {
+	dict := zerolog.Dict()
+	for _, name := range names {
+		if values := header.Values(name); len(values) > 0 {
+			dict = dict.Str(strings.ToLower(name), strings.Join(values, ", "))
+		}
+	}
+	return dict
+}
+
+func redactQueryString(raw string, redact map[string]bool) string {
+	if len(redact) == 0 {
+		return raw
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return "[unparseable]"
+	}
+	for key, vs := range values {
+		if redact[strings.ToLower(key)] {
+			for i := range vs {
+				vs[i] = redactedValue
+			}
+		}
+	}
+	return values.Encode()
+}
@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// logEntries decodes the JSON log lines in out.
+func logEntries(t *testing.T, out string) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func respond(status int, delay time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		time.Sleep(delay)
+		c.Status(status)
+	}
+}
+
+func TestRequestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		delay     time.Duration
+		threshold time.Duration
+		wantLevel string
+		wantSlow  bool
+	}{
+		{"success", http.StatusOK, 0, 0, "info", false},
+		{"client error", http.StatusNotFound, 0, 0, "info", false},
+		{"server error", http.StatusBadGateway, 0, 0, "error", false},
+		{"slow", http.StatusOK, 5 * time.Millisecond, time.Millisecond, "warn", true},
+		{"slow server error", http.StatusInternalServerError, 5 * time.Millisecond, time.Millisecond, "error", true},
+		{"fast with threshold", http.StatusOK, 0, time.Hour, "info", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t, zerolog.DebugLevel)
+			serveLast(request(http.MethodGet, "/items?x=1", "192.0.2.7:1234"),
+				RequestLogger(LoggerConfig{SlowThreshold: tt.threshold}), respond(tt.status, tt.delay))
+
+			entries := logEntries(t, buf.String())
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e["level"] != tt.wantLevel {
+				t.Errorf("level = %v, want %s", e["level"], tt.wantLevel)
+			}
+			if (e["slow"] == true) != tt.wantSlow {
+				t.Errorf("slow = %v, want %v", e["slow"], tt.wantSlow)
+			}
+			if e["status"] != float64(tt.status) || e["path"] != "/items" || e["method"] != "GET" {
+				t.Errorf("entry = %v", e)
+			}
+		})
+	}
+}
+
+func TestRequestLoggerIgnoresForwardedFor(t *testing.T) {
+	buf := captureLog(t, zerolog.DebugLevel)
+	serve(request(http.MethodGet, "/", "192.0.2.7:1234", "X-Forwarded-For", "203.0.113.9"), RequestLogger(LoggerConfig{}))
+
+	if e := logEntries(t, buf.String()); len(e) != 1 || e[0]["ip"] != "192.0.2.7" {
+		t.Errorf("entries = %v, want ip 192.0.2.7", e)
+	}
+}
+
+func TestRequestLoggerFields(t *testing.T) {
+	cfg := LoggerConfig{
+		RequestHeaders:  []string{"X-Tenant"},
+		ResponseHeaders: []string{"Cache-Control"},
+		UserAgent:       true,
+		Query:           true,
+		RedactQuery:     []string{"token"},
+		Route:           true,
+		RequestID:       true,
+		Fields:          func(c *gin.Context, e *zerolog.Event) { e.Str("custom", "yes") },
+	}
+	buf := captureLog(t, zerolog.DebugLevel)
+	handler := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+	}
+	serveLast(request(http.MethodGet, "/items?page=2&Token=s3cret", "192.0.2.7:1234",
+		"X-Tenant", "acme", "User-Agent", "probe/1.0"), RequestID(), RequestLogger(cfg), handler)
+
+	entries := logEntries(t, buf.String())
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	tests := []struct {
+		field string
+		want  interface{}
+	}{
+		{"user_agent", "probe/1.0"},
+		{"route", "/*path"},
+		{"custom", "yes"},
+		{"query", "Token=%5BREDACTED%5D&page=2"},
+		{"request_headers", map[string]interface{}{"x-tenant": "acme"}},
+		{"response_headers", map[string]interface{}{"cache-control": "no-store"}},
+	}
+	for _, tt := range tests {
+		got, _ := json.Marshal(e[tt.field])
+		want, _ := json.Marshal(tt.want)
+		if string(got) != string(want) {
+			t.Errorf("%s = %s, want %s", tt.field, got, want)
+		}
+	}
+	if id, _ := e["request_id"].(string); id == "" {
+		t.Error("request_id missing")
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("redacted query value logged: %s", buf.String())
+	}
+}
+
+func TestRedactQueryString(t *testing.T) {
+	redact := map[string]bool{"code": true}
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"code=abc&state=xyz", "code=%5BREDACTED%5D&state=xyz"},
+		{"CODE=abc&code=def", "CODE=%5BREDACTED%5D&code=%5BREDACTED%5D"},
+		{"state=xyz", "state=xyz"},
+		{"code=%zz", "[unparseable]"},
+	}
+	for _, tt := range tests {
+		if got := redactQueryString(tt.raw, redact); got != tt.want {
+			t.Errorf("redactQueryString(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+	if got := redactQueryString("code=abc", nil); got != "code=abc" {
+		t.Errorf("no redaction configured: got %q", got)
+	}
+}
+
+func TestLoggerConfigSkip(t *testing.T) {
+	cfg := LoggerConfig{
+		SkipPaths:    []string{"/healthz", "/status/*"},
+		SkipPrefixes: []string{"/static/"},
+		SkipPatterns: []*regexp.Regexp{regexp.MustCompile(`\.ico$`)},
+		SkipFunc:     func(r *http.Request) bool { return r.Header.Get("X-Probe") != "" },
+	}
+	tests := []struct {
+		path    string
+		headers []string
+		want    bool
+	}{
+		{"/healthz", nil, true},
+		{"/healthz/deep", nil, false},
+		{"/status/ready", nil, true},
+		{"/status/ready/extra", nil, false},
+		{"/static/app.js", nil, true},
+		{"/staticfile", nil, false},
+		{"/favicon.ico", nil, true},
+		{"/api/users", []string{"X-Probe", "1"}, true},
+		{"/api/users", nil, false},
+	}
+	for _, tt := range tests {
+		if got := cfg.skip(request(http.MethodGet, tt.path, "192.0.2.7:1234", tt.headers...)); got != tt.want {
+			t.Errorf("skip(%s %v) = %v, want %v", tt.path, tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLoggerWithSkip(t *testing.T) {
+	buf := captureLog(t, zerolog.DebugLevel)
+	handler := RequestLoggerWithSkip("/metrics")
+	serve(request(http.MethodGet, "/metrics", "192.0.2.7:1234"), handler)
+	if buf.Len() != 0 {
+		t.Errorf("skipped path logged: %s", buf.String())
+	}
+	serve(request(http.MethodGet, "/api", "192.0.2.7:1234"), handler)
+	if len(logEntries(t, buf.String())) != 1 {
+		t.Errorf("unskipped path not logged: %q", buf.String())
+	}
+}
+
+func TestLogSampling(t *testing.T) {
+	s := &LogSampling{
+		Success:     0,
+		ClientError: 1,
+		ServerError: 1,
+		Routes: map[string]LogSampling{
+			"/checkout": {Success: 1, ClientError: 0, ServerError: 1},
+		},
+	}
+	tests := []struct {
+		route  string
+		status int
+		want   bool
+	}{
+		{"/items", http.StatusOK, false},
+		{"/items", http.StatusBadRequest, true},
+		{"/items", http.StatusServiceUnavailable, true},
+		{"/checkout", http.StatusOK, true},
+		{"/checkout", http.StatusConflict, false},
+		{"/checkout", http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		if got := s.sampled(tt.route, tt.status); got != tt.want {
+			t.Errorf("sampled(%s, %d) = %v, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestLogSamplingRate(t *testing.T) {
+	s := &LogSampling{Success: 0.5}
+	n := 0
+	for i := 0; i < 2000; i++ {
+		if s.sampled("", http.StatusOK) {
+			n++
+		}
+	}
+	if n < 800 || n > 1200 {
+		t.Errorf("sampled %d of 2000 at rate 0.5", n)
+	}
+}
+
+func TestRequestLoggerSampling(t *testing.T) {
+	cfg := LoggerConfig{
+		SlowThreshold: time.Millisecond,
+		Sampling:      &LogSampling{Success: 0, ClientError: 0, ServerError: 1},
+	}
+	tests := []struct {
+		name   string
+		status int
+		delay  time.Duration
+		want   int
+	}{
+		{"dropped success", http.StatusOK, 0, 0},
+		{"dropped client error", http.StatusUnauthorized, 0, 0},
+		{"kept server error", http.StatusInternalServerError, 0, 1},
+		{"slow always kept", http.StatusOK, 5 * time.Millisecond, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t, zerolog.DebugLevel)
+			serveLast(request(http.MethodGet, "/", "192.0.2.7:1234"), RequestLogger(cfg), respond(tt.status, tt.delay))
+			if got := len(logEntries(t, buf.String())); got != tt.want {
+				t.Errorf("got %d entries, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// MaintenanceConfig configures a MaintenanceMode.
+type MaintenanceConfig struct {
+	// RetryAfter advertised to clients while in maintenance. Defaults to 5m.
+	RetryAfter time.Duration
+	// Body rendered as JSON instead of the default problem details.
+	Body interface{}
+	// BypassIPs are CIDRs or addresses that are always served. They are
+	// matched against the address resolved by ClientIP, or the peer address
+	// when it is not installed, never a bare forwarding header.
+	BypassIPs []string
+	// BypassHeader and BypassValue let requests carrying the header with
+	// the given value through, e.g. for smoke tests after a deploy.
+	BypassHeader string
+	BypassValue  string
+}
+
+// MaintenanceMode holds the runtime toggle used by the Maintenance
+// middleware. It is safe for concurrent use.
+type MaintenanceMode struct {
+	cfg     MaintenanceConfig
+	bypass  []netip.Prefix
+	enabled atomic.Bool
+}
+
+// NewMaintenanceMode returns a disabled MaintenanceMode.
+func NewMaintenanceMode(cfg MaintenanceConfig) (*MaintenanceMode, error) {
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 5 * time.Minute
+	}
+	bypass, err := parsePrefixes(cfg.BypassIPs)
+	if err != nil {
+		return nil, err
+	}
+	return &MaintenanceMode{cfg: cfg, bypass: bypass}, nil
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *MaintenanceMode) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Set turns maintenance mode on or off.
+func (m *MaintenanceMode) Set(enabled bool) {
+	if m.enabled.Swap(enabled) != enabled {
+		log.Warn().Bool("enabled", enabled).Msg("Maintenance mode toggled")
+	}
+}
+
+// Watch polls source every interval and applies its result until ctx is
+// done, so the toggle can live in shared storage such as a Redis key.
+// Source errors are logged and leave the current state untouched.
+// Intervals that are not positive default to 10s.
+func (m *MaintenanceMode) Watch(ctx context.Context, interval time.Duration, source func(ctx context.Context) (bool, error)) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if enabled, err := source(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to read maintenance mode source")
+			} else {
+				m.Set(enabled)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+type maintenanceState struct {
+	Enabled bool `json:"enabled"`
+}
+
+// AdminHandler reports the toggle on GET and updates it from a JSON body
+// {"enabled": bool} on other methods. Protect it with BasicAuth or
+// StaticBearer.
+func (m *MaintenanceMode) AdminHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			var state maintenanceState
+			if err := c.ShouldBindJSON(&state); err != nil {
+				abortWithProblem(c, http.StatusBadRequest, "expected {\"enabled\": bool}")
+				return
+			}
+			m.Set(state.Enabled)
+		}
+		c.JSON(http.StatusOK, maintenanceState{Enabled: m.Enabled()})
+	}
+}
+
+// Maintenance answers 503 with Retry-After while m is enabled, except for
+// bypassed clients.
+func Maintenance(m *MaintenanceMode) gin.HandlerFunc {
+	retryAfter := strconv.Itoa(int(math.Ceil(m.cfg.RetryAfter.Seconds())))
+
+	return func(c *gin.Context) {
+		if !m.Enabled() || m.bypassed(c) {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", retryAfter)
+		if m.cfg.Body != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, m.cfg.Body)
+			return
+		}
+		abortWithProblem(c, http.StatusServiceUnavailable, "service under maintenance")
+	}
+}
+
+func (m *MaintenanceMode) bypassed(c *gin.Context) bool {
+	if m.cfg.BypassHeader != "" && m.cfg.BypassValue != "" &&
+		subtle.ConstantTimeCompare([]byte(c.GetHeader(m.cfg.BypassHeader)), []byte(m.cfg.BypassValue)) == 1 {
+		return true
+	}
+	if len(m.bypass) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(clientIP(c))
+	return err == nil && containsAddr(m.bypass, addr.Unmap())
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenance(t *testing.T) {
+	cfg := MaintenanceConfig{
+		RetryAfter:   90 * time.Second,
+		BypassIPs:    []string{"192.0.2.0/24"},
+		BypassHeader: "X-Maintenance-Bypass",
+		BypassValue:  "s3cret",
+	}
+	tests := []struct {
+		name       string
+		enabled    bool
+		remoteAddr string
+		headers    []string
+		want       int
+	}{
+		{"disabled", false, "198.51.100.1:1234", nil, http.StatusOK},
+		{"enabled", true, "198.51.100.1:1234", nil, http.StatusServiceUnavailable},
+		{"bypass IP", true, "192.0.2.7:1234", nil, http.StatusOK},
+		{"bypass header", true, "198.51.100.1:1234", []string{"X-Maintenance-Bypass", "s3cret"}, http.StatusOK},
+		{"wrong bypass header", true, "198.51.100.1:1234", []string{"X-Maintenance-Bypass", "guess"}, http.StatusServiceUnavailable},
+		{"spoofed forwarding header", true, "198.51.100.1:1234", []string{"X-Forwarded-For", "192.0.2.7"}, http.StatusServiceUnavailable},
+		{"spoofed real IP header", true, "198.51.100.1:1234", []string{"X-Real-IP", "192.0.2.7"}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMaintenanceMode(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Set(tt.enabled)
+			w := serve(request(http.MethodGet, "/", tt.remoteAddr, tt.headers...), Maintenance(m))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusServiceUnavailable && w.Header().Get("Retry-After") != "90" {
+				t.Errorf("Retry-After = %q, want 90", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestMaintenanceInvalidBypass(t *testing.T) {
+	if _, err := NewMaintenanceMode(MaintenanceConfig{BypassIPs: []string{"nope"}}); err == nil {
+		t.Error("NewMaintenanceMode succeeded with an invalid bypass address")
+	}
+}
+
+func TestMaintenanceAdminHandler(t *testing.T) {
+	m, _ := NewMaintenanceMode(MaintenanceConfig{})
+	tests := []struct {
+		method, body string
+		want         int
+		enabled      bool
+	}{
+		{http.MethodGet, "", http.StatusOK, false},
+		{http.MethodPut, `{"enabled": true}`, http.StatusOK, true},
+		{http.MethodPut, `{"enabled": "yes"}`, http.StatusBadRequest, true},
+		{http.MethodPut, `{"enabled": false}`, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := serveLast(req, m.AdminHandler())
+		if w.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.body, w.Code, tt.want)
+		}
+		if m.Enabled() != tt.enabled {
+			t.Errorf("%s %s: enabled = %v, want %v", tt.method, tt.body, m.Enabled(), tt.enabled)
+		}
+		if tt.want == http.StatusOK {
+			var state maintenanceState
+			if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil || state.Enabled != tt.enabled {
+				t.Errorf("%s %s: body = %s", tt.method, tt.body, w.Body)
+			}
+		}
+	}
+}
+
+func TestMaintenanceWatch(t *testing.T) {
+	m, _ := NewMaintenanceMode(MaintenanceConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := make(chan struct{}, 10)
+	m.Watch(ctx, 0, func(context.Context) (bool, error) {
+		calls <- struct{}{}
+		return true, nil
+	})
+	<-calls
+	deadline := time.Now().Add(time.Second)
+	for !m.Enabled() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !m.Enabled() {
+		t.Fatal("Watch did not apply the source")
+	}
+
+	m2, _ := NewMaintenanceMode(MaintenanceConfig{})
+	m2.Set(true)
+	done := make(chan struct{})
+	m2.Watch(ctx, time.Hour, func(context.Context) (bool, error) {
+		defer close(done)
+		return false, errors.New("unavailable")
+	})
+	<-done
+	if !m2.Enabled() {
+		t.Error("a failing source changed the state")
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// LimitResult is the outcome of a single Limiter decision.
+type LimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key may proceed.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (LimitResult, error)
+}
+
+// KeyFunc extracts the rate limiting key from a request.
+type KeyFunc func(c *gin.Context) string
+
+// KeyByIP limits per client IP: the address resolved by ClientIP, or the
+// peer address when it is not installed, so clients cannot pick a fresh
+// bucket by rotating forwarding headers.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + clientIP(c)
+}
+
+// KeyByAPIKey limits per API key ID as resolved by APIKey, falling back to
+// the client IP for unauthenticated requests.
+func KeyByAPIKey(c *gin.Context) string {
+	if info, ok := APIKeyFromContext(c); ok {
+		return "key:" + info.ID
+	}
+	return KeyByIP(c)
+}
+
+// RateLimitConfig configures the RateLimit middleware.
+type RateLimitConfig struct {
+	Limiter Limiter
+	// KeyFunc defaults to KeyByIP.
+	KeyFunc KeyFunc
+}
+
+// RateLimit rejects requests exceeding the configured limit with 429 Too
+// Many Requests and a Retry-After header. Limiter failures fail open.
+func RateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	keyFunc := cfg.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+
+	return func(c *gin.Context) {
+		res, err := cfg.Limiter.Allow(c.Request.Context(), keyFunc(c))
+		if err != nil {
+			log.Error().Err(err).Msg("Rate limiter unavailable")
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+			abortWithProblem(c, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// MemoryLimiter is an in-process token bucket Limiter suitable for single
+// instances. Idle buckets are evicted periodically.
+type MemoryLimiter struct {
+	rate  float64 // tokens per second
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryLimiter allows limit requests per period with bursts of up to
+// burst requests. A burst below one defaults to limit. Limit and period
+// must be positive.
+func NewMemoryLimiter(limit int, period time.Duration, burst int) (*MemoryLimiter, error) {
+	if limit <= 0 || period <= 0 {
+		return nil, errors.New("rate limit and period must be positive")
+	}
+	if burst < 1 {
+		burst = limit
+	}
+	return &MemoryLimiter{
+		rate:    float64(limit) / period.Seconds(),
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+	}, nil
+}
+
+// Allow takes a token from the bucket for key.
+func (l *MemoryLimiter) Allow(_ context.Context, key string) (LimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	res := LimitResult{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+		res.Remaining = int(b.tokens)
+		return res, nil
+	}
+	res.RetryAfter = time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return res, nil
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	full := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, key)
+		}
+	}
+}
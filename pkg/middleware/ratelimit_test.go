@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewMemoryLimiterInvalid(t *testing.T) {
+	tests := []struct {
+		limit  int
+		period time.Duration
+	}{
+		{0, time.Second},
+		{-1, time.Second},
+		{10, 0},
+		{10, -time.Second},
+	}
+	for _, tt := range tests {
+		if _, err := NewMemoryLimiter(tt.limit, tt.period, 0); err == nil {
+			t.Errorf("NewMemoryLimiter(%d, %s) succeeded, want error", tt.limit, tt.period)
+		}
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	l, err := NewMemoryLimiter(2, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for i, want := range []bool{true, true, false} {
+		res, err := l.Allow(ctx, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != want {
+			t.Fatalf("request %d: allowed = %v, want %v", i, res.Allowed, want)
+		}
+		if res.Limit != 2 {
+			t.Errorf("request %d: limit = %d, want 2", i, res.Limit)
+		}
+		if !want && (res.RetryAfter <= 0 || res.RetryAfter > 30*time.Minute) {
+			t.Errorf("request %d: retry after = %s", i, res.RetryAfter)
+		}
+	}
+	if res, _ := l.Allow(ctx, "b"); !res.Allowed {
+		t.Error("keys share a bucket")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests [][]string // remote address followed by header pairs
+		want     []int
+	}{
+		{
+			name:     "same client",
+			requests: [][]string{{"192.0.2.1:1"}, {"192.0.2.1:2"}},
+			want:     []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:     "different clients",
+			requests: [][]string{{"192.0.2.1:1"}, {"192.0.2.2:1"}},
+			want:     []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name: "rotating forwarding headers",
+			requests: [][]string{
+				{"192.0.2.1:1", "X-Forwarded-For", "198.51.100.1"},
+				{"192.0.2.1:1", "X-Forwarded-For", "198.51.100.2"},
+			},
+			want: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewMemoryLimiter(1, time.Hour, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mw := RateLimit(RateLimitConfig{Limiter: l})
+			for i, r := range tt.requests {
+				w := serve(request(http.MethodGet, "/", r[0], r[1:]...), mw)
+				if w.Code != tt.want[i] {
+					t.Fatalf("request %d: status = %d, want %d", i, w.Code, tt.want[i])
+				}
+				if w.Header().Get("X-RateLimit-Limit") != "1" {
+					t.Errorf("request %d: X-RateLimit-Limit = %q", i, w.Header().Get("X-RateLimit-Limit"))
+				}
+				if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: missing Retry-After", i)
+				}
+			}
+		})
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string) (LimitResult, error) {
+	return LimitResult{}, errors.New("unavailable")
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	w := serve(request(http.MethodGet, "/", "192.0.2.1:1"), RateLimit(RateLimitConfig{Limiter: failingLimiter{}}))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
+
+func TestKeyByAPIKey(t *testing.T) {
+	var keys []string
+	record := func(c *gin.Context) { keys = append(keys, KeyByAPIKey(c)) }
+	serve(request(http.MethodGet, "/", "192.0.2.1:1"), record)
+	serve(request(http.MethodGet, "/", "192.0.2.1:1"), func(c *gin.Context) {
+		c.Set(APIKeyContextKey, &KeyInfo{ID: "k1"})
+	}, record)
+	if keys[0] != "ip:192.0.2.1" || keys[1] != "key:k1" {
+		t.Errorf("keys = %q", keys)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/PhilipKram/gms-foundation/pkg/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID on requests and responses.
+const RequestIDHeader = requestid.Header
+
+// RequestIDContextKey is the Gin context key holding the request ID.
+const RequestIDContextKey = "middleware.requestID"
+
+const maxRequestIDLength = 128
+
+// RequestID propagates the incoming X-Request-ID or generates a new one,
+// echoes it on the response and stores it in both the Gin and the request
+// context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(RequestIDContextKey, id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestIDFromContext returns the request ID stored by RequestID, or an
+// empty string. It is requestid.FromContext.
+func RequestIDFromContext(ctx context.Context) string {
+	return requestid.FromContext(ctx)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts IDs of reasonable length made of printable ASCII,
+// so client-supplied values cannot inject into logs or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	docs := SecureHeadersConfig{
+		ContentSecurityPolicy: "default-src 'self'",
+		CSPReportOnly:         true,
+		FrameOptions:          "SAMEORIGIN",
+	}
+	cfg := DefaultSecureHeadersConfig()
+	cfg.HSTSPreload = true
+	cfg.Routes = map[string]SecureHeadersConfig{"/docs/*any": docs}
+
+	router := gin.New()
+	router.Use(SecureHeaders(cfg))
+	router.GET("/api/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/docs/*any", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{"/api/users", map[string]string{
+			"Strict-Transport-Security":           "max-age=31536000; includeSubDomains; preload",
+			"Content-Security-Policy":             "default-src 'none'; frame-ancestors 'none'",
+			"Content-Security-Policy-Report-Only": "",
+			"X-Frame-Options":                     "DENY",
+			"X-Content-Type-Options":              "nosniff",
+			"Referrer-Policy":                     "strict-origin-when-cross-origin",
+			"Permissions-Policy":                  "camera=(), microphone=(), geolocation=()",
+		}},
+		{"/docs/index.html", map[string]string{
+			"Strict-Transport-Security":           "",
+			"Content-Security-Policy":             "",
+			"Content-Security-Policy-Report-Only": "default-src 'self'",
+			"X-Frame-Options":                     "SAMEORIGIN",
+			"X-Content-Type-Options":              "",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			for name, want := range tt.want {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSecureHeadersHSTSSeconds(t *testing.T) {
+	w := serve(request(http.MethodGet, "/", "192.0.2.1:1234"), SecureHeaders(SecureHeadersConfig{HSTSMaxAge: 90 * time.Minute}))
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=5400" {
+		t.Errorf("Strict-Transport-Security = %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+package oauth2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256" // register SHA-256 for crypto.Hash
+	_ "crypto/sha512" // register SHA-384/512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ClientAssertionType is the client_assertion_type of private_key_jwt
+// client authentication (RFC 7523).
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// DefaultAssertionLifetime is the validity of a client assertion.
+const DefaultAssertionLifetime = 5 * time.Minute
+
+// AssertionConfig describes a signed JWT identifying the client, used for
+// private_key_jwt authentication or as a JWT client secret.
+type AssertionConfig struct {
+	// ClientID is the sub claim, and the iss claim unless Issuer is set.
+	ClientID string
+	// Issuer overrides iss, e.g. with the team ID for Apple client secrets.
+	Issuer string
+	// Audience is usually the token endpoint or the provider's issuer.
+	Audience string
+	// Key is an *ecdsa.PrivateKey or *rsa.PrivateKey.
+	Key crypto.Signer
+	// KeyID is the kid header the provider uses to find the public key.
+	KeyID string
+	// Algorithm defaults to ES256, ES384 or ES512 for EC keys according to
+	// the curve, and RS256 for RSA keys.
+	Algorithm string
+	// Lifetime defaults to DefaultAssertionLifetime.
+	Lifetime time.Duration
+}
+
+var assertionHash = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// esCurveBits maps ECDSA algorithms to the size of their required curve.
+var esCurveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+// NewClientAssertion signs a client assertion for cfg with a fresh jti.
+func NewClientAssertion(cfg AssertionConfig) (string, error) {
+	if cfg.ClientID == "" || cfg.Audience == "" || cfg.Key == nil {
+		return "", errors.New("client assertion requires a client ID, audience and key")
+	}
+	alg := cfg.Algorithm
+	if alg == "" {
+		switch k := cfg.Key.(type) {
+		case *ecdsa.PrivateKey:
+			for name, bits := range esCurveBits {
+				if bits == k.Curve.Params().BitSize {
+					alg = name
+				}
+			}
+		case *rsa.PrivateKey:
+			alg = "RS256"
+		}
+	}
+	hash, ok := assertionHash[alg]
+	if !ok {
+		return "", fmt.Errorf("unsupported client assertion algorithm %q for %T", alg, cfg.Key)
+	}
+	lifetime := cfg.Lifetime
+	if lifetime <= 0 {
+		lifetime = DefaultAssertionLifetime
+	}
+	issuer := cfg.Issuer
+	if issuer == "" {
+		issuer = cfg.ClientID
+	}
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": cfg.KeyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": issuer,
+		"sub": cfg.ClientID,
+		"aud": cfg.Audience,
+		"iat": now.Unix(),
+		"exp": now.Add(lifetime).Unix(),
+		"jti": jti,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	h := hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	var signature []byte
+	switch k := cfg.Key.(type) {
+	case *ecdsa.PrivateKey:
+		if esCurveBits[alg] != k.Curve.Params().BitSize {
+			return "", fmt.Errorf("algorithm %s does not match P-%d key", alg, k.Curve.Params().BitSize)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			return "", err
+		}
+		// JWS uses the fixed-size r||s encoding rather than ASN.1.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	case *rsa.PrivateKey:
+		if alg[:2] != "RS" {
+			return "", fmt.Errorf("algorithm %s does not match RSA key", alg)
+		}
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported client assertion key %T", cfg.Key)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
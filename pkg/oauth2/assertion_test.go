@@ -0,0 +1,231 @@
+package oauth2
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// verifyJWT checks the signature of a compact JWS against pub and returns
+// its header and claims.
+func verifyJWT(t *testing.T, raw string, pub crypto.PublicKey) (header, claims map[string]interface{}) {
+	t.Helper()
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT %q", raw)
+	}
+	decode := func(s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	if err := json.Unmarshal(decode(parts[0]), &header); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(decode(parts[1]), &claims); err != nil {
+		t.Fatal(err)
+	}
+	hash := assertionHash[header["alg"].(string)]
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	digest := h.Sum(nil)
+	sig := decode(parts[2])
+
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		size := len(sig) / 2
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			t.Fatal("invalid ECDSA signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, sig); err != nil {
+			t.Fatalf("invalid RSA signature: %v", err)
+		}
+	}
+	return header, claims
+}
+
+func TestSecretAssertion(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := ClientAuth{
+		ClientID: "com.example.web",
+		SecretAssertion: &AssertionConfig{
+			ClientID: "com.example.web",
+			Issuer:   "TEAM123456",
+			Audience: "https://appleid.apple.com",
+			Key:      key,
+			KeyID:    "KEY1234567",
+		},
+	}
+
+	var secrets []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("client_assertion") != "" {
+			t.Error("secret JWT sent as client_assertion")
+		}
+		secrets = append(secrets, r.PostForm.Get("client_secret"))
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "at"})
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := PostToken(context.Background(), nil, srv.URL, auth, url.Values{"grant_type": {"authorization_code"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if secrets[0] == secrets[1] {
+		t.Error("client secret was not signed per request")
+	}
+	header, claims := verifyJWT(t, secrets[0], &key.PublicKey)
+	if header["alg"] != "ES256" || header["kid"] != "KEY1234567" {
+		t.Errorf("header = %v", header)
+	}
+	if claims["iss"] != "TEAM123456" || claims["sub"] != "com.example.web" || claims["aud"] != "https://appleid.apple.com" {
+		t.Errorf("claims = %v", claims)
+	}
+}
+
+func TestSecretAssertionBasic(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	auth := ClientAuth{
+		ClientID:        "app",
+		Basic:           true,
+		SecretAssertion: &AssertionConfig{ClientID: "app", Audience: "https://idp.example", Key: key},
+	}
+	req, err := NewFormRequest(context.Background(), "https://idp.example/token", auth, url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, pass, ok := req.BasicAuth()
+	if !ok || user != "app" {
+		t.Fatalf("basic auth = %q, %v", user, ok)
+	}
+	secret, _ := url.QueryUnescape(pass)
+	verifyJWT(t, secret, &key.PublicKey)
+}
+
+func TestSecretAssertionInvalid(t *testing.T) {
+	auth := ClientAuth{ClientID: "app", SecretAssertion: &AssertionConfig{ClientID: "app"}}
+	if _, err := NewFormRequest(context.Background(), "https://idp.example/token", auth, url.Values{}); err == nil {
+		t.Error("secret assertion without audience and key accepted")
+	}
+}
+
+func TestNewClientAssertion(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	p521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		alg     string
+		wantAlg string
+		wantErr bool
+	}{
+		{"P-256 default", p256, "", "ES256", false},
+		{"P-384 default", p384, "", "ES384", false},
+		{"P-521 default", p521, "", "ES512", false},
+		{"RSA default", rsaKey, "", "RS256", false},
+		{"RSA RS512", rsaKey, "RS512", "RS512", false},
+		{"ES256 on RSA key", rsaKey, "ES256", "", true},
+		{"RS256 on EC key", p256, "RS256", "", true},
+		{"ES384 on P-256 key", p256, "ES384", "", true},
+		{"none", p256, "none", "", true},
+		{"HS256", rsaKey, "HS256", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := NewClientAssertion(AssertionConfig{
+				ClientID:  "app",
+				Audience:  "https://idp.example/token",
+				Key:       tt.key,
+				KeyID:     "kid-1",
+				Algorithm: tt.alg,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NewClientAssertion succeeded: %s", raw)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, claims := verifyJWT(t, raw, tt.key.Public())
+			if header["alg"] != tt.wantAlg || header["kid"] != "kid-1" {
+				t.Errorf("header = %v", header)
+			}
+			if claims["iss"] != "app" || claims["sub"] != "app" || claims["aud"] != "https://idp.example/token" {
+				t.Errorf("claims = %v", claims)
+			}
+			if life := claims["exp"].(float64) - claims["iat"].(float64); life != DefaultAssertionLifetime.Seconds() {
+				t.Errorf("lifetime = %vs", life)
+			}
+		})
+	}
+}
+
+func TestNewClientAssertionInvalidConfig(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for _, cfg := range []AssertionConfig{
+		{Audience: "aud", Key: key},
+		{ClientID: "app", Key: key},
+		{ClientID: "app", Audience: "aud"},
+	} {
+		if _, err := NewClientAssertion(cfg); err == nil {
+			t.Errorf("NewClientAssertion(%+v) succeeded", cfg)
+		}
+	}
+}
+
+func TestPrivateKeyJWTRequest(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	auth := ClientAuth{
+		ClientID:     "app",
+		ClientSecret: "ignored",
+		Basic:        true,
+		Assertion:    &AssertionConfig{ClientID: "app", Audience: "aud", Key: key},
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		req, err := NewFormRequest(context.Background(), "https://idp.example/token", auth, url.Values{"grant_type": {"client_credentials"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, ok := req.BasicAuth(); ok {
+			t.Error("Basic credentials sent with an assertion")
+		}
+		if err := req.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if req.PostForm.Get("client_secret") != "" || req.PostForm.Get("client_assertion_type") != ClientAssertionType {
+			t.Errorf("form = %v", req.PostForm)
+		}
+		_, claims := verifyJWT(t, req.PostForm.Get("client_assertion"), &key.PublicKey)
+		jti := claims["jti"].(string)
+		if seen[jti] {
+			t.Error("assertion jti reused")
+		}
+		seen[jti] = true
+	}
+}
@@ -0,0 +1,78 @@
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Endpoint holds a provider's authorization and token endpoints.
+type Endpoint struct {
+	AuthURL  string
+	TokenURL string
+}
+
+// Config is an authorization code grant client for one provider. It is
+// the base of the provider packages and of the OpenID Connect client.
+type Config struct {
+	Endpoint Endpoint
+	// Auth authenticates the client at the token endpoint; Auth.ClientID
+	// is also sent in the authorization request.
+	Auth        ClientAuth
+	RedirectURL string
+	Scopes      []string
+	// ScopeSeparator joins Scopes. Defaults to a space; some providers,
+	// such as Facebook, expect a comma.
+	ScopeSeparator string
+	// HTTPClient defaults to DefaultHTTPClient.
+	HTTPClient *http.Client
+}
+
+// AuthCodeURL builds the authorization URL. pkce may be nil; extra adds or
+// overrides query parameters such as prompt or login_hint.
+func (c *Config) AuthCodeURL(state string, pkce *PKCE, extra url.Values) string {
+	sep := c.ScopeSeparator
+	if sep == "" {
+		sep = " "
+	}
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {c.Auth.ClientID},
+		"state":         {state},
+	}
+	if len(c.Scopes) > 0 {
+		q.Set("scope", strings.Join(c.Scopes, sep))
+	}
+	if c.RedirectURL != "" {
+		q.Set("redirect_uri", c.RedirectURL)
+	}
+	if pkce != nil {
+		q.Set("code_challenge", pkce.Challenge)
+		q.Set("code_challenge_method", pkce.Method)
+	}
+	for k, vs := range extra {
+		q[k] = vs
+	}
+
+	if strings.Contains(c.Endpoint.AuthURL, "?") {
+		return c.Endpoint.AuthURL + "&" + q.Encode()
+	}
+	return c.Endpoint.AuthURL + "?" + q.Encode()
+}
+
+// Exchange trades an authorization code for tokens. verifier is the PKCE
+// code verifier, or empty when PKCE was not used.
+func (c *Config) Exchange(ctx context.Context, code, verifier string) (*TokenResponse, error) {
+	form := url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {code},
+	}
+	if c.RedirectURL != "" {
+		form.Set("redirect_uri", c.RedirectURL)
+	}
+	if verifier != "" {
+		form.Set("code_verifier", verifier)
+	}
+	return PostToken(ctx, c.HTTPClient, c.Endpoint.TokenURL, c.Auth, form)
+}
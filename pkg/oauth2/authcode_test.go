@@ -0,0 +1,106 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAuthCodeURL(t *testing.T) {
+	pkce := &PKCE{Verifier: "v", Challenge: "c", Method: "S256"}
+	tests := []struct {
+		name  string
+		cfg   Config
+		pkce  *PKCE
+		extra url.Values
+		want  url.Values
+	}{
+		{
+			"defaults",
+			Config{Endpoint: Endpoint{AuthURL: "https://idp.example/authorize"}, Auth: ClientAuth{ClientID: "app"}, Scopes: []string{"a", "b"}, RedirectURL: "https://app.example/cb"},
+			pkce, nil,
+			url.Values{"response_type": {"code"}, "client_id": {"app"}, "state": {"st"}, "scope": {"a b"}, "redirect_uri": {"https://app.example/cb"}, "code_challenge": {"c"}, "code_challenge_method": {"S256"}},
+		},
+		{
+			"comma scopes, extra and existing query",
+			Config{Endpoint: Endpoint{AuthURL: "https://idp.example/authorize?tenant=x"}, Auth: ClientAuth{ClientID: "app"}, Scopes: []string{"a", "b"}, ScopeSeparator: ","},
+			nil, url.Values{"prompt": {"consent"}},
+			url.Values{"tenant": {"x"}, "response_type": {"code"}, "client_id": {"app"}, "state": {"st"}, "scope": {"a,b"}, "prompt": {"consent"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.cfg.AuthCodeURL("st", tt.pkce, tt.extra))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.Query(); got.Encode() != tt.want.Encode() {
+				t.Errorf("query = %s, want %s", got.Encode(), tt.want.Encode())
+			}
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		want := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {"abc"},
+			"redirect_uri":  {"https://app.example/cb"},
+			"code_verifier": {"verifier"},
+			"client_id":     {"app"},
+			"client_secret": {"secret"},
+		}
+		if r.PostForm.Encode() != want.Encode() {
+			t.Errorf("form = %s", r.PostForm.Encode())
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "at", "token_type": "bearer"})
+	}))
+	defer srv.Close()
+
+	cfg := Config{Endpoint: Endpoint{TokenURL: srv.URL}, Auth: ClientAuth{ClientID: "app", ClientSecret: "secret"}, RedirectURL: "https://app.example/cb"}
+	token, err := cfg.Exchange(context.Background(), "abc", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "at" {
+		t.Errorf("access token = %q", token.AccessToken)
+	}
+}
+
+func TestPostTokenErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode string
+	}{
+		{"error status", http.StatusBadRequest, `{"error":"invalid_grant","error_description":"expired"}`, "invalid_grant"},
+		{"error with 200", http.StatusOK, `{"error":"bad_verification_code","error_description":"The code is incorrect"}`, "bad_verification_code"},
+		{"no access token", http.StatusOK, `{"token_type":"bearer"}`, ""},
+		{"not JSON", http.StatusOK, `access_token=at`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			_, err := PostToken(context.Background(), nil, srv.URL, ClientAuth{ClientID: "app"}, url.Values{})
+			if err == nil {
+				t.Fatal("PostToken succeeded")
+			}
+			var oauthErr *OAuthError
+			if tt.wantCode != "" && (!errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode) {
+				t.Errorf("error = %v, want OAuthError %s", err, tt.wantCode)
+			}
+		})
+	}
+}
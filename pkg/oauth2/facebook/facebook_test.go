@@ -0,0 +1,90 @@
+package facebook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+func TestAuthCodeURL(t *testing.T) {
+	c, err := New(Config{ClientID: "app", ClientSecret: "secret", RedirectURL: "https://app.example/cb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(c.AuthCodeURL("st", nil, url.Values{"auth_type": {"rerequest"}}))
+	if got := u.Host + u.Path; got != "www.facebook.com/"+DefaultVersion+"/dialog/oauth" {
+		t.Errorf("auth URL = %s", got)
+	}
+	q := u.Query()
+	if q.Get("scope") != "public_profile,email" || q.Get("auth_type") != "rerequest" {
+		t.Errorf("query = %s", u.RawQuery)
+	}
+}
+
+func TestExchangeAndUserInfo(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("at"))
+	wantProof := hex.EncodeToString(mac.Sum(nil))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/access_token":
+			_ = r.ParseForm()
+			if r.PostForm.Get("client_secret") != "secret" || r.PostForm.Get("code") != "abc" {
+				t.Errorf("form = %s", r.PostForm.Encode())
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "at", "token_type": "bearer", "expires_in": 5183944})
+		case "/me":
+			if r.URL.Query().Get("appsecret_proof") != wantProof {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"message":"Invalid appsecret_proof","type":"GraphMethodException","code":100}}`))
+				return
+			}
+			if !strings.Contains(r.URL.Query().Get("fields"), "email") {
+				t.Errorf("fields = %q", r.URL.Query().Get("fields"))
+			}
+			_, _ = w.Write([]byte(`{"id":"123","name":"Ada Lovelace","first_name":"Ada","last_name":"Lovelace","email":"ada@example.com","picture":{"data":{"url":"https://cdn.example/ada.jpg"}}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{
+		ClientID:     "app",
+		ClientSecret: "secret",
+		Endpoint:     oauth2.Endpoint{AuthURL: srv.URL + "/dialog/oauth", TokenURL: srv.URL + "/oauth/access_token"},
+		GraphURL:     srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := c.ExchangeCode(context.Background(), "abc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.GetUserInfo(context.Background(), token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := UserInfo{ID: "123", Name: "Ada Lovelace", FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", PictureURL: "https://cdn.example/ada.jpg"}
+	if *info != want {
+		t.Errorf("info = %+v", info)
+	}
+	if _, err := c.GetUserInfo(context.Background(), "other"); err == nil {
+		t.Error("GetUserInfo succeeded with a mismatched proof")
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New(Config{ClientID: "app"}); err == nil {
+		t.Error("missing secret accepted")
+	}
+}
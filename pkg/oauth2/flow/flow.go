@@ -0,0 +1,315 @@
+// Package flow provides ready-made Gin handlers for the authorization code
+// login flow: redirecting to a provider and handling its callback.
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2/oidc"
+	"github.com/PhilipKram/gms-foundation/pkg/sessions"
+)
+
+// sessionKey holds the pending login in the session.
+const sessionKey = "oauth2.flow"
+
+// DefaultStateTTL bounds how long a user may take to complete a login.
+const DefaultStateTTL = 10 * time.Minute
+
+// maxCallbackSize caps the form body of a form_post callback.
+const maxCallbackSize = 64 << 10
+
+// Provider is an identity provider usable by the flow; *oidc.Client
+// implements it.
+type Provider interface {
+	AuthCodeURL(state string, pkce *oauth2.PKCE, extra url.Values) string
+	Exchange(ctx context.Context, code, verifier string) (*oauth2.TokenResponse, error)
+	VerifyIDToken(ctx context.Context, raw string) (*oidc.IDToken, error)
+	VerifyIDTokenNonce(ctx context.Context, raw, nonce string) (*oidc.IDToken, error)
+}
+
+// Login is the outcome of a successful callback.
+type Login struct {
+	Provider string
+	Token    *oauth2.TokenResponse
+	IDToken  *oidc.IDToken
+	// ReturnTo is the local path passed as return_to to the login handler,
+	// or empty.
+	ReturnTo string
+	// Params holds every parameter the provider sent to the callback, for
+	// provider-specific members such as the user object Apple posts on
+	// the first sign-in.
+	Params url.Values
+}
+
+// Config configures a Flow.
+type Config struct {
+	// Providers maps the :provider path segment to a provider.
+	Providers map[string]Provider
+	// OnLogin is called after the ID token is verified and, when
+	// sessions.Middleware is installed, the session ID rotated. It must
+	// write the response, typically by establishing the user in the
+	// session and redirecting to login.ReturnTo.
+	OnLogin func(c *gin.Context, login *Login)
+	// OnError writes the response for a failed login. By default a JSON
+	// error with the given status is returned; details of 5xx errors are
+	// not exposed.
+	OnError func(c *gin.Context, status int, err error)
+	// AuthParams adds query parameters such as prompt to every
+	// authorization URL.
+	AuthParams url.Values
+	// DisableNonce omits the nonce parameter and its ID token check, for
+	// providers that do not echo it back.
+	DisableNonce bool
+	// FormPost requests response_mode=form_post, so that the provider
+	// POSTs the callback parameters instead of appending them to the
+	// redirect, as Apple requires when name or email are requested.
+	// Browsers send only SameSite=None cookies with that cross-site POST,
+	// so the state must be kept in a CookieStateStore, or a session
+	// cookie, with SameSite set to http.SameSiteNoneMode and Secure.
+	FormPost bool
+	// StateStore keeps the pending login between redirect and callback.
+	// Defaults to SessionStateStore, which requires sessions.Middleware.
+	StateStore StateStore
+	// StateTTL defaults to DefaultStateTTL.
+	StateTTL time.Duration
+}
+
+// Flow serves the login and callback endpoints. The state, PKCE verifier
+// and nonce are kept in the configured StateStore.
+type Flow struct {
+	cfg Config
+}
+
+// pendingLogin is kept in the StateStore between login and callback.
+type pendingLogin struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce,omitempty"`
+	ReturnTo string `json:"return_to,omitempty"`
+}
+
+// New returns a Flow for cfg.
+func New(cfg Config) (*Flow, error) {
+	if len(cfg.Providers) == 0 {
+		return nil, errors.New("flow: at least one provider is required")
+	}
+	if cfg.OnLogin == nil {
+		return nil, errors.New("flow: OnLogin is required")
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = defaultOnError
+	}
+	if cfg.StateStore == nil {
+		cfg.StateStore = SessionStateStore{}
+	}
+	if cfg.StateTTL <= 0 {
+		cfg.StateTTL = DefaultStateTTL
+	}
+	return &Flow{cfg: cfg}, nil
+}
+
+// Register mounts GET /login/:provider and GET /callback/:provider, and
+// POST /callback/:provider when FormPost is set.
+func (f *Flow) Register(r gin.IRoutes) {
+	r.GET("/login/:provider", f.Login)
+	r.GET("/callback/:provider", f.Callback)
+	if f.cfg.FormPost {
+		r.POST("/callback/:provider", f.Callback)
+	}
+}
+
+// Login redirects to the provider's authorization endpoint. An optional
+// return_to query parameter, restricted to local paths, is handed back in
+// Login.ReturnTo.
+func (f *Flow) Login(c *gin.Context) {
+	name := c.Param("provider")
+	provider, ok := f.cfg.Providers[name]
+	if !ok {
+		f.cfg.OnError(c, http.StatusNotFound, fmt.Errorf("unknown provider %q", name))
+		return
+	}
+
+	state, err := oauth2.GenerateState()
+	if err != nil {
+		f.cfg.OnError(c, http.StatusInternalServerError, err)
+		return
+	}
+	pkce, err := oauth2.GeneratePKCE()
+	if err != nil {
+		f.cfg.OnError(c, http.StatusInternalServerError, err)
+		return
+	}
+	var nonce string
+	if !f.cfg.DisableNonce {
+		if nonce, err = oauth2.GenerateNonce(); err != nil {
+			f.cfg.OnError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	pending, err := json.Marshal(pendingLogin{
+		Provider: name,
+		Verifier: pkce.Verifier,
+		Nonce:    nonce,
+		ReturnTo: localPath(c.Query("return_to")),
+	})
+	if err != nil {
+		f.cfg.OnError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if err := f.cfg.StateStore.Put(c, state, pending, f.cfg.StateTTL); err != nil {
+		f.cfg.OnError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	params := url.Values{}
+	if nonce != "" {
+		params.Set("nonce", nonce)
+	}
+	if f.cfg.FormPost {
+		params.Set("response_mode", "form_post")
+	}
+	for k, vs := range f.cfg.AuthParams {
+		params[k] = vs
+	}
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state, &pkce, params))
+}
+
+// Callback validates the state, exchanges the code, verifies the ID token
+// and hands the result to OnLogin. The parameters are read from the query
+// string, or from the form body of a POST when FormPost is set.
+func (f *Flow) Callback(c *gin.Context) {
+	name := c.Param("provider")
+	provider, ok := f.cfg.Providers[name]
+	if !ok {
+		f.cfg.OnError(c, http.StatusNotFound, fmt.Errorf("unknown provider %q", name))
+		return
+	}
+	params, err := f.callbackParams(c)
+	if err != nil {
+		f.cfg.OnError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// The pending login is single-use, whatever the outcome.
+	raw, err := f.cfg.StateStore.Consume(c, params.Get("state"))
+	if errors.Is(err, ErrStateNotFound) {
+		f.cfg.OnError(c, http.StatusBadRequest, errors.New("invalid or expired state"))
+		return
+	}
+	if err != nil {
+		f.cfg.OnError(c, http.StatusInternalServerError, err)
+		return
+	}
+	var pending pendingLogin
+	if err := json.Unmarshal(raw, &pending); err != nil || pending.Provider != name {
+		f.cfg.OnError(c, http.StatusBadRequest, errors.New("invalid state"))
+		return
+	}
+	if code := params.Get("error"); code != "" {
+		f.cfg.OnError(c, http.StatusBadRequest, fmt.Errorf("provider returned %s: %s", code, params.Get("error_description")))
+		return
+	}
+	code := params.Get("code")
+	if code == "" {
+		f.cfg.OnError(c, http.StatusBadRequest, errors.New("missing authorization code"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := provider.Exchange(ctx, code, pending.Verifier)
+	if err != nil {
+		log.Warn().Err(err).Str("provider", name).Msg("Authorization code exchange failed")
+		// A used or expired code is the user's problem, anything else the
+		// provider's.
+		status := http.StatusBadGateway
+		var oauthErr *oauth2.OAuthError
+		if errors.As(err, &oauthErr) && oauthErr.Code == "invalid_grant" {
+			status = http.StatusBadRequest
+		}
+		f.cfg.OnError(c, status, err)
+		return
+	}
+	if token.IDToken == "" {
+		f.cfg.OnError(c, http.StatusBadGateway, errors.New("token response has no id_token"))
+		return
+	}
+	var idToken *oidc.IDToken
+	if pending.Nonce != "" {
+		idToken, err = provider.VerifyIDTokenNonce(ctx, token.IDToken, pending.Nonce)
+	} else {
+		idToken, err = provider.VerifyIDToken(ctx, token.IDToken)
+	}
+	if err != nil {
+		f.cfg.OnError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	if sess := sessions.Get(c); sess != nil {
+		sess.Rotate()
+	}
+	f.cfg.OnLogin(c, &Login{
+		Provider: name,
+		Token:    token,
+		IDToken:  idToken,
+		ReturnTo: pending.ReturnTo,
+		Params:   params,
+	})
+}
+
+// callbackParams returns the parameters of the callback: the form body of
+// a form_post callback, never mixed with the query string, or the query
+// string otherwise.
+func (f *Flow) callbackParams(c *gin.Context) (url.Values, error) {
+	if c.Request.Method != http.MethodPost {
+		return c.Request.URL.Query(), nil
+	}
+	if !f.cfg.FormPost {
+		return nil, errors.New("form_post callbacks are not enabled")
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCallbackSize)
+	if err := c.Request.ParseForm(); err != nil {
+		return nil, fmt.Errorf("invalid callback form: %w", err)
+	}
+	return c.Request.PostForm, nil
+}
+
+func defaultOnError(c *gin.Context, status int, err error) {
+	msg := err.Error()
+	if status >= http.StatusInternalServerError {
+		msg = http.StatusText(status)
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": msg})
+}
+
+// localPath returns p when it is a path on this host, guarding against open
+// redirects, and an empty string otherwise. Browsers strip tabs and
+// newlines and treat backslashes as slashes, so any of those, or other
+// control characters and whitespace, reject the path.
+func localPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return ""
+	}
+	for _, r := range p {
+		if r <= ' ' || r == 0x7f || r == '\\' || unicode.IsSpace(r) {
+			return ""
+		}
+	}
+	u, err := url.Parse(p)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return ""
+	}
+	return p
+}
@@ -0,0 +1,320 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2/oidc"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeProvider accepts the code "good" and the ID token it issues for it.
+// The code "down" fails as if the provider were unreachable.
+type fakeProvider struct {
+	nonce    string
+	verifier string
+}
+
+func (p *fakeProvider) AuthCodeURL(state string, pkce *oauth2.PKCE, extra url.Values) string {
+	q := url.Values{"state": {state}, "code_challenge": {pkce.Challenge}}
+	for k, vs := range extra {
+		q[k] = vs
+	}
+	p.nonce = extra.Get("nonce")
+	return "https://idp.example/authorize?" + q.Encode()
+}
+
+func (p *fakeProvider) Exchange(_ context.Context, code, verifier string) (*oauth2.TokenResponse, error) {
+	p.verifier = verifier
+	if code == "down" {
+		return nil, errors.New("connection refused")
+	}
+	if code != "good" {
+		return nil, &oauth2.OAuthError{StatusCode: http.StatusBadRequest, Code: "invalid_grant"}
+	}
+	return &oauth2.TokenResponse{AccessToken: "at", IDToken: "id-token"}, nil
+}
+
+func (p *fakeProvider) VerifyIDToken(ctx context.Context, raw string) (*oidc.IDToken, error) {
+	return p.VerifyIDTokenNonce(ctx, raw, p.nonce)
+}
+
+func (p *fakeProvider) VerifyIDTokenNonce(_ context.Context, raw, nonce string) (*oidc.IDToken, error) {
+	if raw != "id-token" || nonce != p.nonce {
+		return nil, errors.New("invalid ID token")
+	}
+	return &oidc.IDToken{Subject: "user-1", Nonce: nonce}, nil
+}
+
+type harness struct {
+	router   *gin.Engine
+	provider *fakeProvider
+	login    *Login
+}
+
+func newHarness(t *testing.T, mutate func(*Config)) *harness {
+	t.Helper()
+	store, err := NewCookieStateStore([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &harness{router: gin.New(), provider: &fakeProvider{}}
+	cfg := Config{
+		Providers:  map[string]Provider{"idp": h.provider},
+		StateStore: store,
+		OnLogin: func(c *gin.Context, login *Login) {
+			h.login = login
+			c.Status(http.StatusNoContent)
+		},
+	}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	f, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Register(h.router)
+	return h
+}
+
+func (h *harness) do(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.router.ServeHTTP(w, req)
+	return w
+}
+
+// start runs the login handler and returns the authorization URL and the
+// state cookie.
+func (h *harness) start(t *testing.T, target string) (*url.URL, *http.Cookie) {
+	t.Helper()
+	w := h.do(httptest.NewRequest(http.MethodGet, target, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d", w.Code)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("login set %d cookies", len(cookies))
+	}
+	return location, cookies[0]
+}
+
+func TestFormPostCallback(t *testing.T) {
+	h := newHarness(t, func(cfg *Config) { cfg.FormPost = true })
+	location, cookie := h.start(t, "/login/idp")
+	if got := location.Query().Get("response_mode"); got != "form_post" {
+		t.Fatalf("response_mode = %q", got)
+	}
+
+	user := `{"name":{"firstName":"Ada","lastName":"Lovelace"},"email":"ada@privaterelay.appleid.com"}`
+	form := url.Values{"state": {location.Query().Get("state")}, "code": {"good"}, "user": {user}}
+	req := httptest.NewRequest(http.MethodPost, "/callback/idp", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	if w := h.do(req); w.Code != http.StatusNoContent {
+		t.Fatalf("callback status = %d: %s", w.Code, w.Body.String())
+	}
+	if h.login == nil || h.login.IDToken.Subject != "user-1" {
+		t.Fatalf("login = %+v", h.login)
+	}
+	if got := h.login.Params.Get("user"); got != user {
+		t.Errorf("user param = %q", got)
+	}
+}
+
+func TestFormPostCallbackRejections(t *testing.T) {
+	tests := []struct {
+		name     string
+		formPost bool
+		query    string
+		form     func(state string) url.Values
+		want     int
+	}{
+		{"not enabled", false, "", func(state string) url.Values {
+			return url.Values{"state": {state}, "code": {"good"}}
+		}, http.StatusNotFound},
+		{"state only in query", true, "?state=", func(state string) url.Values {
+			return url.Values{"code": {"good"}}
+		}, http.StatusBadRequest},
+		{"wrong state", true, "", func(string) url.Values {
+			return url.Values{"state": {"forged"}, "code": {"good"}}
+		}, http.StatusBadRequest},
+		{"oversized body", true, "", func(state string) url.Values {
+			return url.Values{"state": {state}, "code": {"good"}, "user": {strings.Repeat("x", maxCallbackSize)}}
+		}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, func(cfg *Config) { cfg.FormPost = tt.formPost })
+			location, cookie := h.start(t, "/login/idp")
+			state := location.Query().Get("state")
+			query := tt.query
+			if query != "" {
+				query += url.QueryEscape(state)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/callback/idp"+query, strings.NewReader(tt.form(state).Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(cookie)
+			if w := h.do(req); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if h.login != nil {
+				t.Error("OnLogin was called")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	onLogin := func(*gin.Context, *Login) {}
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"no providers", Config{OnLogin: onLogin}},
+		{"no OnLogin", Config{Providers: map[string]Provider{"idp": &fakeProvider{}}}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.cfg); err == nil {
+			t.Errorf("%s: New succeeded", tt.name)
+		}
+	}
+}
+
+func TestCallback(t *testing.T) {
+	h := newHarness(t, nil)
+	location, cookie := h.start(t, "/login/idp?return_to=%2Faccount%3Ftab%3D1")
+	q := location.Query()
+	if q.Get("nonce") == "" || q.Get("code_challenge") == "" || q.Get("response_mode") != "" {
+		t.Fatalf("authorization URL = %s", location)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/callback/idp?code=good&state="+url.QueryEscape(q.Get("state")), nil)
+	req.AddCookie(cookie)
+	if w := h.do(req); w.Code != http.StatusNoContent {
+		t.Fatalf("callback status = %d: %s", w.Code, w.Body.String())
+	}
+	if h.login == nil || h.login.Provider != "idp" || h.login.ReturnTo != "/account?tab=1" || h.login.Token.AccessToken != "at" {
+		t.Fatalf("login = %+v", h.login)
+	}
+	if h.provider.verifier == "" {
+		t.Error("PKCE verifier not sent")
+	}
+}
+
+func TestCallbackRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		query  func(state string) string
+		want   int
+	}{
+		{"unknown provider on login", "/login/other", nil, http.StatusNotFound},
+		{"unknown provider", "/callback/other", func(s string) string { return "code=good&state=" + s }, http.StatusNotFound},
+		{"state of another provider", "/callback/alt", func(s string) string { return "code=good&state=" + s }, http.StatusBadRequest},
+		{"missing state", "/callback/idp", func(string) string { return "code=good" }, http.StatusBadRequest},
+		{"forged state", "/callback/idp", func(string) string { return "code=good&state=forged" }, http.StatusBadRequest},
+		{"provider error", "/callback/idp", func(s string) string { return "error=access_denied&state=" + s }, http.StatusBadRequest},
+		{"missing code", "/callback/idp", func(s string) string { return "state=" + s }, http.StatusBadRequest},
+		{"invalid_grant", "/callback/idp", func(s string) string { return "code=used&state=" + s }, http.StatusBadRequest},
+		{"provider unreachable", "/callback/idp", func(s string) string { return "code=down&state=" + s }, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, func(cfg *Config) { cfg.Providers["alt"] = &fakeProvider{} })
+			location, cookie := h.start(t, "/login/idp")
+			target := tt.target
+			if tt.query != nil {
+				target += "?" + tt.query(url.QueryEscape(location.Query().Get("state")))
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.AddCookie(cookie)
+			w := h.do(req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if w.Code >= 500 && strings.Contains(w.Body.String(), "connection refused") {
+				t.Errorf("5xx error details exposed: %s", w.Body.String())
+			}
+			if h.login != nil {
+				t.Error("OnLogin was called")
+			}
+		})
+	}
+}
+
+func TestCallbackConsumesState(t *testing.T) {
+	h := newHarness(t, nil)
+	location, cookie := h.start(t, "/login/idp")
+	target := "/callback/idp?code=good&state=" + url.QueryEscape(location.Query().Get("state"))
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.AddCookie(cookie)
+	w := h.do(req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("first callback status = %d", w.Code)
+	}
+	cleared := w.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].Name != cookie.Name || cleared[0].MaxAge >= 0 {
+		t.Fatalf("state cookie not cleared: %v", cleared)
+	}
+	h.login = nil
+
+	if w := h.do(httptest.NewRequest(http.MethodGet, target, nil)); w.Code != http.StatusBadRequest || h.login != nil {
+		t.Errorf("repeated callback status = %d, login = %v", w.Code, h.login)
+	}
+}
+
+func TestCallbackNonceMismatch(t *testing.T) {
+	h := newHarness(t, nil)
+	location, cookie := h.start(t, "/login/idp")
+	h.provider.nonce = "other"
+
+	req := httptest.NewRequest(http.MethodGet, "/callback/idp?code=good&state="+url.QueryEscape(location.Query().Get("state")), nil)
+	req.AddCookie(cookie)
+	if w := h.do(req); w.Code != http.StatusUnauthorized || h.login != nil {
+		t.Errorf("status = %d, login = %v", w.Code, h.login)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/account", "/account"},
+		{"/a/b?c=d#e", "/a/b?c=d#e"},
+		{"", ""},
+		{"account", ""},
+		{"//evil.example", ""},
+		{"https://evil.example/", ""},
+		{"/\\evil.example", ""},
+		{"\\evil.example", ""},
+		{"/\tevil", ""},
+		{"/\t/evil.example", ""},
+		{"/\n/evil.example", ""},
+		{"/ /evil.example", ""},
+		{"/\u00a0/evil.example", ""},
+		{"/\x7f", ""},
+	}
+	for _, tt := range tests {
+		if got := localPath(tt.in); got != tt.want {
+			t.Errorf("localPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package flow
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PhilipKram/gms-foundation/pkg/sessions"
+)
+
+// ErrStateNotFound is returned by StateStore.Consume for unknown, expired
+// or already consumed states.
+var ErrStateNotFound = errors.New("state not found")
+
+// StateStore keeps a pending login between the login redirect and the
+// callback. Values are one-time: Consume removes them.
+type StateStore interface {
+	// Put stores value under state for ttl.
+	Put(c *gin.Context, state string, value []byte, ttl time.Duration) error
+	// Consume returns and removes the value stored under state, or
+	// ErrStateNotFound.
+	Consume(c *gin.Context, state string) ([]byte, error)
+}
+
+// stateEntry is the stored form of a pending login.
+type stateEntry struct {
+	State   string    `json:"state"`
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// SessionStateStore keeps pending logins in the session loaded by
+// sessions.Middleware. Only the most recent login per session is kept.
+type SessionStateStore struct{}
+
+// Put implements StateStore.
+func (SessionStateStore) Put(c *gin.Context, state string, value []byte, ttl time.Duration) error {
+	sess := sessions.Get(c)
+	if sess == nil {
+		return errors.New("session middleware is not installed")
+	}
+	raw, err := json.Marshal(stateEntry{State: state, Value: value, Expires: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	sess.Set(sessionKey, string(raw))
+	return nil
+}
+
+// Consume implements StateStore.
+func (SessionStateStore) Consume(c *gin.Context, state string) ([]byte, error) {
+	sess := sessions.Get(c)
+	if sess == nil {
+		return nil, errors.New("session middleware is not installed")
+	}
+	raw := sess.GetString(sessionKey)
+	if raw == "" {
+		return nil, ErrStateNotFound
+	}
+	sess.Delete(sessionKey)
+	return openEntry([]byte(raw), state)
+}
+
+// DefaultStateCookie is the cookie used by CookieStateStore.
+const DefaultStateCookie = "oauth2_state"
+
+// CookieStateStore keeps pending logins client-side in a short-lived
+// cookie encrypted with AES-GCM, for stateless deployments. Only the most
+// recent login per browser is kept.
+type CookieStateStore struct {
+	// CookieName defaults to DefaultStateCookie.
+	CookieName string
+	// CookiePath should cover the callback route. Defaults to "/".
+	CookiePath string
+	Secure     bool
+	// SameSite defaults to http.SameSiteLaxMode, which lets the cookie
+	// through on the provider's top-level redirect. Providers that POST
+	// the callback need http.SameSiteNoneMode together with Secure.
+	SameSite http.SameSite
+
+	aeads []cipher.AEAD
+}
+
+// NewCookieStateStore returns a CookieStateStore. Each key must be 16, 24
+// or 32 bytes. The first key encrypts; all keys decrypt, which allows key
+// rotation.
+func NewCookieStateStore(keys ...[]byte) (*CookieStateStore, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+	store := &CookieStateStore{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state key: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		store.aeads = append(store.aeads, aead)
+	}
+	return store, nil
+}
+
+// Put implements StateStore.
+func (s *CookieStateStore) Put(c *gin.Context, state string, value []byte, ttl time.Duration) error {
+	plain, err := json.Marshal(stateEntry{State: state, Value: value, Expires: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	aead := s.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	cookie := s.cookie()
+	cookie.Value = base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil))
+	cookie.MaxAge = int(ttl / time.Second)
+	http.SetCookie(c.Writer, cookie)
+	return nil
+}
+
+// Consume implements StateStore.
+func (s *CookieStateStore) Consume(c *gin.Context, state string) ([]byte, error) {
+	cookie := s.cookie()
+	value, err := c.Cookie(cookie.Name)
+	if err != nil || value == "" {
+		return nil, ErrStateNotFound
+	}
+	cookie.MaxAge = -1
+	http.SetCookie(c.Writer, cookie)
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrStateNotFound
+	}
+	for _, aead := range s.aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+		nonce, ciphertext := raw[:aead.NonceSize()], raw[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			continue
+		}
+		return openEntry(plain, state)
+	}
+	return nil, ErrStateNotFound
+}
+
+func (s *CookieStateStore) cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     s.CookieName,
+		Path:     s.CookiePath,
+		Secure:   s.Secure,
+		HttpOnly: true,
+		SameSite: s.SameSite,
+	}
+	if cookie.Name == "" {
+		cookie.Name = DefaultStateCookie
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	return cookie
+}
+
+// openEntry decodes a stored entry and checks it belongs to state and has
+// not expired.
+func openEntry(raw []byte, state string) ([]byte, error) {
+	var entry stateEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, ErrStateNotFound
+	}
+	if subtle.ConstantTimeCompare([]byte(entry.State), []byte(state)) != 1 || time.Now().After(entry.Expires) {
+		return nil, ErrStateNotFound
+	}
+	return entry.Value, nil
+}
@@ -0,0 +1,185 @@
+package flow
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/PhilipKram/gms-foundation/pkg/sessions"
+)
+
+var (
+	stateKey1 = []byte("0123456789abcdef0123456789abcdef")
+	stateKey2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+// putCookie stores value under state with s and returns the cookie set.
+func putCookie(t *testing.T, s *CookieStateStore, state string, ttl time.Duration) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/login", nil)
+	if err := s.Put(c, state, []byte("pending"), ttl); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Put set %d cookies", len(cookies))
+	}
+	return cookies[0]
+}
+
+// consumeCookie presents cookie to s and returns the stored value and the
+// cookie that Consume set.
+func consumeCookie(s *CookieStateStore, cookie *http.Cookie, state string) ([]byte, *http.Cookie, error) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/callback", nil)
+	if cookie != nil {
+		c.Request.AddCookie(cookie)
+	}
+	value, err := s.Consume(c, state)
+	var cleared *http.Cookie
+	if cookies := w.Result().Cookies(); len(cookies) > 0 {
+		cleared = cookies[0]
+	}
+	return value, cleared, err
+}
+
+func TestCookieStateStore(t *testing.T) {
+	store, err := NewCookieStateStore(stateKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewCookieStateStore(stateKey2)
+	good := putCookie(t, store, "s1", time.Minute)
+	expired := putCookie(t, store, "s1", -time.Second)
+	foreign := putCookie(t, other, "s1", time.Minute)
+
+	raw, _ := base64.RawURLEncoding.DecodeString(good.Value)
+	raw[len(raw)-1] ^= 1
+	tampered := *good
+	tampered.Value = base64.RawURLEncoding.EncodeToString(raw)
+	garbage := *good
+	garbage.Value = "!!"
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+		ok     bool
+	}{
+		{"valid", good, "s1", true},
+		{"state mismatch", good, "s2", false},
+		{"empty state", good, "", false},
+		{"tampered cookie", &tampered, "s1", false},
+		{"not base64", &garbage, "s1", false},
+		{"other key", foreign, "s1", false},
+		{"expired", expired, "s1", false},
+		{"no cookie", nil, "s1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, cleared, err := consumeCookie(store, tt.cookie, tt.state)
+			if !tt.ok {
+				if !errors.Is(err, ErrStateNotFound) {
+					t.Errorf("err = %v, want ErrStateNotFound", err)
+				}
+			} else if err != nil || string(value) != "pending" {
+				t.Fatalf("Consume = %q, %v", value, err)
+			}
+			if tt.cookie != nil && (cleared == nil || cleared.MaxAge >= 0) {
+				t.Errorf("state cookie not cleared: %v", cleared)
+			}
+		})
+	}
+}
+
+func TestCookieStateStoreKeyRotation(t *testing.T) {
+	old, _ := NewCookieStateStore(stateKey1)
+	cookie := putCookie(t, old, "s1", time.Minute)
+
+	rotated, err := NewCookieStateStore(stateKey2, stateKey1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := consumeCookie(rotated, cookie, "s1"); err != nil {
+		t.Errorf("cookie from the previous key rejected: %v", err)
+	}
+	if _, err := NewCookieStateStore([]byte("short")); err == nil {
+		t.Error("invalid key accepted")
+	}
+	if _, err := NewCookieStateStore(); err == nil {
+		t.Error("no keys accepted")
+	}
+}
+
+func TestCookieStateStoreAttributes(t *testing.T) {
+	store, _ := NewCookieStateStore(stateKey1)
+	store.Secure = true
+	store.SameSite = http.SameSiteNoneMode
+	store.CookiePath = "/auth"
+	cookie := putCookie(t, store, "s1", time.Minute)
+	if cookie.Name != DefaultStateCookie || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteNoneMode || cookie.Path != "/auth" || cookie.MaxAge != 60 {
+		t.Errorf("cookie = %+v", cookie)
+	}
+}
+
+func TestSessionStateStore(t *testing.T) {
+	sessStore := sessions.NewServerStore(sessions.NewMemoryBackend())
+	var got []byte
+	var gotErr error
+	router := gin.New()
+	router.Use(sessions.Middleware(sessStore, sessions.Config{}))
+	router.GET("/put", func(c *gin.Context) {
+		if err := (SessionStateStore{}).Put(c, c.Query("state"), []byte("pending"), time.Minute); err != nil {
+			t.Error(err)
+		}
+	})
+	router.GET("/consume", func(c *gin.Context) {
+		got, gotErr = SessionStateStore{}.Consume(c, c.Query("state"))
+	})
+	do := func(target string, cookie *http.Cookie) *http.Cookie {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if cookies := w.Result().Cookies(); len(cookies) > 0 {
+			return cookies[0]
+		}
+		return cookie
+	}
+
+	cookie := do("/put?state=s1", nil)
+	cookie = do("/consume?state=s2", cookie)
+	if !errors.Is(gotErr, ErrStateNotFound) {
+		t.Fatalf("mismatched state: err = %v", gotErr)
+	}
+	// A failed attempt consumes the pending login too.
+	do("/consume?state=s1", cookie)
+	if !errors.Is(gotErr, ErrStateNotFound) {
+		t.Fatalf("state usable after a failed attempt: err = %v", gotErr)
+	}
+
+	cookie = do("/put?state=s1", cookie)
+	cookie = do("/consume?state=s1", cookie)
+	if gotErr != nil || string(got) != "pending" {
+		t.Fatalf("Consume = %q, %v", got, gotErr)
+	}
+	do("/consume?state=s1", cookie)
+	if !errors.Is(gotErr, ErrStateNotFound) {
+		t.Errorf("state consumed twice: err = %v", gotErr)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if err := (SessionStateStore{}).Put(c, "s", nil, time.Minute); err == nil {
+		t.Error("Put without sessions.Middleware succeeded")
+	}
+}
@@ -0,0 +1,167 @@
+// Package github implements "Sign in with GitHub" on top of the OAuth2
+// authorization code grant. GitHub does not issue ID tokens; the user is
+// identified through the REST API.
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+// Endpoint is github.com's OAuth2 endpoint.
+var Endpoint = oauth2.Endpoint{
+	AuthURL:  "https://github.com/login/oauth/authorize",
+	TokenURL: "https://github.com/login/oauth/access_token",
+}
+
+// DefaultAPIURL is the REST API of github.com.
+const DefaultAPIURL = "https://api.github.com"
+
+// DefaultScopes grant read access to the profile and email addresses.
+var DefaultScopes = []string{"read:user", "user:email"}
+
+// Config configures a Client.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes defaults to DefaultScopes.
+	Scopes []string
+	// Endpoint defaults to Endpoint; set it and APIURL for GitHub
+	// Enterprise Server.
+	Endpoint oauth2.Endpoint
+	// APIURL defaults to DefaultAPIURL.
+	APIURL string
+	// HTTPClient defaults to oauth2.DefaultHTTPClient.
+	HTTPClient *http.Client
+}
+
+// Client signs users in with GitHub.
+type Client struct {
+	code   *oauth2.Config
+	apiURL string
+	client *http.Client
+}
+
+// New returns a Client for cfg.
+func New(cfg Config) (*Client, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, errors.New("github: client ID and secret are required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	if cfg.Endpoint == (oauth2.Endpoint{}) {
+		cfg.Endpoint = Endpoint
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultAPIURL
+	}
+	return &Client{
+		code: &oauth2.Config{
+			Endpoint:    cfg.Endpoint,
+			Auth:        oauth2.ClientAuth{ClientID: cfg.ClientID, ClientSecret: cfg.ClientSecret},
+			RedirectURL: cfg.RedirectURL,
+			Scopes:      cfg.Scopes,
+			HTTPClient:  cfg.HTTPClient,
+		},
+		apiURL: strings.TrimSuffix(cfg.APIURL, "/"),
+		client: cfg.HTTPClient,
+	}, nil
+}
+
+// AuthCodeURL builds the authorization URL. pkce may be nil; extra adds
+// parameters such as login or allow_signup.
+func (c *Client) AuthCodeURL(state string, pkce *oauth2.PKCE, extra url.Values) string {
+	return c.code.AuthCodeURL(state, pkce, extra)
+}
+
+// ExchangeCode trades an authorization code for an access token. verifier
+// is the PKCE code verifier, or empty when PKCE was not used.
+func (c *Client) ExchangeCode(ctx context.Context, code, verifier string) (*oauth2.TokenResponse, error) {
+	return c.code.Exchange(ctx, code, verifier)
+}
+
+// UserInfo is the signed-in GitHub user.
+type UserInfo struct {
+	ID        int64
+	Login     string
+	Name      string
+	AvatarURL string
+	// ProfileURL is the user's page on GitHub.
+	ProfileURL string
+	// Email is the primary address. It is empty when the token lacks the
+	// user:email scope and the user keeps their address private.
+	Email         string
+	EmailVerified bool
+}
+
+type user struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+	HTMLURL   string `json:"html_url"`
+	Email     string `json:"email"`
+}
+
+type email struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// GetUserInfo fetches the profile and primary email address of the user
+// the access token belongs to.
+func (c *Client) GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	var u user
+	if err := c.get(ctx, accessToken, "/user", &u); err != nil {
+		return nil, err
+	}
+	if u.ID == 0 {
+		return nil, errors.New("github: user response has no id")
+	}
+	info := &UserInfo{
+		ID:         u.ID,
+		Login:      u.Login,
+		Name:       u.Name,
+		AvatarURL:  u.AvatarURL,
+		ProfileURL: u.HTMLURL,
+	}
+
+	// The public profile email carries no verification status, so the
+	// primary address is taken from the email list when it is readable.
+	var emails []email
+	err := c.get(ctx, accessToken, "/user/emails", &emails)
+	var oauthErr *oauth2.OAuthError
+	switch {
+	case err == nil:
+		for _, e := range emails {
+			if e.Primary {
+				info.Email, info.EmailVerified = e.Email, e.Verified
+			}
+		}
+	case errors.As(err, &oauthErr) && (oauthErr.StatusCode == http.StatusForbidden || oauthErr.StatusCode == http.StatusNotFound):
+		// The token lacks the user:email scope.
+		info.Email = u.Email
+	default:
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *Client) get(ctx context.Context, accessToken, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return oauth2.Do(c.client, req, out)
+}
@@ -0,0 +1,121 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := New(Config{
+		ClientID:     "app",
+		ClientSecret: "secret",
+		Endpoint:     oauth2.Endpoint{AuthURL: srv.URL + "/login/oauth/authorize", TokenURL: srv.URL + "/login/oauth/access_token"},
+		APIURL:       srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	c, err := New(Config{ClientID: "app", ClientSecret: "secret", RedirectURL: "https://app.example/cb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(c.AuthCodeURL("st", &oauth2.PKCE{Challenge: "ch", Method: "S256"}, nil))
+	if u.Host != "github.com" {
+		t.Errorf("host = %s", u.Host)
+	}
+	q := u.Query()
+	if q.Get("scope") != "read:user user:email" || q.Get("code_challenge") != "ch" || q.Get("state") != "st" {
+		t.Errorf("query = %s", u.RawQuery)
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") == "used" {
+			// GitHub reports grant errors with 200 OK.
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		if r.PostForm.Get("code_verifier") != "v" {
+			t.Errorf("code_verifier = %q", r.PostForm.Get("code_verifier"))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "gho_x", "token_type": "bearer", "scope": "read:user,user:email"})
+	})
+
+	token, err := c.ExchangeCode(context.Background(), "abc", "v")
+	if err != nil || token.AccessToken != "gho_x" {
+		t.Fatalf("ExchangeCode = %v, %v", token, err)
+	}
+	if _, err := c.ExchangeCode(context.Background(), "used", "v"); err == nil {
+		t.Error("error response accepted")
+	}
+}
+
+func TestGetUserInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		emailsStatus int
+		emails       []email
+		wantEmail    string
+		wantVerified bool
+	}{
+		{"primary verified", http.StatusOK, []email{{"old@example.com", false, true}, {"ada@example.com", true, true}}, "ada@example.com", true},
+		{"primary unverified", http.StatusOK, []email{{"ada@example.com", true, false}}, "ada@example.com", false},
+		{"no email scope", http.StatusForbidden, nil, "public@example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer gho_x" {
+					t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+				}
+				switch r.URL.Path {
+				case "/user":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": 42, "login": "ada", "name": "Ada", "email": "public@example.com"})
+				case "/user/emails":
+					w.WriteHeader(tt.emailsStatus)
+					_ = json.NewEncoder(w).Encode(tt.emails)
+				}
+			})
+			info, err := c.GetUserInfo(context.Background(), "gho_x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.ID != 42 || info.Login != "ada" {
+				t.Errorf("info = %+v", info)
+			}
+			if info.Email != tt.wantEmail || info.EmailVerified != tt.wantVerified {
+				t.Errorf("email = %q verified %v, want %q %v", info.Email, info.EmailVerified, tt.wantEmail, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestGetUserInfoRejectsBadToken(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	if _, err := c.GetUserInfo(context.Background(), "bad"); err == nil {
+		t.Error("GetUserInfo succeeded on 401")
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New(Config{ClientID: "app"}); err == nil {
+		t.Error("missing secret accepted")
+	}
+}
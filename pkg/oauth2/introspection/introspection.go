@@ -0,0 +1,132 @@
+// Package introspection validates opaque access tokens against an OAuth2
+// token introspection endpoint (RFC 7662).
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+// Config configures a Client.
+type Config struct {
+	Endpoint string
+	// Auth authenticates the resource server at the endpoint.
+	Auth oauth2.ClientAuth
+	// HTTPClient defaults to oauth2.DefaultHTTPClient.
+	HTTPClient *http.Client
+}
+
+// Client calls an introspection endpoint.
+type Client struct {
+	cfg Config
+}
+
+// NewClient returns a Client for cfg.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("introspection: endpoint is required")
+	}
+	return &Client{cfg: cfg}, nil
+}
+
+// Response is an introspection response. Only Active is guaranteed; the
+// other members are present when the server chooses to send them.
+type Response struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Expiry    int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	JTI       string   `json:"jti,omitempty"`
+	// Claims holds every member for server-specific extensions.
+	Claims map[string]interface{} `json:"-"`
+}
+
+// Scopes returns the space-separated scope as a slice.
+func (r *Response) Scopes() []string {
+	return strings.Fields(r.Scope)
+}
+
+// HasScope reports whether scope was granted.
+func (r *Response) HasScope(scope string) bool {
+	for _, s := range r.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpiresAt returns the token expiry, or the zero time when unknown.
+func (r *Response) ExpiresAt() time.Time {
+	if r.Expiry == 0 {
+		return time.Time{}
+	}
+	return time.Unix(r.Expiry, 0)
+}
+
+// Audience is the aud member, which may be a string or an array.
+type Audience []string
+
+// UnmarshalJSON accepts both forms of aud.
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multi []string
+	if err := json.Unmarshal(b, &multi); err != nil {
+		return err
+	}
+	*a = multi
+	return nil
+}
+
+// Introspect asks the server about token. An inactive token is not an
+// error; callers must check Response.Active.
+func (c *Client) Introspect(ctx context.Context, token string) (*Response, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := oauth2.NewFormRequest(ctx, c.cfg.Endpoint, c.cfg.Auth, form)
+	if err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := oauth2.Do(c.cfg.HTTPClient, req, &raw); err != nil {
+		return nil, err
+	}
+
+	var resp Response
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &resp.Claims); err != nil {
+		return nil, err
+	}
+	// Servers may omit exp and nbf, but a token outside a known validity
+	// window is inactive whatever the server says.
+	now := time.Now()
+	if resp.Active && resp.Expiry != 0 && now.After(resp.ExpiresAt()) {
+		resp.Active = false
+	}
+	if resp.Active && resp.NotBefore != 0 && now.Before(time.Unix(resp.NotBefore, 0)) {
+		resp.Active = false
+	}
+	return &resp, nil
+}
@@ -0,0 +1,93 @@
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+func TestIntrospect(t *testing.T) {
+	now := time.Now().Unix()
+	tests := []struct {
+		name       string
+		response   map[string]interface{}
+		wantActive bool
+	}{
+		{"active", map[string]interface{}{"active": true, "exp": now + 60, "nbf": now - 60}, true},
+		{"active without exp", map[string]interface{}{"active": true}, true},
+		{"inactive", map[string]interface{}{"active": false}, false},
+		{"expired", map[string]interface{}{"active": true, "exp": now - 60}, false},
+		{"not yet valid", map[string]interface{}{"active": true, "exp": now + 3600, "nbf": now + 600}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				if got := r.PostForm.Get("token"); got != "opaque" {
+					t.Errorf("token = %q", got)
+				}
+				if user, _, ok := r.BasicAuth(); !ok || user != "rs" {
+					t.Errorf("client authentication missing")
+				}
+				_ = json.NewEncoder(w).Encode(tt.response)
+			}))
+			defer srv.Close()
+
+			c, err := NewClient(Config{Endpoint: srv.URL, Auth: oauth2.ClientAuth{ClientID: "rs", ClientSecret: "s", Basic: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Introspect(context.Background(), "opaque")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Active != tt.wantActive {
+				t.Errorf("Active = %v, want %v", resp.Active, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestIntrospectServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	c, _ := NewClient(Config{Endpoint: srv.URL})
+	if _, err := c.Introspect(context.Background(), "opaque"); err == nil {
+		t.Error("Introspect succeeded on 502")
+	}
+}
+
+func TestResponse(t *testing.T) {
+	var resp Response
+	raw := `{"active":true,"scope":"read write","aud":"api","exp":1700000000,"ext":"x"}`
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.HasScope("write") || resp.HasScope("admin") {
+		t.Errorf("scopes = %v", resp.Scopes())
+	}
+	if len(resp.Audience) != 1 || resp.Audience[0] != "api" {
+		t.Errorf("aud = %v", resp.Audience)
+	}
+	if !resp.ExpiresAt().Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("ExpiresAt = %s", resp.ExpiresAt())
+	}
+
+	var multi Audience
+	if err := json.Unmarshal([]byte(`["a","b"]`), &multi); err != nil || len(multi) != 2 {
+		t.Errorf("aud array = %v, %v", multi, err)
+	}
+}
+
+func TestNewClientRequiresEndpoint(t *testing.T) {
+	if _, err := NewClient(Config{}); err == nil {
+		t.Error("missing endpoint accepted")
+	}
+}
@@ -0,0 +1,310 @@
+// Package jwks fetches and caches JSON Web Key Sets used to verify tokens
+// signed by OAuth2 and OpenID Connect providers.
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+// maxSetSize caps how much of a JWKS response is read.
+const maxSetSize = 1 << 20
+
+// Config tunes a Cache. Zero values select the defaults.
+type Config struct {
+	// HTTPClient defaults to oauth2.DefaultHTTPClient.
+	HTTPClient *http.Client
+	// DefaultTTL applies when the response has no caching headers.
+	// Defaults to 1h.
+	DefaultTTL time.Duration
+	// MinTTL and MaxTTL clamp the TTL derived from Cache-Control or
+	// Expires. They default to 5m and 24h.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+	// Jitter shortens each TTL by a random fraction up to this value so
+	// that instances do not refetch in lockstep. Defaults to 0.1.
+	Jitter float64
+	// MinRefreshInterval limits refetches triggered by unknown key IDs
+	// and failed fetches. Defaults to 1m.
+	MinRefreshInterval time.Duration
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = oauth2.DefaultHTTPClient
+	}
+	if cfg.DefaultTTL <= 0 {
+		cfg.DefaultTTL = time.Hour
+	}
+	if cfg.MinTTL <= 0 {
+		cfg.MinTTL = 5 * time.Minute
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = 24 * time.Hour
+	}
+	if cfg.Jitter <= 0 || cfg.Jitter >= 1 {
+		cfg.Jitter = 0.1
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = time.Minute
+	}
+}
+
+// Cache holds the signing keys published at one JWKS URL. Keys are
+// refreshed in the background shortly before they expire, and on demand
+// when a token references an unknown key ID. Stale keys keep being served
+// while the provider is unreachable.
+type Cache struct {
+	url string
+	cfg Config
+
+	// fetchMu serializes fetches so concurrent misses share one request.
+	fetchMu sync.Mutex
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetched     time.Time
+	expires     time.Time
+	lastAttempt time.Time
+	refreshing  bool
+}
+
+// New returns a Cache for the JWKS at url. Nothing is fetched until the
+// first lookup.
+func New(url string, cfg Config) *Cache {
+	cfg.setDefaults()
+	return &Cache{url: url, cfg: cfg}
+}
+
+// URL returns the JWKS URL.
+func (c *Cache) URL() string {
+	return c.url
+}
+
+// Key returns the public key for kid. Tokens without a key ID match a set
+// holding exactly one key.
+func (c *Cache) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	now := time.Now()
+	c.mu.RLock()
+	key, ok := c.lookup(kid)
+	fetched, expires, lastAttempt := c.fetched, c.expires, c.lastAttempt
+	c.mu.RUnlock()
+
+	if ok && now.Before(expires) {
+		// Refresh ahead during the last fifth of the TTL. After a failed
+		// fetch the window stays open, so MinRefreshInterval paces retries.
+		if now.After(expires.Add(-expires.Sub(fetched)/5)) && now.Sub(lastAttempt) >= c.cfg.MinRefreshInterval {
+			c.refreshAsync()
+		}
+		return key, nil
+	}
+	if now.Sub(lastAttempt) < c.cfg.MinRefreshInterval {
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := c.refresh(ctx, lastAttempt); err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	c.mu.RLock()
+	key, ok = c.lookup(kid)
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// Refresh fetches the set now.
+func (c *Cache) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	lastAttempt := c.lastAttempt
+	c.mu.RUnlock()
+	return c.refresh(ctx, lastAttempt)
+}
+
+// lookup finds kid in the cached keys. Callers must hold c.mu.
+func (c *Cache) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *Cache) refreshAsync() {
+	c.mu.Lock()
+	if c.refreshing {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing = true
+	lastAttempt := c.lastAttempt
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			c.refreshing = false
+			c.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), oauth2.DefaultTimeout)
+		defer cancel()
+		if err := c.refresh(ctx, lastAttempt); err != nil {
+			log.Warn().Err(err).Str("url", c.url).Msg("Background JWKS refresh failed")
+		}
+	}()
+}
+
+// refresh fetches the set unless another fetch started after since.
+func (c *Cache) refresh(ctx context.Context, since time.Time) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	c.mu.Lock()
+	if c.lastAttempt.After(since) {
+		c.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	c.lastAttempt = now
+	c.mu.Unlock()
+
+	keys, ttl, err := c.fetch(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if len(c.keys) > 0 {
+			// Keep serving the stale keys and retry later.
+			c.expires = now.Add(c.cfg.MinRefreshInterval)
+		}
+		return err
+	}
+	c.keys = keys
+	c.fetched = now
+	c.expires = now.Add(ttl)
+	return nil
+}
+
+func (c *Cache) fetch(ctx context.Context) (map[string]crypto.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSetSize))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch JWKS: %s returned %d", c.url, resp.StatusCode)
+	}
+	var set Set
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, 0, fmt.Errorf("JWKS at %s has no usable signing keys", c.url)
+	}
+	return keys, c.ttl(resp.Header), nil
+}
+
+// ttl derives the cache lifetime from the response headers, clamped to the
+// configured bounds and shortened by jitter.
+func (c *Cache) ttl(h http.Header) time.Duration {
+	ttl := c.cfg.DefaultTTL
+	if maxAge, ok := cacheMaxAge(h.Get("Cache-Control")); ok {
+		ttl = maxAge
+	} else if expires, err := http.ParseTime(h.Get("Expires")); err == nil {
+		ttl = time.Until(expires)
+	}
+	if ttl < c.cfg.MinTTL {
+		ttl = c.cfg.MinTTL
+	}
+	if ttl > c.cfg.MaxTTL {
+		ttl = c.cfg.MaxTTL
+	}
+	return ttl - time.Duration(rand.Float64()*c.cfg.Jitter*float64(ttl))
+}
+
+// cacheMaxAge returns the max-age directive of a Cache-Control header;
+// no-store and no-cache yield zero.
+func cacheMaxAge(header string) (time.Duration, bool) {
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache":
+			return 0, true
+		case strings.HasPrefix(directive, "max-age="):
+			secs, err := strconv.Atoi(strings.Trim(directive[len("max-age="):], `"`))
+			if err == nil && secs >= 0 {
+				return time.Duration(secs) * time.Second, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Registry shares one Cache per JWKS URL, e.g. across clients of the same
+// issuer.
+type Registry struct {
+	cfg Config
+
+	mu     sync.Mutex
+	caches map[string]*Cache
+}
+
+// NewRegistry returns a Registry whose caches use cfg.
+func NewRegistry(cfg Config) *Registry {
+	return &Registry{cfg: cfg, caches: make(map[string]*Cache)}
+}
+
+// Get returns the Cache for url, creating it on first use.
+func (r *Registry) Get(url string) *Cache {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.caches[url]
+	if !ok {
+		c = New(url, r.cfg)
+		r.caches[url] = c
+	}
+	return c
+}
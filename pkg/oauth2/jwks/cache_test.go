@@ -0,0 +1,196 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer serves set with status and counts the requests.
+type jwksServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	set    Set
+	status int
+	header http.Header
+	calls  atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...JWK) *jwksServer {
+	t.Helper()
+	s := &jwksServer{set: Set{Keys: keys}, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for k, v := range s.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(s.status)
+		_ = json.NewEncoder(w).Encode(s.set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) update(status int, keys ...JWK) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	if keys != nil {
+		s.set = Set{Keys: keys}
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	k1, _ := rsaJWK(t, "k1")
+	enc, _ := rsaJWK(t, "enc")
+	enc.Use = "enc"
+	bad := JWK{Kty: "RSA", Kid: "bad", N: "!!"}
+	srv := newJWKSServer(t, k1, enc, bad)
+	c := New(srv.URL, Config{MinRefreshInterval: time.Hour})
+	ctx := context.Background()
+
+	tests := []struct {
+		kid     string
+		wantErr bool
+	}{
+		{"k1", false},
+		{"k1", false},
+		{"enc", true},
+		{"bad", true},
+		{"unknown", true},
+	}
+	for _, tt := range tests {
+		if _, err := c.Key(ctx, tt.kid); (err != nil) != tt.wantErr {
+			t.Errorf("Key(%q) err = %v, wantErr %v", tt.kid, err, tt.wantErr)
+		}
+	}
+	if n := srv.calls.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1: unknown key IDs must not bypass MinRefreshInterval", n)
+	}
+}
+
+func TestCacheRotation(t *testing.T) {
+	k1, _ := rsaJWK(t, "k1")
+	k2, _ := rsaJWK(t, "k2")
+	srv := newJWKSServer(t, k1)
+	c := New(srv.URL, Config{MinRefreshInterval: time.Millisecond})
+	ctx := context.Background()
+
+	if _, err := c.Key(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	srv.update(http.StatusOK, k2)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := c.Key(ctx, "k2"); err != nil {
+		t.Fatalf("rotated key not fetched: %v", err)
+	}
+	if _, err := c.Key(ctx, ""); err != nil {
+		t.Errorf("single key not matched without kid: %v", err)
+	}
+}
+
+func TestCacheServesStaleKeys(t *testing.T) {
+	k1, _ := rsaJWK(t, "k1")
+	srv := newJWKSServer(t, k1)
+	srv.header = http.Header{"Cache-Control": {"no-store"}}
+	c := New(srv.URL, Config{MinTTL: time.Millisecond, MinRefreshInterval: time.Millisecond})
+	ctx := context.Background()
+
+	if _, err := c.Key(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	srv.update(http.StatusServiceUnavailable)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := c.Key(ctx, "k1"); err != nil {
+		t.Errorf("stale key not served during outage: %v", err)
+	}
+	if err := c.Refresh(ctx); err == nil {
+		t.Error("Refresh succeeded during outage")
+	}
+}
+
+func TestCacheFetchErrors(t *testing.T) {
+	enc, _ := rsaJWK(t, "enc")
+	enc.Use = "enc"
+	tests := []struct {
+		name   string
+		status int
+		keys   []JWK
+	}{
+		{"server error", http.StatusInternalServerError, nil},
+		{"no usable keys", http.StatusOK, []JWK{enc}},
+		{"empty set", http.StatusOK, []JWK{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newJWKSServer(t, tt.keys...)
+			srv.update(tt.status)
+			if _, err := New(srv.URL, Config{}).Key(context.Background(), "enc"); err == nil {
+				t.Error("Key succeeded")
+			}
+		})
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"max-age=600", 10 * time.Minute, true},
+		{"public, MAX-AGE=60, must-revalidate", time.Minute, true},
+		{`max-age="30"`, 30 * time.Second, true},
+		{"no-store", 0, true},
+		{"no-cache, max-age=60", 0, true},
+		{"max-age=-1", 0, false},
+		{"max-age=abc", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := cacheMaxAge(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("cacheMaxAge(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := New("", Config{DefaultTTL: time.Hour, MinTTL: time.Minute, MaxTTL: 2 * time.Hour, Jitter: 0.1})
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"default", http.Header{}, time.Hour},
+		{"max-age", http.Header{"Cache-Control": {"max-age=1800"}}, 30 * time.Minute},
+		{"clamped low", http.Header{"Cache-Control": {"no-store"}}, time.Minute},
+		{"clamped high", http.Header{"Cache-Control": {"max-age=86400"}}, 2 * time.Hour},
+		{"expires", http.Header{"Expires": {time.Now().Add(90 * time.Minute).UTC().Format(http.TimeFormat)}}, 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.ttl(tt.header)
+			if got > tt.want || got < tt.want*9/10-time.Second {
+				t.Errorf("ttl = %v, want within 10%% below %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(Config{})
+	if r.Get("https://a/jwks") != r.Get("https://a/jwks") {
+		t.Error("same URL returned different caches")
+	}
+	if r.Get("https://a/jwks") == r.Get("https://b/jwks") {
+		t.Error("different URLs share a cache")
+	}
+}
@@ -0,0 +1,78 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(t *testing.T, kid string) (JWK, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return JWK{Kty: "RSA", Kid: kid, N: b64(key.N.Bytes()), E: b64([]byte{1, 0, 1})}, key
+}
+
+func ecJWK(t *testing.T, curve elliptic.Curve, crv, kid string) JWK {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	x, y := make([]byte, size), make([]byte, size)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return JWK{Kty: "EC", Kid: kid, Crv: crv, X: b64(x), Y: b64(y)}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	rsaKey, _ := rsaJWK(t, "r")
+	p256 := ecJWK(t, elliptic.P256(), "P-256", "e")
+	offCurve := p256
+	y, _ := base64.RawURLEncoding.DecodeString(p256.Y)
+	y[len(y)-1] ^= 1
+	offCurve.Y = b64(y)
+
+	with := func(k JWK, edit func(*JWK)) JWK {
+		edit(&k)
+		return k
+	}
+
+	tests := []struct {
+		name    string
+		jwk     JWK
+		wantErr bool
+	}{
+		{"RSA", rsaKey, false},
+		{"P-256", p256, false},
+		{"P-384", ecJWK(t, elliptic.P384(), "P-384", "e"), false},
+		{"P-521", ecJWK(t, elliptic.P521(), "P-521", "e"), false},
+		{"point not on curve", offCurve, true},
+		{"oversized coordinate", with(p256, func(k *JWK) { k.X = b64(make([]byte, 33)) }), true},
+		{"curve mismatch", with(p256, func(k *JWK) { k.Crv = "P-384" }), true},
+		{"unsupported curve", with(p256, func(k *JWK) { k.Crv = "secp256k1" }), true},
+		{"exponent 1", with(rsaKey, func(k *JWK) { k.E = b64([]byte{1}) }), true},
+		{"huge exponent", with(rsaKey, func(k *JWK) { k.E = b64([]byte{1, 0, 0, 0, 0, 0, 0, 0, 1}) }), true},
+		{"missing modulus", with(rsaKey, func(k *JWK) { k.N = "" }), true},
+		{"bad base64", with(rsaKey, func(k *JWK) { k.N = "!!" }), true},
+		{"symmetric key", JWK{Kty: "oct", Kid: "s"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := tt.jwk.PublicKey()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PublicKey = %T, %v; wantErr %v", key, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Package microsoft implements "Sign in with Microsoft" against the
+// Microsoft identity platform (v2.0 endpoints), identifying the user
+// through Microsoft Graph. Single-tenant applications that need ID token
+// validation can use oauth2/oidc with the tenant's issuer instead.
+package microsoft
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+// Tenants accepted besides a tenant ID or domain.
+const (
+	// TenantCommon admits work, school and personal accounts.
+	TenantCommon = "common"
+	// TenantOrganizations admits work and school accounts only.
+	TenantOrganizations = "organizations"
+	// TenantConsumers admits personal Microsoft accounts only.
+	TenantConsumers = "consumers"
+)
+
+// DefaultGraphURL is the Microsoft Graph v1.0 endpoint.
+const DefaultGraphURL = "https://graph.microsoft.com/v1.0"
+
+// DefaultScopes request the profile through Microsoft Graph.
+var DefaultScopes = []string{"openid", "profile", "email", "User.Read"}
+
+// TenantEndpoint returns the v2.0 endpoint of tenant.
+func TenantEndpoint(tenant string) oauth2.Endpoint {
+	base := "https://login.microsoftonline.com/" + url.PathEscape(tenant) + "/oauth2/v2.0"
+	return oauth2.Endpoint{AuthURL: base + "/authorize", TokenURL: base + "/token"}
+}
+
+// Config configures a Client.
+type Config struct {
+	// Tenant is a tenant ID or domain, or one of TenantCommon,
+	// TenantOrganizations and TenantConsumers. Defaults to TenantCommon.
+	Tenant       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes defaults to DefaultScopes. Add offline_access for a refresh
+	// token.
+	Scopes []string
+	// Endpoint overrides the endpoint derived from Tenant, e.g. for
+	// national clouds.
+	Endpoint oauth2.Endpoint
+	// GraphURL defaults to DefaultGraphURL.
+	GraphURL string
+	// HTTPClient defaults to oauth2.DefaultHTTPClient.
+	HTTPClient *http.Client
+}
+
+// Client signs users in with Microsoft.
+type Client struct {
+	code     *oauth2.Config
+	graphURL string
+	client   *http.Client
+}
+
+// New returns a Client for cfg.
+func New(cfg Config) (*Client, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, errors.New("microsoft: client ID and secret are required")
+	}
+	if cfg.Tenant == "" {
+		cfg.Tenant = TenantCommon
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	if cfg.Endpoint == (oauth2.Endpoint{}) {
+		cfg.Endpoint = TenantEndpoint(cfg.Tenant)
+	}
+	if cfg.GraphURL == "" {
+		cfg.GraphURL = DefaultGraphURL
+	}
+	return &Client{
+		code: &oauth2.Config{
+			Endpoint:    cfg.Endpoint,
+			Auth:        oauth2.ClientAuth{ClientID: cfg.ClientID, ClientSecret: cfg.ClientSecret},
+			RedirectURL: cfg.RedirectURL,
+			Scopes:      cfg.Scopes,
+			HTTPClient:  cfg.HTTPClient,
+		},
+		graphURL: strings.TrimSuffix(cfg.GraphURL, "/"),
+		client:   cfg.HTTPClient,
+	}, nil
+}
+
+// AuthCodeURL builds the authorization URL. pkce may be nil; extra adds
+// parameters such as prompt, login_hint or domain_hint.
+func (c *Client) AuthCodeURL(state string, pkce *oauth2.PKCE, extra url.Values) string {
+	return c.code.AuthCodeURL(state, pkce, extra)
+}
+
+// ExchangeCode trades an authorization code for tokens. verifier is the
+// PKCE code verifier, or empty when PKCE was not used.
+func (c *Client) ExchangeCode(ctx context.Context, code, verifier string) (*oauth2.TokenResponse, error) {
+	return c.code.Exchange(ctx, code, verifier)
+}
+
+// UserInfo is the signed-in user as reported by Microsoft Graph.
+type UserInfo struct {
+	// ID is the object ID, unique within the user's tenant.
+	ID          string
+	DisplayName string
+	GivenName   string
+	Surname     string
+	// Mail is the user's email address. It is often empty for personal
+	// accounts and is not verified by Microsoft.
+	Mail string
+	// UserPrincipalName is the sign-in name, usually but not necessarily
+	// an email address.
+	UserPrincipalName string
+	PreferredLanguage string
+}
+
+type graphUser struct {
+	ID                string `json:"id"`
+	DisplayName       string `json:"displayName"`
+	GivenName         string `json:"givenName"`
+	Surname           string `json:"surname"`
+	Mail              string `json:"mail"`
+	UserPrincipalName string `json:"userPrincipalName"`
+	PreferredLanguage string `json:"preferredLanguage"`
+}
+
+// GetUserInfo fetches the profile of the user the access token belongs to.
+// The token needs the User.Read scope.
+func (c *Client) GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.graphURL+"/me", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var u graphUser
+	if err := oauth2.Do(c.client, req, &u); err != nil {
+		return nil, err
+	}
+	if u.ID == "" {
+		return nil, errors.New("microsoft: user response has no id")
+	}
+	return &UserInfo{
+		ID:                u.ID,
+		DisplayName:       u.DisplayName,
+		GivenName:         u.GivenName,
+		Surname:           u.Surname,
+		Mail:              u.Mail,
+		UserPrincipalName: u.UserPrincipalName,
+		PreferredLanguage: u.PreferredLanguage,
+	}, nil
+}
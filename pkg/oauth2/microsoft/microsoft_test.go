@@ -0,0 +1,89 @@
+package microsoft
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+func TestTenantEndpoint(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{ClientID: "app", ClientSecret: "s"}, "https://login.microsoftonline.com/common/oauth2/v2.0/authorize"},
+		{Config{ClientID: "app", ClientSecret: "s", Tenant: "contoso.onmicrosoft.com"}, "https://login.microsoftonline.com/contoso.onmicrosoft.com/oauth2/v2.0/authorize"},
+	}
+	for _, tt := range tests {
+		c, err := New(tt.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, _ := url.Parse(c.AuthCodeURL("st", nil, nil))
+		if got := u.Scheme + "://" + u.Host + u.Path; got != tt.want {
+			t.Errorf("tenant %q: auth URL = %s, want %s", tt.cfg.Tenant, got, tt.want)
+		}
+		if u.Query().Get("scope") != "openid profile email User.Read" {
+			t.Errorf("scope = %q", u.Query().Get("scope"))
+		}
+	}
+}
+
+func TestExchangeAndUserInfo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_ = r.ParseForm()
+			if r.PostForm.Get("code") != "abc" || r.PostForm.Get("code_verifier") != "v" {
+				t.Errorf("form = %s", r.PostForm.Encode())
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "at", "token_type": "Bearer", "expires_in": 3600})
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer at" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"id": "oid", "displayName": "Ada Lovelace", "givenName": "Ada", "surname": "Lovelace",
+				"mail": "ada@contoso.com", "userPrincipalName": "ada@contoso.onmicrosoft.com",
+			})
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{
+		ClientID:     "app",
+		ClientSecret: "secret",
+		Endpoint:     oauth2.Endpoint{AuthURL: srv.URL + "/authorize", TokenURL: srv.URL + "/token"},
+		GraphURL:     srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := c.ExchangeCode(context.Background(), "abc", "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.GetUserInfo(context.Background(), token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := UserInfo{ID: "oid", DisplayName: "Ada Lovelace", GivenName: "Ada", Surname: "Lovelace", Mail: "ada@contoso.com", UserPrincipalName: "ada@contoso.onmicrosoft.com"}
+	if *info != want {
+		t.Errorf("info = %+v", info)
+	}
+	if _, err := c.GetUserInfo(context.Background(), "wrong"); err == nil {
+		t.Error("GetUserInfo succeeded on 401")
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New(Config{ClientSecret: "s"}); err == nil {
+		t.Error("missing client ID accepted")
+	}
+}
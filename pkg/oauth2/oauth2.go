@@ -0,0 +1,284 @@
+// Package oauth2 holds the primitives shared by the OAuth 2.0 and OpenID
+// Connect clients in its subpackages.
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+)
+
+// DefaultTimeout bounds calls made with the default HTTP client.
+const DefaultTimeout = 10 * time.Second
+
+// maxResponseSize caps how much of a provider response is read.
+const maxResponseSize = 1 << 20
+
+// DefaultHTTPClient is used when no client is configured.
+var DefaultHTTPClient = &http.Client{Timeout: DefaultTimeout}
+
+// TokenResponse is the token endpoint response defined by RFC 6749 and
+// OpenID Connect.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Expiry returns the absolute expiry relative to issued, or the zero time
+// when the provider did not send expires_in.
+func (t *TokenResponse) Expiry(issued time.Time) time.Time {
+	if t.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	return issued.Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+// ClientAuth authenticates a client at the token endpoint.
+type ClientAuth struct {
+	ClientID     string
+	ClientSecret string
+	// Basic sends the credentials with HTTP Basic authentication
+	// (client_secret_basic) instead of in the form body (client_secret_post).
+	Basic bool
+	// Assertion authenticates with a signed JWT (private_key_jwt) instead
+	// of ClientSecret. A new assertion is signed for every request.
+	Assertion *AssertionConfig
+	// SecretAssertion signs a JWT that is sent as ClientSecret, for
+	// providers such as Apple that expect a signed client secret rather
+	// than private_key_jwt. A new secret is signed for every request.
+	SecretAssertion *AssertionConfig
+}
+
+// secret returns the client secret, signing it when SecretAssertion is set.
+func (a ClientAuth) secret() (string, error) {
+	if a.SecretAssertion == nil {
+		return a.ClientSecret, nil
+	}
+	return NewClientAssertion(*a.SecretAssertion)
+}
+
+// PostToken sends form to a token endpoint and decodes the response.
+// Error objects sent with a 2xx status, as GitHub does, are returned as
+// *OAuthError like any other provider error.
+func PostToken(ctx context.Context, client *http.Client, endpoint string, auth ClientAuth, form url.Values) (*TokenResponse, error) {
+	req, err := NewFormRequest(ctx, endpoint, auth, form)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		TokenResponse
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+		URI         string `json:"error_uri"`
+	}
+	if err := Do(client, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, &OAuthError{
+			Endpoint:    req.URL.Redacted(),
+			StatusCode:  http.StatusOK,
+			Code:        resp.Error,
+			Description: resp.Description,
+			URI:         resp.URI,
+		}
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("token response from %s has no access_token", endpoint)
+	}
+	return &resp.TokenResponse, nil
+}
+
+// Revoke invalidates token at a revocation endpoint (RFC 7009). hint is an
+// optional token_type_hint such as "refresh_token" or "access_token".
+func Revoke(ctx context.Context, client *http.Client, endpoint string, auth ClientAuth, token, hint string) error {
+	form := url.Values{"token": {token}}
+	if hint != "" {
+		form.Set("token_type_hint", hint)
+	}
+	req, err := NewFormRequest(ctx, endpoint, auth, form)
+	if err != nil {
+		return err
+	}
+	return Do(client, req, nil)
+}
+
+// NewFormRequest builds a form POST to a provider endpoint, authenticated
+// as the client.
+func NewFormRequest(ctx context.Context, endpoint string, auth ClientAuth, form url.Values) (*http.Request, error) {
+	form = cloneValues(form)
+	secret, err := auth.secret()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case auth.Assertion != nil:
+		assertion, err := NewClientAssertion(*auth.Assertion)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("client_id", auth.ClientID)
+		form.Set("client_assertion_type", ClientAssertionType)
+		form.Set("client_assertion", assertion)
+	case !auth.Basic:
+		form.Set("client_id", auth.ClientID)
+		if secret != "" {
+			form.Set("client_secret", secret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if auth.Basic && auth.Assertion == nil {
+		req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(secret))
+	}
+	return req, nil
+}
+
+// OAuthError is a non-2xx response from a provider endpoint. Code and
+// Description hold the RFC 6749 error and error_description members when
+// the provider sent them.
+type OAuthError struct {
+	Endpoint    string
+	StatusCode  int
+	Code        string
+	Description string
+	URI         string
+	// Body is the raw response body when it was not an OAuth error object.
+	Body string
+	// RetryAfter is the provider's Retry-After delay, or zero.
+	RetryAfter time.Duration
+}
+
+func newOAuthError(endpoint string, resp *http.Response, body []byte) *OAuthError {
+	e := &OAuthError{Endpoint: endpoint, StatusCode: resp.StatusCode}
+	var wire struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+		URI         string `json:"error_uri"`
+	}
+	if json.Unmarshal(body, &wire) == nil && wire.Error != "" {
+		e.Code, e.Description, e.URI = wire.Error, wire.Description, wire.URI
+	} else {
+		e.Body = strings.TrimSpace(string(body))
+	}
+	e.RetryAfter = resilience.ParseRetryAfter(resp.Header)
+	return e
+}
+
+func (e *OAuthError) Error() string {
+	switch {
+	case e.Code != "" && e.Description != "":
+		return fmt.Sprintf("%s returned %d: %s: %s", e.Endpoint, e.StatusCode, e.Code, e.Description)
+	case e.Code != "":
+		return fmt.Sprintf("%s returned %d: %s", e.Endpoint, e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("%s returned %d: %s", e.Endpoint, e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the same request may succeed later: server
+// errors, rate limiting and the temporarily_unavailable error code.
+// Errors such as invalid_grant or invalid_client are permanent.
+func (e *OAuthError) Retryable() bool {
+	switch e.Code {
+	case "temporarily_unavailable", "server_error":
+		return true
+	}
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// Do executes req and decodes a successful JSON response into out. Non-2xx
+// responses are returned as *OAuthError.
+func Do(client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = DefaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response from %s: %w", req.URL.Redacted(), err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newOAuthError(req.URL.Redacted(), resp, body)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", req.URL.Redacted(), err)
+	}
+	return nil
+}
+
+// GenerateState returns a random value for the OAuth2 state parameter.
+func GenerateState() (string, error) {
+	return randomString(32)
+}
+
+// GenerateNonce returns a random value for the OpenID Connect nonce
+// parameter, to be checked against the ID token's nonce claim.
+func GenerateNonce() (string, error) {
+	return randomString(32)
+}
+
+// PKCE holds a Proof Key for Code Exchange pair (RFC 7636).
+type PKCE struct {
+	Verifier  string
+	Challenge string
+	// Method is always S256.
+	Method string
+}
+
+// GeneratePKCE returns a new verifier and its S256 challenge.
+func GeneratePKCE() (PKCE, error) {
+	verifier, err := randomString(32)
+	if err != nil {
+		return PKCE{}, err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return PKCE{
+		Verifier:  verifier,
+		Challenge: base64.RawURLEncoding.EncodeToString(sum[:]),
+		Method:    "S256",
+	}, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := make(url.Values, len(v)+3)
+	for k, vs := range v {
+		out[k] = append([]string(nil), vs...)
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateNonce(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		nonce, err := GenerateNonce()
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(nonce)
+		if err != nil || len(raw) != 32 {
+			t.Fatalf("nonce %q is not 32 random bytes", nonce)
+		}
+		if seen[nonce] {
+			t.Fatalf("nonce %q repeated", nonce)
+		}
+		seen[nonce] = true
+	}
+}
+
+func TestDoErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		want       OAuthError
+		wantString string
+		retryable  bool
+	}{
+		{
+			name: "oauth error", status: http.StatusBadRequest,
+			body:       `{"error":"invalid_grant","error_description":"code expired","error_uri":"https://idp/doc"}`,
+			want:       OAuthError{StatusCode: 400, Code: "invalid_grant", Description: "code expired", URI: "https://idp/doc"},
+			wantString: "returned 400: invalid_grant: code expired",
+		},
+		{
+			name: "code only", status: http.StatusUnauthorized, body: `{"error":"invalid_client"}`,
+			want: OAuthError{StatusCode: 401, Code: "invalid_client"}, wantString: "returned 401: invalid_client",
+		},
+		{
+			name: "html error page", status: http.StatusBadGateway, body: "<html>bad gateway</html>\n",
+			want: OAuthError{StatusCode: 502, Body: "<html>bad gateway</html>"}, wantString: "returned 502: <html>bad gateway</html>",
+			retryable: true,
+		},
+		{
+			name: "rate limited", status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"7"}},
+			body: `{"error":"slow_down"}`,
+			want: OAuthError{StatusCode: 429, Code: "slow_down", RetryAfter: 7 * time.Second}, wantString: "returned 429: slow_down",
+			retryable: true,
+		},
+		{
+			name: "temporarily unavailable", status: http.StatusBadRequest, body: `{"error":"temporarily_unavailable"}`,
+			want: OAuthError{StatusCode: 400, Code: "temporarily_unavailable"}, wantString: "temporarily_unavailable",
+			retryable: true,
+		},
+		{
+			name: "server_error code", status: http.StatusBadRequest, body: `{"error":"server_error"}`,
+			want: OAuthError{StatusCode: 400, Code: "server_error"}, wantString: "server_error",
+			retryable: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+"/token", nil)
+			err := Do(nil, req, nil)
+			var got *OAuthError
+			if !errors.As(err, &got) {
+				t.Fatalf("err = %v, want *OAuthError", err)
+			}
+			tt.want.Endpoint = got.Endpoint
+			if *got != tt.want {
+				t.Errorf("error = %+v, want %+v", *got, tt.want)
+			}
+			if !strings.Contains(got.Error(), tt.wantString) {
+				t.Errorf("Error() = %q, want it to contain %q", got.Error(), tt.wantString)
+			}
+			if got.Retryable() != tt.retryable {
+				t.Errorf("Retryable() = %v, want %v", got.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
+
+func TestDoDecode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"a":` + strings.Repeat(" ", maxResponseSize) + `1}`))
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	var out map[string]int
+	if err := Do(nil, req, &out); err == nil {
+		t.Error("response beyond maxResponseSize decoded")
+	}
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	req, _ = http.NewRequest(http.MethodGet, down.URL, nil)
+	var oauthErr *OAuthError
+	if err := Do(nil, req, nil); err == nil || errors.As(err, &oauthErr) {
+		t.Errorf("transport failure = %v, want a non-OAuth error", err)
+	}
+}
@@ -0,0 +1,434 @@
+// Package oidc is a generic OpenID Connect relying party client driven by
+// provider discovery, suitable for Keycloak, Auth0, Okta and similar.
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2/jwks"
+)
+
+// DefaultScopes are requested when Config.Scopes is empty.
+var DefaultScopes = []string{"openid", "profile", "email"}
+
+// clockSkew is tolerated when validating token timestamps.
+const clockSkew = time.Minute
+
+// Config configures a Client.
+type Config struct {
+	// Issuer URL; discovery is performed against
+	// <Issuer>/.well-known/openid-configuration.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// Assertion enables private_key_jwt client authentication instead of
+	// ClientSecret. ClientID and Audience default to the client ID and the
+	// token endpoint.
+	Assertion *oauth2.AssertionConfig
+	// SecretAssertion signs a JWT sent as the client secret instead of
+	// ClientSecret, as Apple requires. ClientID and Audience default to
+	// the client ID and the issuer.
+	SecretAssertion *oauth2.AssertionConfig
+	// RevocationURL overrides the discovered revocation_endpoint, for
+	// providers such as Apple that do not advertise it.
+	RevocationURL string
+	// HTTPClient defaults to oauth2.DefaultHTTPClient.
+	HTTPClient *http.Client
+	// Retry retries transient failures of provider calls. Userinfo and
+	// discovery are retried on connection errors, 5xx and 429; token
+	// exchanges only when the provider could not be reached, since codes
+	// and refresh tokens are single-use. Nil disables retries.
+	Retry *oauth2.RetryPolicy
+	// Keys optionally shares signing key caches between clients of the
+	// same issuer.
+	Keys *jwks.Registry
+}
+
+// Metadata is the subset of the discovery document used by the client.
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// Client is an OpenID Connect client bound to one provider.
+type Client struct {
+	cfg      Config
+	metadata Metadata
+	keys     *jwks.Cache
+	auth     oauth2.ClientAuth
+	code     *oauth2.Config
+}
+
+// NewClient performs discovery for cfg.Issuer and returns a Client.
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil, errors.New("oidc: issuer and client ID are required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = oauth2.DefaultHTTPClient
+	}
+	if cfg.Retry != nil {
+		cfg.HTTPClient = oauth2.WithRetry(cfg.HTTPClient, *cfg.Retry)
+	}
+
+	wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	var metadata Metadata
+	if err := oauth2.Do(cfg.HTTPClient, req, &metadata); err != nil {
+		return nil, fmt.Errorf("oidc: discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(cfg.Issuer, "/") {
+		return nil, fmt.Errorf("oidc: discovered issuer %q does not match %q", metadata.Issuer, cfg.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is missing required endpoints")
+	}
+
+	var keys *jwks.Cache
+	if cfg.Keys != nil {
+		keys = cfg.Keys.Get(metadata.JWKSURI)
+	} else {
+		keys = jwks.New(metadata.JWKSURI, jwks.Config{HTTPClient: cfg.HTTPClient})
+	}
+
+	auth := oauth2.ClientAuth{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Basic:        cfg.ClientSecret != "" && supportsBasicAuth(metadata.TokenEndpointAuthMethodsSupported),
+	}
+	if cfg.SecretAssertion != nil {
+		secret := *cfg.SecretAssertion
+		if secret.ClientID == "" {
+			secret.ClientID = cfg.ClientID
+		}
+		if secret.Audience == "" {
+			secret.Audience = metadata.Issuer
+		}
+		auth.SecretAssertion = &secret
+	}
+	if cfg.Assertion != nil {
+		assertion := *cfg.Assertion
+		if assertion.ClientID == "" {
+			assertion.ClientID = cfg.ClientID
+		}
+		if assertion.Audience == "" {
+			assertion.Audience = metadata.TokenEndpoint
+		}
+		auth = oauth2.ClientAuth{ClientID: cfg.ClientID, Assertion: &assertion}
+	}
+
+	return &Client{
+		cfg:      cfg,
+		metadata: metadata,
+		keys:     keys,
+		auth:     auth,
+		code: &oauth2.Config{
+			Endpoint:    oauth2.Endpoint{AuthURL: metadata.AuthorizationEndpoint, TokenURL: metadata.TokenEndpoint},
+			Auth:        auth,
+			RedirectURL: cfg.RedirectURL,
+			Scopes:      cfg.Scopes,
+			HTTPClient:  cfg.HTTPClient,
+		},
+	}, nil
+}
+
+// supportsBasicAuth reports whether client_secret_basic may be used; it is
+// the default when the provider does not advertise its methods.
+func supportsBasicAuth(methods []string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == "client_secret_basic" {
+			return true
+		}
+	}
+	return false
+}
+
+// Metadata returns the discovered provider metadata.
+func (c *Client) Metadata() Metadata {
+	return c.metadata
+}
+
+// AuthCodeURL builds the authorization URL. pkce may be nil; extra adds or
+// overrides query parameters such as prompt or login_hint.
+func (c *Client) AuthCodeURL(state string, pkce *oauth2.PKCE, extra url.Values) string {
+	return c.code.AuthCodeURL(state, pkce, extra)
+}
+
+// Exchange trades an authorization code for tokens. verifier is the PKCE
+// code verifier, or empty when PKCE was not used.
+func (c *Client) Exchange(ctx context.Context, code, verifier string) (*oauth2.TokenResponse, error) {
+	return c.code.Exchange(ctx, code, verifier)
+}
+
+// Refresh obtains new tokens with a refresh token. scopes optionally
+// narrows the granted scope. Providers that do not rotate refresh tokens,
+// such as Apple, return none; the original is carried over so callers can
+// always persist the response's RefreshToken.
+func (c *Client) Refresh(ctx context.Context, refreshToken string, scopes ...string) (*oauth2.TokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+	token, err := oauth2.PostToken(ctx, c.cfg.HTTPClient, c.metadata.TokenEndpoint, c.auth, form)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// Revoke invalidates an access or refresh token (RFC 7009), e.g. during
+// account deletion. hint is an optional token_type_hint.
+func (c *Client) Revoke(ctx context.Context, token, hint string) error {
+	endpoint := c.cfg.RevocationURL
+	if endpoint == "" {
+		endpoint = c.metadata.RevocationEndpoint
+	}
+	if endpoint == "" {
+		return errors.New("oidc: provider has no revocation endpoint")
+	}
+	return oauth2.Revoke(ctx, c.cfg.HTTPClient, endpoint, c.auth, token, hint)
+}
+
+// IDToken holds the validated claims of an ID token.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Audience      []string
+	Expiry        time.Time
+	IssuedAt      time.Time
+	Nonce         string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Claims holds every claim for provider-specific fields.
+	Claims map[string]interface{}
+}
+
+// idTokenClaims is the wire form of the standard claims.
+type idTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	AZP           string   `json:"azp"`
+	Expiry        int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	NotBefore     int64    `json:"nbf"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// VerifyIDToken validates the signature against the provider's JWKS and
+// checks issuer, audience and expiry.
+func (c *Client) VerifyIDToken(ctx context.Context, raw string) (*IDToken, error) {
+	return c.verifyIDToken(ctx, raw, "")
+}
+
+// VerifyIDTokenNonce is VerifyIDToken that additionally requires the nonce
+// claim to equal the nonce sent with the authorization request, preventing
+// token replay.
+func (c *Client) VerifyIDTokenNonce(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	if nonce == "" {
+		return nil, errors.New("oidc: nonce is required")
+	}
+	return c.verifyIDToken(ctx, raw, nonce)
+}
+
+func (c *Client) verifyIDToken(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	jwt, err := parseJWT(raw)
+	if err != nil {
+		return nil, err
+	}
+	key, err := c.keys.Key(ctx, jwt.header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := jwt.verify(key); err != nil {
+		return nil, err
+	}
+
+	var claims idTokenClaims
+	if err := json.Unmarshal(jwt.payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token claims: %w", err)
+	}
+	if claims.Issuer != c.metadata.Issuer {
+		return nil, fmt.Errorf("ID token issued by %q, expected %q", claims.Issuer, c.metadata.Issuer)
+	}
+	if !claims.Audience.contains(c.cfg.ClientID) {
+		return nil, errors.New("ID token audience does not include client ID")
+	}
+	if len(claims.Audience) > 1 && claims.AZP != "" && claims.AZP != c.cfg.ClientID {
+		return nil, errors.New("ID token authorized party does not match client ID")
+	}
+	now := time.Now()
+	if claims.Expiry == 0 || now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)) {
+		return nil, errors.New("ID token expired")
+	}
+	if claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, errors.New("ID token not yet valid")
+	}
+	if nonce != "" && subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	token := &IDToken{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Audience:      claims.Audience,
+		Expiry:        time.Unix(claims.Expiry, 0),
+		IssuedAt:      time.Unix(claims.IssuedAt, 0),
+		Nonce:         claims.Nonce,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+	if err := json.Unmarshal(jwt.payload, &token.Claims); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// UserInfo is the response of the userinfo endpoint.
+type UserInfo struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Picture       string
+	Locale        string
+	// Claims holds every returned claim for provider-specific fields.
+	Claims map[string]interface{}
+}
+
+// userInfoClaims is the wire form of the standard userinfo claims.
+type userInfoClaims struct {
+	Subject       string   `json:"sub"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+	Picture       string   `json:"picture"`
+	Locale        string   `json:"locale"`
+}
+
+// UserInfo fetches the userinfo endpoint with an access token.
+func (c *Client) UserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	if c.metadata.UserinfoEndpoint == "" {
+		return nil, errors.New("oidc: provider has no userinfo endpoint")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.metadata.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var raw json.RawMessage
+	if err := oauth2.Do(c.cfg.HTTPClient, req, &raw); err != nil {
+		return nil, err
+	}
+	var claims userInfoClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, fmt.Errorf("malformed userinfo response: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("oidc: userinfo response has no subject")
+	}
+
+	info := &UserInfo{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		Picture:       claims.Picture,
+		Locale:        claims.Locale,
+	}
+	if err := json.Unmarshal(raw, &info.Claims); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// audience accepts both the string and array forms of the aud claim.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// flexBool accepts booleans encoded as JSON booleans or strings, as some
+// providers send email_verified as "true".
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case bool:
+		*f = flexBool(t)
+	case string:
+		*f = flexBool(t == "true")
+	}
+	return nil
+}
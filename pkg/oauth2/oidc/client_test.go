@@ -0,0 +1,481 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2/jwks"
+)
+
+// provider is a fake OpenID provider serving discovery, JWKS, token,
+// userinfo and revocation endpoints.
+type provider struct {
+	*httptest.Server
+	// metadata edits the discovery document before it is served.
+	metadata func(m map[string]interface{})
+	token    func(w http.ResponseWriter, r *http.Request)
+	userinfo func(w http.ResponseWriter, r *http.Request)
+
+	mu       sync.Mutex
+	requests []*http.Request
+	forms    []url.Values
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	p := &provider{}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *provider) serve(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	p.mu.Lock()
+	p.requests = append(p.requests, r)
+	p.forms = append(p.forms, r.PostForm)
+	p.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		m := map[string]interface{}{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"userinfo_endpoint":      p.URL + "/userinfo",
+			"jwks_uri":               p.URL + "/jwks",
+			"revocation_endpoint":    p.URL + "/revoke",
+		}
+		if p.metadata != nil {
+			p.metadata(m)
+		}
+		writeJSON(w, http.StatusOK, m)
+	case "/jwks":
+		writeJSON(w, http.StatusOK, jwks.Set{Keys: []jwks.JWK{rsaJWK("rsa"), ecJWK("ec")}})
+	case "/token":
+		if p.token != nil {
+			p.token(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "at", "token_type": "Bearer", "expires_in": 60})
+	case "/userinfo":
+		if p.userinfo != nil {
+			p.userinfo(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"sub": "alice", "email": "a@example.com", "email_verified": "true"})
+	case "/revoke", "/auth/revoke":
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// last returns the last request to path and its form.
+func (p *provider) last(path string) (*http.Request, url.Values) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := len(p.requests) - 1; i >= 0; i-- {
+		if p.requests[i].URL.Path == path {
+			return p.requests[i], p.forms[i]
+		}
+	}
+	return nil, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string) jwks.JWK {
+	return jwks.JWK{Kty: "RSA", Kid: kid, N: b64(rsaKey.N.Bytes()), E: b64([]byte{1, 0, 1})}
+}
+
+func ecJWK(kid string) jwks.JWK {
+	x, y := make([]byte, 32), make([]byte, 32)
+	ecKey.X.FillBytes(x)
+	ecKey.Y.FillBytes(y)
+	return jwks.JWK{Kty: "EC", Kid: kid, Crv: "P-256", X: b64(x), Y: b64(y)}
+}
+
+func newTestClient(t *testing.T, p *provider, cfg Config) *Client {
+	t.Helper()
+	cfg.Issuer = p.URL
+	if cfg.ClientID == "" {
+		cfg.ClientID = "client"
+	}
+	c, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNewClientDiscovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata func(m map[string]interface{})
+		wantErr  string
+	}{
+		{"valid", nil, ""},
+		{"issuer mismatch", func(m map[string]interface{}) { m["issuer"] = "https://evil.example" }, "does not match"},
+		{"missing token endpoint", func(m map[string]interface{}) { delete(m, "token_endpoint") }, "missing required endpoints"},
+		{"missing jwks", func(m map[string]interface{}) { delete(m, "jwks_uri") }, "missing required endpoints"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProvider(t)
+			p.metadata = tt.metadata
+			_, err := NewClient(context.Background(), Config{Issuer: p.URL, ClientID: "client"})
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewClient(context.Background(), Config{Issuer: "http://127.0.0.1:0"}); err == nil {
+		t.Error("missing client ID accepted")
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	if _, err := NewClient(context.Background(), Config{Issuer: down.URL, ClientID: "client"}); err == nil {
+		t.Error("unreachable issuer accepted")
+	}
+}
+
+func TestClientAuthMethod(t *testing.T) {
+	tests := []struct {
+		name      string
+		methods   []string
+		secret    string
+		wantBasic bool
+	}{
+		{"default basic", nil, "s", true},
+		{"basic advertised", []string{"client_secret_post", "client_secret_basic"}, "s", true},
+		{"post only", []string{"client_secret_post"}, "s", false},
+		{"public client", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProvider(t)
+			p.metadata = func(m map[string]interface{}) {
+				if tt.methods != nil {
+					m["token_endpoint_auth_methods_supported"] = tt.methods
+				}
+			}
+			c := newTestClient(t, p, Config{ClientSecret: tt.secret})
+			if _, err := c.Exchange(context.Background(), "code", ""); err != nil {
+				t.Fatal(err)
+			}
+			r, form := p.last("/token")
+			_, _, basic := r.BasicAuth()
+			if basic != tt.wantBasic {
+				t.Errorf("basic = %v, want %v", basic, tt.wantBasic)
+			}
+			if (form.Get("client_secret") != "") != (!tt.wantBasic && tt.secret != "") {
+				t.Errorf("client_secret in form = %q", form.Get("client_secret"))
+			}
+		})
+	}
+}
+
+func TestClientAuthCodeURLAndExchange(t *testing.T) {
+	p := newProvider(t)
+	c := newTestClient(t, p, Config{ClientSecret: "s", RedirectURL: "https://app.example/cb"})
+
+	u, err := url.Parse(c.AuthCodeURL("st", &oauth2.PKCE{Challenge: "ch", Method: "S256"}, url.Values{"prompt": {"login"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("scope") != "openid profile email" || q.Get("state") != "st" ||
+		q.Get("code_challenge") != "ch" || q.Get("prompt") != "login" || q.Get("redirect_uri") != "https://app.example/cb" {
+		t.Errorf("AuthCodeURL = %s", u)
+	}
+
+	p.token = func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code used"})
+	}
+	_, err = c.Exchange(context.Background(), "code", "verifier")
+	var oauthErr *oauth2.OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" {
+		t.Fatalf("err = %v, want invalid_grant", err)
+	}
+	if _, form := p.last("/token"); form.Get("code_verifier") != "verifier" || form.Get("grant_type") != "authorization_code" {
+		t.Errorf("token form = %v", form)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	p := newProvider(t)
+	c := newTestClient(t, p, Config{})
+	now := time.Now()
+	claims := func(edit func(m map[string]interface{})) map[string]interface{} {
+		m := map[string]interface{}{
+			"iss": p.URL, "sub": "alice", "aud": "client",
+			"exp": now.Add(time.Hour).Unix(), "iat": now.Unix(),
+			"email": "a@example.com", "email_verified": true,
+		}
+		if edit != nil {
+			edit(m)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{"RS256", signJWT(t, rsaKey, "RS256", "rsa", claims(nil)), ""},
+		{"ES256", signJWT(t, ecKey, "ES256", "ec", claims(nil)), ""},
+		{"alg none", signJWT(t, nil, "none", "rsa", claims(nil)), "unsupported JWT algorithm"},
+		{"RS256 against EC key", signJWT(t, rsaKey, "RS256", "ec", claims(nil)), "does not match"},
+		{"ES256 against RSA key", signJWT(t, ecKey, "ES256", "rsa", claims(nil)), "does not match"},
+		{"foreign key", signJWT(t, mustRSA(t), "RS256", "rsa", claims(nil)), "invalid JWT signature"},
+		{"unknown kid", signJWT(t, rsaKey, "RS256", "other", claims(nil)), "unknown signing key"},
+		{"wrong issuer", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) { m["iss"] = "https://evil.example" })), "issued by"},
+		{"wrong audience", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) { m["aud"] = "other" })), "audience"},
+		{"audience list", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) { m["aud"] = []string{"other", "client"} })), ""},
+		{"foreign azp", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) {
+			m["aud"], m["azp"] = []string{"other", "client"}, "other"
+		})), "authorized party"},
+		{"expired", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) { m["exp"] = now.Add(-2 * time.Minute).Unix() })), "expired"},
+		{"expired within skew", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) { m["exp"] = now.Add(-30 * time.Second).Unix() })), ""},
+		{"no exp", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) { delete(m, "exp") })), "expired"},
+		{"not yet valid", signJWT(t, rsaKey, "RS256", "rsa", claims(func(m map[string]interface{}) { m["nbf"] = now.Add(time.Hour).Unix() })), "not yet valid"},
+		{"malformed", "not.a.jwt", "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, err := c.VerifyIDToken(context.Background(), tt.raw)
+			switch {
+			case tt.wantErr == "":
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tok.Subject != "alice" || !tok.EmailVerified || tok.Claims["email"] != "a@example.com" {
+					t.Errorf("token = %+v", tok)
+				}
+			case err == nil || !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request)
+		wantErr bool
+	}{
+		{"valid", nil, false},
+		{"no subject", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{"email": "a@example.com"})
+		}, true},
+		{"unauthorized", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		}, true},
+		{"not an object", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, []string{"alice"})
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProvider(t)
+			p.userinfo = tt.handler
+			c := newTestClient(t, p, Config{})
+			info, err := c.UserInfo(context.Background(), "at")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("UserInfo = %+v, want error", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Subject != "alice" || !info.EmailVerified {
+				t.Errorf("info = %+v", info)
+			}
+			if r, _ := p.last("/userinfo"); r.Header.Get("Authorization") != "Bearer at" {
+				t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestSecretAssertionDefaults(t *testing.T) {
+	p := newProvider(t)
+	c := newTestClient(t, p, Config{
+		ClientID:        "com.example.app",
+		SecretAssertion: &oauth2.AssertionConfig{Key: ecKey, KeyID: "kid", Issuer: "TEAMID"},
+	})
+	if _, err := c.Exchange(context.Background(), "code", ""); err != nil {
+		t.Fatal(err)
+	}
+	_, form := p.last("/token")
+	jwt, err := parseJWT(form.Get("client_secret"))
+	if err != nil {
+		t.Fatalf("client_secret is not a JWT: %v", err)
+	}
+	if err := jwt.verify(&ecKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+		Sub string `json:"sub"`
+		Aud string `json:"aud"`
+	}
+	if err := json.Unmarshal(jwt.payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "TEAMID" || claims.Sub != "com.example.app" || claims.Aud != p.URL {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    map[string]interface{}
+		status      int
+		scopes      []string
+		wantRefresh string
+		wantErr     string
+	}{
+		{"rotated", map[string]interface{}{"access_token": "at2", "refresh_token": "rt2"}, http.StatusOK, nil, "rt2", ""},
+		{"not rotated keeps original", map[string]interface{}{"access_token": "at2"}, http.StatusOK, []string{"openid", "email"}, "rt", ""},
+		{"revoked", map[string]interface{}{"error": "invalid_grant"}, http.StatusBadRequest, nil, "", "invalid_grant"},
+		{"error with 200", map[string]interface{}{"error": "invalid_grant"}, http.StatusOK, nil, "", "invalid_grant"},
+		{"no access token", map[string]interface{}{"token_type": "Bearer"}, http.StatusOK, nil, "", "no access_token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProvider(t)
+			p.token = func(w http.ResponseWriter, r *http.Request) { writeJSON(w, tt.status, tt.response) }
+			c := newTestClient(t, p, Config{ClientSecret: "s"})
+
+			tok, err := c.Refresh(context.Background(), "rt", tt.scopes...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok.RefreshToken != tt.wantRefresh {
+				t.Errorf("RefreshToken = %q, want %q", tok.RefreshToken, tt.wantRefresh)
+			}
+			_, form := p.last("/token")
+			if form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != "rt" || form.Get("scope") != strings.Join(tt.scopes, " ") {
+				t.Errorf("token form = %v", form)
+			}
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	tests := []struct {
+		name      string
+		override  string
+		discovery bool
+		wantPath  string
+		wantErr   bool
+	}{
+		{"discovered endpoint", "", true, "/revoke", false},
+		{"override", "/auth/revoke", true, "/auth/revoke", false},
+		{"override without discovery", "/auth/revoke", false, "/auth/revoke", false},
+		{"no endpoint", "", false, "", true},
+		{"provider error", "/missing", true, "/missing", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProvider(t)
+			if !tt.discovery {
+				p.metadata = func(m map[string]interface{}) { delete(m, "revocation_endpoint") }
+			}
+			cfg := Config{ClientSecret: "s"}
+			if tt.override != "" {
+				cfg.RevocationURL = p.URL + tt.override
+			}
+			c := newTestClient(t, p, cfg)
+
+			err := c.Revoke(context.Background(), "rt", "refresh_token")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			r, form := p.last(tt.wantPath)
+			if r == nil {
+				t.Fatal("revocation endpoint not called")
+			}
+			if form.Get("token") != "rt" || form.Get("token_type_hint") != "refresh_token" {
+				t.Errorf("revocation form = %v", form)
+			}
+			if _, _, ok := r.BasicAuth(); !ok {
+				t.Error("revocation request not authenticated")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenNonce(t *testing.T) {
+	p := newProvider(t)
+	c := newTestClient(t, p, Config{})
+	token := func(nonce string) string {
+		m := map[string]interface{}{"iss": p.URL, "sub": "alice", "aud": "client", "exp": time.Now().Add(time.Hour).Unix()}
+		if nonce != "" {
+			m["nonce"] = nonce
+		}
+		return signJWT(t, rsaKey, "RS256", "rsa", m)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		nonce   string
+		wantErr bool
+	}{
+		{"match", token("n-123"), "n-123", false},
+		{"mismatch", token("n-123"), "n-456", true},
+		{"prefix", token("n-123"), "n-12", true},
+		{"missing claim", token(""), "n-123", true},
+		{"no expected nonce", token("n-123"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, err := c.VerifyIDTokenNonce(context.Background(), tt.raw, tt.nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tok.Nonce != tt.nonce {
+				t.Errorf("Nonce = %q", tok.Nonce)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256" // register SHA-256 for crypto.Hash
+	_ "crypto/sha512" // register SHA-384/512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// jwtHeader is the JOSE header of a compact JWS.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// parsedJWT is a compact JWS split into its parts.
+type parsedJWT struct {
+	header       jwtHeader
+	payload      []byte
+	signingInput string
+	signature    []byte
+}
+
+func parseJWT(raw string) (*parsedJWT, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT")
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT header: %w", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT payload: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT signature: %w", err)
+	}
+
+	jwt := &parsedJWT{
+		payload:      payload,
+		signingInput: parts[0] + "." + parts[1],
+		signature:    signature,
+	}
+	if err := json.Unmarshal(headerJSON, &jwt.header); err != nil {
+		return nil, fmt.Errorf("malformed JWT header: %w", err)
+	}
+	return jwt, nil
+}
+
+// algHash maps supported JWS algorithms to their hash.
+var algHash = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// esCurveBits maps ECDSA algorithms to the size of their required curve.
+var esCurveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+// verify checks the signature with key. The "none" algorithm and
+// algorithm/key type mismatches are rejected.
+func (j *parsedJWT) verify(key crypto.PublicKey) error {
+	hash, ok := algHash[j.header.Alg]
+	if !ok {
+		return fmt.Errorf("unsupported JWT algorithm %q", j.header.Alg)
+	}
+	h := hash.New()
+	h.Write([]byte(j.signingInput))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(j.header.Alg, "RS") {
+			return fmt.Errorf("algorithm %s does not match RSA key", j.header.Alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, j.signature); err != nil {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		bits := k.Curve.Params().BitSize
+		if esCurveBits[j.header.Alg] != bits {
+			return fmt.Errorf("algorithm %s does not match P-%d key", j.header.Alg, bits)
+		}
+		size := (bits + 7) / 8
+		if len(j.signature) != 2*size {
+			return errors.New("invalid JWT signature")
+		}
+		r := new(big.Int).SetBytes(j.signature[:size])
+		s := new(big.Int).SetBytes(j.signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %T", key)
+}
@@ -0,0 +1,124 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var (
+	rsaKey, _   = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _    = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384Key, _ = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+)
+
+// signJWT returns a compact JWS over claims. alg selects the hash; the
+// signature is produced with key regardless, so mismatches can be built.
+func signJWT(t *testing.T, key crypto.Signer, alg, kid string, claims interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	if alg == "none" || key == nil {
+		return input + "."
+	}
+
+	hash, ok := algHash[alg]
+	if !ok {
+		hash = crypto.SHA256
+	}
+	h := hash.New()
+	h.Write([]byte(input))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+	case *ecdsa.PrivateKey:
+		r, s, e := ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig, err = make([]byte, 2*size), e
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerify(t *testing.T) {
+	claims := map[string]string{"sub": "alice"}
+	tamper := func(raw string) string {
+		parts := strings.Split(raw, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory"}`))
+		return strings.Join(parts, ".")
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		key     crypto.PublicKey
+		wantErr string
+	}{
+		{"RS256", signJWT(t, rsaKey, "RS256", "k", claims), &rsaKey.PublicKey, ""},
+		{"RS512", signJWT(t, rsaKey, "RS512", "k", claims), &rsaKey.PublicKey, ""},
+		{"ES256", signJWT(t, ecKey, "ES256", "k", claims), &ecKey.PublicKey, ""},
+		{"ES384", signJWT(t, ec384Key, "ES384", "k", claims), &ec384Key.PublicKey, ""},
+		{"alg none", signJWT(t, nil, "none", "k", claims), &rsaKey.PublicKey, "unsupported JWT algorithm"},
+		{"alg HS256 with RSA public key", signJWT(t, rsaKey, "HS256", "k", claims), &rsaKey.PublicKey, "unsupported JWT algorithm"},
+		{"ES256 header on RSA key", signJWT(t, rsaKey, "ES256", "k", claims), &rsaKey.PublicKey, "does not match RSA key"},
+		{"RS256 header on EC key", signJWT(t, ecKey, "RS256", "k", claims), &ecKey.PublicKey, "does not match P-256 key"},
+		{"ES384 header on P-256 key", signJWT(t, ecKey, "ES384", "k", claims), &ecKey.PublicKey, "does not match P-256 key"},
+		{"tampered RS256 payload", tamper(signJWT(t, rsaKey, "RS256", "k", claims)), &rsaKey.PublicKey, "invalid JWT signature"},
+		{"tampered ES256 payload", tamper(signJWT(t, ecKey, "ES256", "k", claims)), &ecKey.PublicKey, "invalid JWT signature"},
+		{"wrong RSA key", signJWT(t, rsaKey, "RS256", "k", claims), &mustRSA(t).PublicKey, "invalid JWT signature"},
+		{"short EC signature", shorten(signJWT(t, ecKey, "ES256", "k", claims)), &ecKey.PublicKey, "invalid JWT signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwt, err := parseJWT(tt.raw)
+			if err == nil {
+				err = jwt.verify(tt.key)
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("verify: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// shorten drops the last three bytes of the signature.
+func shorten(raw string) string {
+	return raw[:len(raw)-4]
+}
+
+func mustRSA(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestParseJWTMalformed(t *testing.T) {
+	for _, raw := range []string{"", "a.b", "a.b.c.d", "!!.e30.", "e30.!!.", "e30.e30.!!", "bm90IGpzb24.e30."} {
+		if _, err := parseJWT(raw); err == nil {
+			t.Errorf("parseJWT(%q) succeeded", raw)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package oauth2
+
+import (
+	"net/http"
+
+	"github.com/PhilipKram/gms-foundation/pkg/resilience"
+)
+
+// RetryPolicy configures retries of transient provider failures. It is
+// resilience.RetryPolicy.
+type RetryPolicy = resilience.RetryPolicy
+
+// RetryTransport is resilience.RetryTransport.
+type RetryTransport = resilience.RetryTransport
+
+// WithRetry returns a copy of client whose transport retries transient
+// failures according to policy. Token requests are POSTs and therefore
+// only retried when the provider could not be reached, so single-use
+// grants such as authorization codes and refresh tokens are never
+// redeemed twice. A nil client means DefaultHTTPClient.
+func WithRetry(client *http.Client, policy RetryPolicy) *http.Client {
+	if client == nil {
+		client = DefaultHTTPClient
+	}
+	c := *client
+	c.Transport = &RetryTransport{Base: client.Transport, Policy: policy}
+	return &c
+}
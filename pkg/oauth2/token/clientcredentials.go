@@ -0,0 +1,158 @@
+// Package token implements provider-agnostic OAuth2 grants that do not
+// involve a browser redirect: client credentials and device authorization.
+package token
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+// DefaultExpiryDelta is how long before expiry a cached token is renewed.
+const DefaultExpiryDelta = 30 * time.Second
+
+// ClientCredentialsConfig configures a ClientCredentials source.
+type ClientCredentialsConfig struct {
+	TokenURL string
+	Auth     oauth2.ClientAuth
+	Scopes   []string
+	// Params adds provider-specific form values such as audience.
+	Params url.Values
+	// HTTPClient defaults to oauth2.DefaultHTTPClient.
+	HTTPClient *http.Client
+	// ExpiryDelta defaults to DefaultExpiryDelta.
+	ExpiryDelta time.Duration
+}
+
+// ClientCredentials obtains service-to-service tokens with the
+// client_credentials grant and caches them until shortly before expiry.
+// It is safe for concurrent use; concurrent callers share one request.
+type ClientCredentials struct {
+	cfg ClientCredentialsConfig
+
+	mu      sync.Mutex
+	token   *oauth2.TokenResponse
+	expires time.Time
+	fetch   *tokenFetch
+}
+
+// tokenFetch is a token request shared by concurrent callers. token and
+// err are set before done is closed.
+type tokenFetch struct {
+	done  chan struct{}
+	token *oauth2.TokenResponse
+	err   error
+}
+
+// NewClientCredentials returns a ClientCredentials source for cfg.
+func NewClientCredentials(cfg ClientCredentialsConfig) (*ClientCredentials, error) {
+	if cfg.TokenURL == "" || cfg.Auth.ClientID == "" {
+		return nil, errors.New("token: token URL and client ID are required")
+	}
+	if cfg.ExpiryDelta <= 0 {
+		cfg.ExpiryDelta = DefaultExpiryDelta
+	}
+	return &ClientCredentials{cfg: cfg}, nil
+}
+
+// Token returns a cached token, requesting a new one when none is cached
+// or it is about to expire. Tokens without expires_in are reused until
+// Invalidate is called.
+//
+// The request is not bound to the cancellation of the caller that started
+// it, so callers waiting for the same token are not failed when that one
+// gives up; each caller stops waiting when its own ctx is done. The request
+// itself is bounded by oauth2.DefaultTimeout.
+func (s *ClientCredentials) Token(ctx context.Context) (*oauth2.TokenResponse, error) {
+	s.mu.Lock()
+	if s.token != nil && (s.expires.IsZero() || time.Now().Before(s.expires.Add(-s.cfg.ExpiryDelta))) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	f := s.fetch
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		s.fetch = f
+		go s.request(context.WithoutCancel(ctx), f)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// request performs f and caches its token.
+func (s *ClientCredentials) request(ctx context.Context, f *tokenFetch) {
+	ctx, cancel := context.WithTimeout(ctx, oauth2.DefaultTimeout)
+	defer cancel()
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	for k, vs := range s.cfg.Params {
+		form[k] = vs
+	}
+	issued := time.Now()
+	f.token, f.err = oauth2.PostToken(ctx, s.cfg.HTTPClient, s.cfg.TokenURL, s.cfg.Auth, form)
+
+	s.mu.Lock()
+	if f.err == nil {
+		s.token = f.token
+		s.expires = f.token.Expiry(issued)
+	}
+	s.fetch = nil
+	s.mu.Unlock()
+	close(f.done)
+}
+
+// Invalidate drops the cached token, e.g. after a resource server rejected
+// it.
+func (s *ClientCredentials) Invalidate() {
+	s.mu.Lock()
+	s.token = nil
+	s.mu.Unlock()
+}
+
+// Transport is an http.RoundTripper that authenticates requests with a
+// bearer token from Source.
+type Transport struct {
+	Source *ClientCredentials
+	// Base defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token(req.Context())
+	if err != nil {
+		// RoundTrippers must close the body even on errors.
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	resp, err := base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		t.Source.Invalidate()
+	}
+	return resp, err
+}
@@ -0,0 +1,220 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+func tokenServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestClientCredentialsCaches(t *testing.T) {
+	var calls atomic.Int32
+	srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "client_credentials" {
+			t.Errorf("grant_type = %q", got)
+		}
+		if got := r.PostForm.Get("scope"); got != "read write" {
+			t.Errorf("scope = %q", got)
+		}
+		if got := r.PostForm.Get("audience"); got != "api" {
+			t.Errorf("audience = %q", got)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "at", "token_type": "Bearer", "expires_in": 3600})
+	})
+
+	cc, err := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL: srv.URL,
+		Auth:     oauth2.ClientAuth{ClientID: "svc", ClientSecret: "secret"},
+		Scopes:   []string{"read", "write"},
+		Params:   map[string][]string{"audience": {"api"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		tok, err := cc.Token(context.Background())
+		if err != nil || tok.AccessToken != "at" {
+			t.Fatalf("Token = %v, %v", tok, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("token requests = %d, want 1", calls.Load())
+	}
+
+	cc.Invalidate()
+	if _, err := cc.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("token requests after Invalidate = %d, want 2", calls.Load())
+	}
+}
+
+func TestClientCredentialsRenewsBeforeExpiry(t *testing.T) {
+	var calls atomic.Int32
+	srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "at", "expires_in": 10})
+	})
+	cc, _ := NewClientCredentials(ClientCredentialsConfig{
+		TokenURL:    srv.URL,
+		Auth:        oauth2.ClientAuth{ClientID: "svc"},
+		ExpiryDelta: 30 * time.Second,
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := cc.Token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("token requests = %d, want 2 for a token inside ExpiryDelta", calls.Load())
+	}
+}
+
+func TestClientCredentialsSharesRequest(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "at", "expires_in": 3600})
+	})
+	cc, _ := NewClientCredentials(ClientCredentialsConfig{TokenURL: srv.URL, Auth: oauth2.ClientAuth{ClientID: "svc"}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cc.Token(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("token requests = %d, want 1", calls.Load())
+	}
+}
+
+func TestClientCredentialsWaitHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "at", "expires_in": 3600})
+	})
+	defer close(release)
+	cc, _ := NewClientCredentials(ClientCredentialsConfig{TokenURL: srv.URL, Auth: oauth2.ClientAuth{ClientID: "svc"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := cc.Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Token = %v, want deadline exceeded", err)
+	}
+
+	// The pending request must not hold later callers past their deadline.
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel2()
+	start := time.Now()
+	if _, err := cc.Token(ctx2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second Token = %v, want deadline exceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("second caller waited past its deadline")
+	}
+}
+
+func TestClientCredentialsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   map[string]interface{}
+		code   string
+	}{
+		{"invalid client", http.StatusUnauthorized, map[string]interface{}{"error": "invalid_client"}, "invalid_client"},
+		{"no access token", http.StatusOK, map[string]interface{}{"token_type": "Bearer"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, tt.body)
+			})
+			cc, _ := NewClientCredentials(ClientCredentialsConfig{TokenURL: srv.URL, Auth: oauth2.ClientAuth{ClientID: "svc"}})
+			_, err := cc.Token(context.Background())
+			if err == nil {
+				t.Fatal("Token succeeded")
+			}
+			var oauthErr *oauth2.OAuthError
+			if tt.code != "" && (!errors.As(err, &oauthErr) || oauthErr.Code != tt.code) {
+				t.Errorf("error = %v, want OAuthError %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestNewClientCredentialsValidates(t *testing.T) {
+	if _, err := NewClientCredentials(ClientCredentialsConfig{Auth: oauth2.ClientAuth{ClientID: "svc"}}); err == nil {
+		t.Error("missing token URL accepted")
+	}
+	if _, err := NewClientCredentials(ClientCredentialsConfig{TokenURL: "https://idp.example/token"}); err == nil {
+		t.Error("missing client ID accepted")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "at", "expires_in": 3600})
+	})
+	var gotAuth string
+	api := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	cc, _ := NewClientCredentials(ClientCredentialsConfig{TokenURL: srv.URL, Auth: oauth2.ClientAuth{ClientID: "svc"}})
+	client := &http.Client{Transport: &Transport{Source: cc}}
+
+	req, _ := http.NewRequest(http.MethodGet, api.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if gotAuth != "Bearer at" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("caller's request was modified")
+	}
+	cc.mu.Lock()
+	cached := cc.token
+	cc.mu.Unlock()
+	if cached != nil {
+		t.Error("token still cached after 401")
+	}
+}
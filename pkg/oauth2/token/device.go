@@ -0,0 +1,118 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+// Polling intervals defined by RFC 8628.
+const (
+	defaultPollInterval = 5 * time.Second
+	slowDownIncrement   = 5 * time.Second
+)
+
+// ErrDeviceAuthExpired is returned when the user did not approve the
+// device before the device code expired.
+var ErrDeviceAuthExpired = errors.New("device authorization expired")
+
+// ErrAccessDenied is returned when the user declined the authorization.
+var ErrAccessDenied = errors.New("device authorization denied")
+
+// DeviceConfig configures the device authorization grant (RFC 8628).
+type DeviceConfig struct {
+	DeviceAuthURL string
+	TokenURL      string
+	Auth          oauth2.ClientAuth
+	Scopes        []string
+	// HTTPClient defaults to oauth2.DefaultHTTPClient.
+	HTTPClient *http.Client
+}
+
+// DeviceAuthorization is the device authorization response. UserCode and
+// VerificationURI are shown to the user.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// StartDeviceAuth initiates the device flow.
+func StartDeviceAuth(ctx context.Context, cfg DeviceConfig) (*DeviceAuthorization, error) {
+	form := url.Values{}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	req, err := oauth2.NewFormRequest(ctx, cfg.DeviceAuthURL, cfg.Auth, form)
+	if err != nil {
+		return nil, err
+	}
+	var auth DeviceAuthorization
+	if err := oauth2.Do(cfg.HTTPClient, req, &auth); err != nil {
+		return nil, err
+	}
+	if auth.DeviceCode == "" || auth.UserCode == "" {
+		return nil, errors.New("device authorization response is incomplete")
+	}
+	return &auth, nil
+}
+
+// PollDeviceToken polls the token endpoint until the user approves the
+// device, the code expires or ctx is done. The interval grows by five
+// seconds on every slow_down response.
+func PollDeviceToken(ctx context.Context, cfg DeviceConfig, auth *DeviceAuthorization) (*oauth2.TokenResponse, error) {
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	var deadline time.Time
+	if auth.ExpiresIn > 0 {
+		deadline = time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	}
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {auth.DeviceCode},
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, ErrDeviceAuthExpired
+		}
+
+		token, err := oauth2.PostToken(ctx, cfg.HTTPClient, cfg.TokenURL, cfg.Auth, form)
+		if err == nil {
+			return token, nil
+		}
+		var oauthErr *oauth2.OAuthError
+		if !errors.As(err, &oauthErr) {
+			return nil, err
+		}
+		switch oauthErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += slowDownIncrement
+		case "access_denied":
+			return nil, ErrAccessDenied
+		case "expired_token":
+			return nil, ErrDeviceAuthExpired
+		default:
+			return nil, err
+		}
+		timer.Reset(interval)
+	}
+}
@@ -0,0 +1,88 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PhilipKram/gms-foundation/pkg/oauth2"
+)
+
+func TestStartDeviceAuth(t *testing.T) {
+	srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if got := r.PostForm.Get("scope"); got != "openid" {
+			t.Errorf("scope = %q", got)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code": "dc", "user_code": "ABCD-EFGH", "verification_uri": "https://idp.example/device", "expires_in": 600,
+		})
+	})
+	auth, err := StartDeviceAuth(context.Background(), DeviceConfig{DeviceAuthURL: srv.URL, Auth: oauth2.ClientAuth{ClientID: "tv"}, Scopes: []string{"openid"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.DeviceCode != "dc" || auth.UserCode != "ABCD-EFGH" {
+		t.Errorf("auth = %+v", auth)
+	}
+
+	incomplete := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"verification_uri": "https://idp.example/device"})
+	})
+	if _, err := StartDeviceAuth(context.Background(), DeviceConfig{DeviceAuthURL: incomplete.URL, Auth: oauth2.ClientAuth{ClientID: "tv"}}); err == nil {
+		t.Error("incomplete response accepted")
+	}
+}
+
+func TestPollDeviceToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string // error codes, "" for success
+		wantErr error
+	}{
+		{"approved after pending", []string{"authorization_pending", ""}, nil},
+		{"denied", []string{"access_denied"}, ErrAccessDenied},
+		{"expired", []string{"expired_token"}, ErrDeviceAuthExpired},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls atomic.Int32
+			srv := tokenServer(t, func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				if got := r.PostForm.Get("device_code"); got != "dc" {
+					t.Errorf("device_code = %q", got)
+				}
+				code := tt.answers[int(calls.Add(1))-1]
+				if code == "" {
+					writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "at"})
+					return
+				}
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": code})
+			})
+			cfg := DeviceConfig{TokenURL: srv.URL, Auth: oauth2.ClientAuth{ClientID: "tv"}}
+			tok, err := PollDeviceToken(context.Background(), cfg, &DeviceAuthorization{DeviceCode: "dc", Interval: 1, ExpiresIn: 60})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PollDeviceToken = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && tok.AccessToken != "at" {
+				t.Errorf("access token = %q", tok.AccessToken)
+			}
+			if int(calls.Load()) != len(tt.answers) {
+				t.Errorf("polls = %d, want %d", calls.Load(), len(tt.answers))
+			}
+		})
+	}
+}
+
+func TestPollDeviceTokenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := PollDeviceToken(ctx, DeviceConfig{TokenURL: "http://127.0.0.1:1"}, &DeviceAuthorization{DeviceCode: "dc"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("PollDeviceToken = %v, want context.Canceled", err)
+	}
+}
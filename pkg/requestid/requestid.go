@@ -0,0 +1,23 @@
+// Package requestid carries request IDs in contexts without depending on
+// a web framework, so outbound clients can forward the ID of the request
+// they serve.
+package requestid
+
+import "context"
+
+// Header carries the request ID on requests and responses.
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored by NewContext, or an empty
+// string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
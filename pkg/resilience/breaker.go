@@ -0,0 +1,245 @@
+// Package resilience provides fault-tolerance primitives for inbound and
+// outbound HTTP traffic.
+package resilience
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrOpen is returned when a call is rejected by an open circuit breaker.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker.
+type State int
+
+// Breaker states.
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+// String returns the lower-case state name.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig configures a Breaker. Zero values select the defaults noted
+// on each field.
+type BreakerConfig struct {
+	Name string
+	// Window over which the failure rate is measured. Defaults to 1m.
+	Window time.Duration
+	// MinRequests in a window before the failure rate is evaluated.
+	// Defaults to 10.
+	MinRequests int
+	// FailureRate in (0, 1] at which the breaker opens. Defaults to 0.5.
+	FailureRate float64
+	// OpenTimeout before an open breaker lets probes through. Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of successful probes required to
+	// close the breaker again. Defaults to 1.
+	HalfOpenRequests int
+	// OnStateChange is invoked, without locks held, on every transition.
+	OnStateChange func(name string, from, to State)
+}
+
+// Breaker is a failure-rate based circuit breaker.
+type Breaker struct {
+	cfg BreakerConfig
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	inFlight    int
+	successes   int
+}
+
+// NewBreaker returns a closed Breaker.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.FailureRate <= 0 || cfg.FailureRate > 1 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &Breaker{cfg: cfg, windowStart: time.Now()}
+}
+
+// Name returns the configured breaker name.
+func (b *Breaker) Name() string {
+	return b.cfg.Name
+}
+
+// State returns the current state, promoting an expired open breaker to
+// half-open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	from, to := b.advance(time.Now())
+	state := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return state
+}
+
+// RetryAfter returns how long until an open breaker admits probes again.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateOpen {
+		return 0
+	}
+	return time.Until(b.openedAt.Add(b.cfg.OpenTimeout))
+}
+
+// Allow reserves a call. It returns ErrOpen when the call must be rejected;
+// otherwise the caller must invoke done with the call's outcome.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	finish, err := b.reserve()
+	if err != nil {
+		return nil, err
+	}
+	return func(success bool) { finish(success, true) }, nil
+}
+
+// reserve is Allow, but its finish func can also release the call without
+// counting it when counted is false, e.g. for calls the caller canceled.
+func (b *Breaker) reserve() (finish func(success, counted bool), err error) {
+	b.mu.Lock()
+	from, to := b.advance(time.Now())
+	switch b.state {
+	case StateOpen:
+		b.mu.Unlock()
+		b.notify(from, to)
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.inFlight >= b.cfg.HalfOpenRequests {
+			b.mu.Unlock()
+			b.notify(from, to)
+			return nil, ErrOpen
+		}
+		b.inFlight++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(from, to)
+
+	var once sync.Once
+	return func(success, counted bool) {
+		once.Do(func() { b.record(generation, success, counted) })
+	}, nil
+}
+
+// Execute runs fn if the breaker admits it, counting a non-nil error as a
+// failure.
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err == nil)
+	return err
+}
+
+func (b *Breaker) record(generation uint64, success, counted bool) {
+	b.mu.Lock()
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	if !counted {
+		if b.state == StateHalfOpen {
+			b.inFlight--
+		}
+		b.mu.Unlock()
+		return
+	}
+
+	from, to := b.state, b.state
+	now := time.Now()
+	switch b.state {
+	case StateClosed:
+		if now.Sub(b.windowStart) > b.cfg.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if !success {
+			b.failures++
+		}
+		if b.requests >= b.cfg.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.cfg.FailureRate {
+			to = b.transition(StateOpen, now)
+		}
+	case StateHalfOpen:
+		b.inFlight--
+		if !success {
+			to = b.transition(StateOpen, now)
+		} else if b.successes++; b.successes >= b.cfg.HalfOpenRequests {
+			to = b.transition(StateClosed, now)
+		}
+	}
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// advance moves an open breaker whose timeout elapsed to half-open.
+// Callers must hold b.mu.
+func (b *Breaker) advance(now time.Time) (from, to State) {
+	from, to = b.state, b.state
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		to = b.transition(StateHalfOpen, now)
+	}
+	return from, to
+}
+
+// transition resets the counters for state. Callers must hold b.mu.
+func (b *Breaker) transition(state State, now time.Time) State {
+	b.state = state
+	b.generation++
+	b.windowStart, b.requests, b.failures = now, 0, 0
+	b.inFlight, b.successes = 0, 0
+	if state == StateOpen {
+		b.openedAt = now
+	}
+	return state
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from == to {
+		return
+	}
+	log.Warn().
+		Str("breaker", b.cfg.Name).
+		Str("from", from.String()).
+		Str("to", to.String()).
+		Msg("Circuit breaker state changed")
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.cfg.Name, from, to)
+	}
+}
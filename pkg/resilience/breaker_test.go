@@ -0,0 +1,142 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreakerStates(t *testing.T) {
+	var changes []string
+	b := NewBreaker(BreakerConfig{
+		Name:        "upstream",
+		MinRequests: 4,
+		FailureRate: 0.5,
+		OpenTimeout: 20 * time.Millisecond,
+		OnStateChange: func(name string, from, to State) {
+			changes = append(changes, from.String()+">"+to.String())
+		},
+	})
+	fail := errors.New("boom")
+
+	for _, err := range []error{nil, fail, nil, fail} {
+		_ = b.Execute(func() error { return err })
+	}
+	if got := b.State(); got != StateOpen {
+		t.Fatalf("state = %s after 50%% failures, want open", got)
+	}
+	if err := b.Execute(func() error { t.Error("call ran while open"); return nil }); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Execute while open = %v, want ErrOpen", err)
+	}
+	if b.RetryAfter() <= 0 {
+		t.Error("RetryAfter is not positive while open")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe rejected after OpenTimeout: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("second concurrent probe = %v, want ErrOpen", err)
+	}
+	done(true)
+	if got := b.State(); got != StateClosed {
+		t.Errorf("state = %s after successful probe, want closed", got)
+	}
+
+	want := "closed>open,open>half-open,half-open>closed"
+	if got := strings.Join(changes, ","); got != want {
+		t.Errorf("state changes = %s, want %s", got, want)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b := NewBreaker(BreakerConfig{MinRequests: 1, OpenTimeout: time.Millisecond})
+	_ = b.Execute(func() error { return errors.New("boom") })
+	time.Sleep(2 * time.Millisecond)
+	_ = b.Execute(func() error { return errors.New("boom") })
+	if got := b.State(); got != StateOpen {
+		t.Errorf("state = %s after failed probe, want open", got)
+	}
+}
+
+// bodyRecorder records whether the request body was closed.
+type bodyRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (b *bodyRecorder) Close() error {
+	b.closed = true
+	return nil
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		outcome   func(*http.Request) (*http.Response, error)
+		wantState State
+	}{
+		{"success", func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}, StateClosed},
+		{"client error is a success", func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody}, nil
+		}, StateClosed},
+		{"server error", func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}, nil
+		}, StateOpen},
+		{"transport error", func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}, StateOpen},
+		{"canceled is not counted", func(*http.Request) (*http.Response, error) {
+			return nil, context.Canceled
+		}, StateClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(BreakerConfig{MinRequests: 2})
+			tr := NewTransport(b, roundTripFunc(tt.outcome))
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest(http.MethodGet, "http://example.test/", nil)
+				if resp, err := tr.RoundTrip(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("state = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestTransportOpenClosesBody(t *testing.T) {
+	b := NewBreaker(BreakerConfig{MinRequests: 1})
+	_ = b.Execute(func() error { return errors.New("boom") })
+
+	called := false
+	tr := NewTransport(b, roundTripFunc(func(*http.Request) (*http.Response, error) {
+		called = true
+		return nil, nil
+	}))
+	body := &bodyRecorder{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequest(http.MethodPost, "http://example.test/", body)
+	if _, err := tr.RoundTrip(req); !errors.Is(err, ErrOpen) {
+		t.Fatalf("RoundTrip = %v, want ErrOpen", err)
+	}
+	if called {
+		t.Error("upstream was contacted while open")
+	}
+	if !body.closed {
+		t.Error("request body was not closed")
+	}
+}
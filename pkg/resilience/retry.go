@@ -0,0 +1,153 @@
+package resilience
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxDrainSize caps how much of a discarded response is read so that its
+// connection can be reused.
+const maxDrainSize = 1 << 20
+
+// RetryPolicy configures retries of transient failures.
+type RetryPolicy struct {
+	// Attempts is the total number of tries, including the first.
+	// Defaults to 3.
+	Attempts int
+	// BaseDelay is the backoff before the second try; it doubles on every
+	// further try and is fully jittered. Defaults to 200ms.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After beyond it ends the retries.
+	// Defaults to 5s.
+	MaxDelay time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = 3
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 200 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 5 * time.Second
+	}
+	return p
+}
+
+// backoff returns the jittered delay after the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// RetryTransport is an http.RoundTripper that retries transient failures
+// with jittered exponential backoff, honouring Retry-After. Idempotent
+// requests, and requests carrying an Idempotency-Key header, are retried
+// on connection errors, 5xx and 429. Other requests, such as single-use
+// OAuth grant exchanges, are only retried when the connection could not
+// be established, since the server never saw them. Requests whose body
+// cannot be replayed are not retried.
+type RetryTransport struct {
+	// Base defaults to http.DefaultTransport.
+	Base   http.RoundTripper
+	Policy RetryPolicy
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	policy := t.Policy.withDefaults()
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := base.RoundTrip(req)
+		if attempt >= policy.Attempts || ctx.Err() != nil || !retryable(req, resp, err) {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := policy.backoff(attempt)
+		if resp != nil {
+			if after := ParseRetryAfter(resp.Header); after > 0 {
+				if after > policy.MaxDelay {
+					return resp, err
+				}
+				delay = after
+			}
+			// Drain so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if !idempotent(req) {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented ||
+		resp.StatusCode == http.StatusTooManyRequests
+}
+
+// idempotent reports whether req may be repeated without changing the
+// outcome.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// ParseRetryAfter returns the delay of a Retry-After header in either
+// delta-seconds or HTTP-date form, or zero.
+func ParseRetryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package resilience
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedTransport answers the n-th attempt with the n-th outcome,
+// repeating the last one.
+type scriptedTransport struct {
+	outcomes []func() (*http.Response, error)
+	calls    int
+	bodies   []string
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		s.bodies = append(s.bodies, string(b))
+	}
+	i := s.calls
+	if i >= len(s.outcomes) {
+		i = len(s.outcomes) - 1
+	}
+	s.calls++
+	return s.outcomes[i]()
+}
+
+func status(code int) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return &http.Response{StatusCode: code, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+}
+
+func fail(err error) func() (*http.Response, error) {
+	return func() (*http.Response, error) { return nil, err }
+}
+
+var (
+	dialErr  = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	resetErr = &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+)
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		header    string
+		outcomes  []func() (*http.Response, error)
+		wantCalls int
+	}{
+		{"GET 503 then 200", http.MethodGet, "", []func() (*http.Response, error){status(503), status(200)}, 2},
+		{"GET keeps failing", http.MethodGet, "", []func() (*http.Response, error){status(502)}, 3},
+		{"GET 501 not retried", http.MethodGet, "", []func() (*http.Response, error){status(501)}, 1},
+		{"GET 429", http.MethodGet, "", []func() (*http.Response, error){status(429), status(200)}, 2},
+		{"GET 400 not retried", http.MethodGet, "", []func() (*http.Response, error){status(400)}, 1},
+		{"GET reset", http.MethodGet, "", []func() (*http.Response, error){fail(resetErr), status(200)}, 2},
+		{"POST 503 not retried", http.MethodPost, "", []func() (*http.Response, error){status(503)}, 1},
+		{"POST reset not retried", http.MethodPost, "", []func() (*http.Response, error){fail(resetErr)}, 1},
+		{"POST dial error", http.MethodPost, "", []func() (*http.Response, error){fail(dialErr), status(200)}, 2},
+		{"POST with idempotency key", http.MethodPost, "k1", []func() (*http.Response, error){status(503), status(200)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &scriptedTransport{outcomes: tt.outcomes}
+			rt := &RetryTransport{Base: base, Policy: RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond}}
+			req, _ := http.NewRequest(tt.method, "http://example.test/token", strings.NewReader("grant_type=authorization_code"))
+			if tt.header != "" {
+				req.Header.Set("Idempotency-Key", tt.header)
+			}
+			resp, err := rt.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if base.calls != tt.wantCalls {
+				t.Errorf("attempts = %d, want %d", base.calls, tt.wantCalls)
+			}
+			for i, b := range base.bodies {
+				if b != "grant_type=authorization_code" {
+					t.Errorf("attempt %d sent body %q", i+1, b)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportRetryAfterBeyondMaxDelay(t *testing.T) {
+	base := &scriptedTransport{outcomes: []func() (*http.Response, error){func() (*http.Response, error) {
+		resp, _ := status(503)()
+		resp.Header.Set("Retry-After", "120")
+		return resp, nil
+	}}}
+	rt := &RetryTransport{Base: base, Policy: RetryPolicy{Attempts: 3, MaxDelay: time.Second}}
+	req, _ := http.NewRequest(http.MethodGet, "http://example.test/", nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if base.calls != 1 {
+		t.Errorf("attempts = %d, want 1", base.calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"5", 5 * time.Second, 5 * time.Second},
+		{"-1", 0, 0},
+		{"soon", 0, 0},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set("Retry-After", tt.value)
+		if got := ParseRetryAfter(h); got < tt.min || got > tt.max {
+			t.Errorf("ParseRetryAfter(%q) = %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper guarded by a circuit breaker. Transport
+// errors and 5xx responses count as failures; requests canceled by the
+// caller are not counted at all.
+type Transport struct {
+	Breaker *Breaker
+	// Base is the wrapped transport. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// NewTransport wraps base with breaker.
+func NewTransport(breaker *Breaker, base http.RoundTripper) *Transport {
+	return &Transport{Breaker: breaker, Base: base}
+}
+
+// RoundTrip executes the request unless the breaker is open, in which case
+// ErrOpen is returned without contacting the upstream.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	finish, err := t.Breaker.reserve()
+	if err != nil {
+		// RoundTrippers must close the body even on errors.
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	finish(err == nil && resp.StatusCode < http.StatusInternalServerError, !errors.Is(err, context.Canceled))
+	return resp, err
+}
@@ -13,7 +13,10 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/PhilipKram/gms-foundation/pkg/healthcheck"
+	"github.com/PhilipKram/gms-foundation/pkg/logger"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
@@ -23,8 +26,16 @@ type ConfigSchema struct {
 	Port       string
 	AccessLog  bool `yaml:"accessLog"`
 	Production bool
+	// DrainDelay is how long Start keeps serving the server returned by
+	// Setup after a termination signal with readiness failing, so load
+	// balancers stop routing new requests before the listener closes.
+	DrainDelay time.Duration `yaml:"drainDelay"`
 }
 
+// drainDelays holds the DrainDelay of each server built by Setup, keyed by
+// *http.Server, so that servers of one process keep their own delay.
+var drainDelays sync.Map
+
 // Define a buffer pool for efficient buffer reuse
 var bufferPool = &sync.Pool{
 	New: func() interface{} {
@@ -41,20 +52,35 @@ func Setup(serverConfig ConfigSchema) (*http.Server, *gin.Engine) {
 	}
 
 	router := gin.New()
+	// Forwarding headers are only believed from proxies configured through
+	// middleware.ClientIP.
+	_ = router.SetTrustedProxies(nil)
 	if serverConfig.AccessLog {
 		router.Use(gin.Logger())
 	}
 	router.Use(gin.Recovery())
 
 	srv := &http.Server{
-		Addr:    ":" + serverConfig.Port,
-		Handler: router,
+		Addr:     ":" + serverConfig.Port,
+		Handler:  router,
+		ErrorLog: logger.StdLogger(nil, zerolog.WarnLevel, "http.Server"),
 	}
+	drainDelays.Store(srv, serverConfig.DrainDelay)
 
 	return srv, router
 }
 
+// Start serves srv until SIGINT or SIGTERM, then fails readiness, keeps
+// serving for the DrainDelay passed to Setup and shuts srv down.
 func Start(srv *http.Server) {
+	drainDelay, _ := drainDelays.Load(srv)
+	d, _ := drainDelay.(time.Duration)
+	StartWithDrain(srv, d)
+}
+
+// StartWithDrain is Start with an explicit drain delay, e.g. for servers
+// not built by Setup.
+func StartWithDrain(srv *http.Server, drainDelay time.Duration) {
 	// Initializing the server in a goroutine so that
 	// it won't block the graceful shutdown handling below
 	go func() {
@@ -71,15 +97,29 @@ func Start(srv *http.Server) {
 	<-quit
 	log.Info().Msg("Shutting down server...")
 
+	// Fail readiness while liveness stays up, and give load balancers time
+	// to notice before connections are refused.
+	healthcheck.Shutdown()
+	if drainDelay > 0 {
+		log.Info().Dur("delay", drainDelay).Msg("Draining before shutdown")
+		time.Sleep(drainDelay)
+	}
+
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal().Msgf("Server forced to shutdown: %s", err)
+	err := srv.Shutdown(ctx)
+	cancel()
+	if err != nil {
+		log.Error().Err(err).Msg("Server forced to shutdown")
+	} else {
+		log.Info().Msg("Server exiting")
+	}
+	// Flush async buffers and close log files; nothing can be logged after.
+	_ = logger.Close()
+	if err != nil {
+		os.Exit(1)
 	}
-
-	log.Info().Msg("Server exiting")
 }
 
 func HandleRequestBody(c *gin.Context, contentType string, out interface{}) error {
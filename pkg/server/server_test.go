@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetupStoresDrainDelay(t *testing.T) {
+	tests := []time.Duration{0, 3 * time.Second, time.Minute}
+	for _, want := range tests {
+		srv, _ := Setup(ConfigSchema{Port: "0", DrainDelay: want})
+		got, ok := drainDelays.Load(srv)
+		if !ok || got.(time.Duration) != want {
+			t.Errorf("drain delay = %v, want %s", got, want)
+		}
+	}
+}
+
+func TestSetupIgnoresForwardingHeaders(t *testing.T) {
+	_, router := Setup(ConfigSchema{Port: "0"})
+	router.GET("/ip", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	req.Header.Set("X-Forwarded-For", "192.0.2.7")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Body.String(); got != "198.51.100.1" {
+		t.Errorf("ClientIP = %q, want the peer address", got)
+	}
+}
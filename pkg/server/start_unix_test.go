@@ -0,0 +1,41 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PhilipKram/gms-foundation/pkg/healthcheck"
+)
+
+func TestStartDrainsBeforeShutdown(t *testing.T) {
+	const delay = 200 * time.Millisecond
+	srv, _ := Setup(ConfigSchema{Port: "0", DrainDelay: delay})
+
+	done := make(chan time.Time)
+	go func() {
+		Start(srv)
+		done <- time.Now()
+	}()
+	// Give Start time to install its signal handler.
+	time.Sleep(50 * time.Millisecond)
+	sent := time.Now()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case stopped := <-done:
+		if stopped.Sub(sent) < delay {
+			t.Errorf("Start returned after %s, before the %s drain delay", stopped.Sub(sent), delay)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after SIGTERM")
+	}
+	if healthcheck.Ready() {
+		t.Error("readiness still up after shutdown")
+	}
+}
@@ -0,0 +1,180 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// ContextKey is the Gin context key holding the request's *Session.
+const ContextKey = "sessions.session"
+
+// Config configures the session Middleware.
+type Config struct {
+	// CookieName defaults to "session".
+	CookieName   string
+	CookiePath   string
+	CookieDomain string
+	CookieSecure bool
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+	// IdleTimeout expires sessions without activity. Defaults to 30m.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout caps the lifetime of a session regardless of
+	// activity. Defaults to 24h.
+	AbsoluteTimeout time.Duration
+}
+
+// Middleware loads the session before the handlers run and saves it right
+// before the response headers are written.
+func Middleware(store Store, cfg Config) gin.HandlerFunc {
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session"
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Minute
+	}
+	if cfg.AbsoluteTimeout <= 0 {
+		cfg.AbsoluteTimeout = 24 * time.Hour
+	}
+	// Activity is only persisted at this granularity to avoid a write per
+	// request.
+	touchInterval := cfg.IdleTimeout / 10
+	if touchInterval > time.Minute {
+		touchInterval = time.Minute
+	}
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		sess := load(c, store, &cfg, now)
+		if !sess.isNew && now.Sub(sess.data.LastSeen) > touchInterval {
+			sess.data.LastSeen = now
+			sess.dirty = true
+		}
+		c.Set(ContextKey, sess)
+
+		sw := &sessionWriter{ResponseWriter: c.Writer}
+		sw.commit = func() { save(c, store, &cfg, sess, sw.ResponseWriter) }
+		c.Writer = sw
+		c.Next()
+		sw.once.Do(sw.commit)
+		c.Writer = sw.ResponseWriter
+	}
+}
+
+// Get returns the session loaded by Middleware.
+func Get(c *gin.Context) *Session {
+	v, ok := c.Get(ContextKey)
+	if !ok {
+		return nil
+	}
+	sess, _ := v.(*Session)
+	return sess
+}
+
+func load(c *gin.Context, store Store, cfg *Config, now time.Time) *Session {
+	value, err := c.Cookie(cfg.CookieName)
+	if err != nil || value == "" {
+		return newSession(now)
+	}
+
+	sess, err := store.Load(c.Request.Context(), value)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Error().Err(err).Msg("Failed to load session")
+		}
+		return newSession(now)
+	}
+
+	if now.Sub(sess.data.LastSeen) > cfg.IdleTimeout || now.Sub(sess.data.CreatedAt) > cfg.AbsoluteTimeout {
+		if err := store.Delete(c.Request.Context(), sess.data.ID); err != nil {
+			log.Error().Err(err).Msg("Failed to delete expired session")
+		}
+		return newSession(now)
+	}
+	return sess
+}
+
+func save(c *gin.Context, store Store, cfg *Config, sess *Session, w http.ResponseWriter) {
+	ctx := c.Request.Context()
+	cookie := &http.Cookie{
+		Name:     cfg.CookieName,
+		Path:     cfg.CookiePath,
+		Domain:   cfg.CookieDomain,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: true,
+		SameSite: cfg.SameSite,
+	}
+
+	if sess.destroyed {
+		for _, id := range []string{sess.data.ID, sess.previousID} {
+			if id == "" {
+				continue
+			}
+			if err := store.Delete(ctx, id); err != nil {
+				log.Error().Err(err).Msg("Failed to delete session")
+			}
+		}
+		if !sess.isNew || sess.previousID != "" {
+			cookie.MaxAge = -1
+			http.SetCookie(w, cookie)
+		}
+		return
+	}
+	if !sess.dirty || (sess.isNew && len(sess.data.Values) == 0) {
+		return
+	}
+
+	remaining := cfg.AbsoluteTimeout - time.Since(sess.data.CreatedAt)
+	ttl := cfg.IdleTimeout
+	if remaining < ttl {
+		ttl = remaining
+	}
+	value, err := store.Save(ctx, sess, ttl)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to save session")
+		return
+	}
+	cookie.Value = value
+	cookie.MaxAge = int(remaining / time.Second)
+	http.SetCookie(w, cookie)
+	sess.dirty = false
+}
+
+// sessionWriter saves the session before the first byte of the response
+// is written, while cookies can still be set.
+type sessionWriter struct {
+	gin.ResponseWriter
+	once   sync.Once
+	commit func()
+}
+
+func (w *sessionWriter) WriteHeaderNow() {
+	w.once.Do(w.commit)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	w.once.Do(w.commit)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *sessionWriter) WriteString(s string) (int, error) {
+	w.once.Do(w.commit)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *sessionWriter) Flush() {
+	w.once.Do(w.commit)
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,138 @@
+package sessions
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// run serves one request through Middleware and handler, sending cookie
+// when it is not empty, and returns the session cookie in the response.
+func run(t *testing.T, store Store, cfg Config, cookie string, handler gin.HandlerFunc) (*Session, *http.Cookie) {
+	t.Helper()
+	var sess *Session
+	router := gin.New()
+	router.GET("/", Middleware(store, cfg), func(c *gin.Context) {
+		sess = Get(c)
+		handler(c)
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "session", Value: cookie})
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "session" {
+			return sess, c
+		}
+	}
+	return sess, nil
+}
+
+func noop(*gin.Context) {}
+
+func TestMiddlewareLifecycle(t *testing.T) {
+	store, _ := NewCookieStore(testKey(1))
+	cfg := Config{CookieSecure: true}
+
+	sess, cookie := run(t, store, cfg, "", noop)
+	if !sess.IsNew() || cookie != nil {
+		t.Fatalf("empty new session: isNew=%v cookie=%v", sess.IsNew(), cookie)
+	}
+
+	_, cookie = run(t, store, cfg, "", func(c *gin.Context) { Get(c).Set("user", "alice") })
+	if cookie == nil {
+		t.Fatal("no cookie for a session with values")
+	}
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/" || cookie.MaxAge <= 0 {
+		t.Errorf("cookie attributes = %+v", cookie)
+	}
+
+	sess, _ = run(t, store, cfg, cookie.Value, noop)
+	if sess.IsNew() || sess.GetString("user") != "alice" {
+		t.Errorf("session not restored: isNew=%v values=%v", sess.IsNew(), sess.data.Values)
+	}
+
+	_, expired := run(t, store, cfg, cookie.Value, func(c *gin.Context) { Get(c).Destroy() })
+	if expired == nil || expired.MaxAge >= 0 {
+		t.Errorf("Destroy did not expire the cookie: %+v", expired)
+	}
+}
+
+func TestMiddlewareRejectsBadCookies(t *testing.T) {
+	store, _ := NewCookieStore(testKey(1))
+	ctx := context.Background()
+
+	valid := func(created, lastSeen time.Time) string {
+		sess := newSession(created)
+		sess.data.LastSeen = lastSeen
+		sess.Set("user", "alice")
+		v, err := store.Save(ctx, sess, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	now := time.Now()
+	good := valid(now, now)
+	raw, _ := base64.RawURLEncoding.DecodeString(good)
+	raw[len(raw)/2] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(raw)
+	forged, _ := NewCookieStore(testKey(9))
+	forgedSess := newSession(now)
+	forgedSess.Set("user", "admin")
+	forgedValue, _ := forged.Save(ctx, forgedSess, time.Hour)
+
+	tests := []struct {
+		name    string
+		cookie  string
+		wantNew bool
+	}{
+		{"valid", good, false},
+		{"tampered", tampered, true},
+		{"signed with another key", forgedValue, true},
+		{"garbage", "not-a-session", true},
+		{"idle timeout", valid(now.Add(-time.Hour), now.Add(-31*time.Minute)), true},
+		{"absolute timeout", valid(now.Add(-25*time.Hour), now), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess, _ := run(t, store, Config{}, tt.cookie, noop)
+			if sess.IsNew() != tt.wantNew {
+				t.Fatalf("isNew = %v, want %v", sess.IsNew(), tt.wantNew)
+			}
+			if tt.wantNew && len(sess.data.Values) != 0 {
+				t.Errorf("rejected cookie leaked values %v", sess.data.Values)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRotate(t *testing.T) {
+	store := NewServerStore(NewMemoryBackend())
+	_, cookie := run(t, store, Config{}, "", func(c *gin.Context) { Get(c).Set("user", "alice") })
+	oldID := cookie.Value
+
+	_, rotated := run(t, store, Config{}, oldID, func(c *gin.Context) { Get(c).Rotate() })
+	if rotated == nil || rotated.Value == oldID {
+		t.Fatalf("Rotate did not issue a new ID: %+v", rotated)
+	}
+	if sess, _ := run(t, store, Config{}, oldID, noop); !sess.IsNew() {
+		t.Error("fixated session ID still valid after Rotate")
+	}
+	if sess, _ := run(t, store, Config{}, rotated.Value, noop); sess.GetString("user") != "alice" {
+		t.Error("values lost on Rotate")
+	}
+}
@@ -0,0 +1,107 @@
+// Package sessions provides HTTP sessions stored either client-side in
+// encrypted cookies or server-side behind a pluggable backend.
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+const sessionIDBytes = 32
+
+// Session is the per-request session state. It is not safe for concurrent
+// use across goroutines of the same request.
+type Session struct {
+	data sessionData
+
+	isNew     bool
+	dirty     bool
+	destroyed bool
+	// previousID is the ID replaced by Rotate, deleted on save.
+	previousID string
+}
+
+type sessionData struct {
+	ID        string                 `json:"id"`
+	Values    map[string]interface{} `json:"values"`
+	CreatedAt time.Time              `json:"created_at"`
+	LastSeen  time.Time              `json:"last_seen"`
+}
+
+func newSession(now time.Time) *Session {
+	return &Session{
+		data: sessionData{
+			ID:        newSessionID(),
+			Values:    make(map[string]interface{}),
+			CreatedAt: now,
+			LastSeen:  now,
+		},
+		isNew: true,
+		dirty: true,
+	}
+}
+
+// ID returns the session identifier.
+func (s *Session) ID() string {
+	return s.data.ID
+}
+
+// IsNew reports whether the session was created by this request.
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+// CreatedAt returns when the session was first created.
+func (s *Session) CreatedAt() time.Time {
+	return s.data.CreatedAt
+}
+
+// Get returns the value stored under key. Values round-trip through JSON,
+// so numbers come back as float64.
+func (s *Session) Get(key string) (interface{}, bool) {
+	v, ok := s.data.Values[key]
+	return v, ok
+}
+
+// GetString returns the string stored under key, or an empty string.
+func (s *Session) GetString(key string) string {
+	v, _ := s.data.Values[key].(string)
+	return v
+}
+
+// Set stores a JSON-serializable value under key.
+func (s *Session) Set(key string, value interface{}) {
+	s.data.Values[key] = value
+	s.dirty = true
+}
+
+// Delete removes key from the session.
+func (s *Session) Delete(key string) {
+	delete(s.data.Values, key)
+	s.dirty = true
+}
+
+// Rotate assigns a new session ID while keeping the values. Call it on
+// every privilege change, such as login, to prevent session fixation.
+func (s *Session) Rotate() {
+	if s.previousID == "" && !s.isNew {
+		s.previousID = s.data.ID
+	}
+	s.data.ID = newSessionID()
+	s.dirty = true
+}
+
+// Destroy discards the session and expires its cookie.
+func (s *Session) Destroy() {
+	s.destroyed = true
+	s.data.Values = make(map[string]interface{})
+}
+
+func newSessionID() string {
+	b := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
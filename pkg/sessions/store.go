@@ -0,0 +1,229 @@
+package sessions
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxCookieSize is the conservative browser limit for a single cookie.
+const maxCookieSize = 4096
+
+var (
+	// ErrNotFound is returned when a cookie references no valid session.
+	ErrNotFound = errors.New("session not found")
+	// ErrCookieTooLarge is returned when an encoded cookie session exceeds
+	// the browser cookie size limit.
+	ErrCookieTooLarge = errors.New("session too large for cookie storage")
+)
+
+// Store persists sessions and maps them to cookie values. Custom server-side
+// storage should implement Backend and use NewServerStore.
+type Store interface {
+	// Load returns the session referenced by a cookie value, or
+	// ErrNotFound.
+	Load(ctx context.Context, value string) (*Session, error)
+	// Save persists s for ttl and returns the cookie value referencing it.
+	Save(ctx context.Context, s *Session, ttl time.Duration) (string, error)
+	// Delete removes the session with the given ID.
+	Delete(ctx context.Context, id string) error
+}
+
+// CookieStore keeps the whole session in the cookie, encrypted and
+// authenticated with AES-GCM.
+type CookieStore struct {
+	aeads []cipher.AEAD
+}
+
+// NewCookieStore returns a CookieStore. Each key must be 16, 24 or 32 bytes.
+// The first key encrypts; all keys decrypt, which allows key rotation.
+func NewCookieStore(keys ...[]byte) (*CookieStore, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+	store := &CookieStore{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session key: %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		store.aeads = append(store.aeads, aead)
+	}
+	return store, nil
+}
+
+// Load decrypts the cookie value.
+func (s *CookieStore) Load(_ context.Context, value string) (*Session, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	for _, aead := range s.aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+		nonce, ciphertext := raw[:aead.NonceSize()], raw[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			continue
+		}
+		return decodeSession(plain)
+	}
+	return nil, ErrNotFound
+}
+
+// Save encrypts the session into a cookie value.
+func (s *CookieStore) Save(_ context.Context, sess *Session, _ time.Duration) (string, error) {
+	plain, err := json.Marshal(sess.data)
+	if err != nil {
+		return "", err
+	}
+	aead := s.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil))
+	if len(value) > maxCookieSize {
+		return "", ErrCookieTooLarge
+	}
+	return value, nil
+}
+
+// Delete is a no-op; cookie sessions are discarded by expiring the cookie.
+func (s *CookieStore) Delete(context.Context, string) error {
+	return nil
+}
+
+// Backend is the key-value storage used by ServerStore. A Redis backend
+// maps directly onto GET, SET with expiry and DEL.
+type Backend interface {
+	// Get returns the stored value or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// ServerStore keeps sessions in a Backend and only the random session ID
+// in the cookie.
+type ServerStore struct {
+	backend Backend
+	prefix  string
+}
+
+// NewServerStore returns a ServerStore storing sessions under "session:<id>".
+func NewServerStore(backend Backend) *ServerStore {
+	return &ServerStore{backend: backend, prefix: "session:"}
+}
+
+// Load fetches the session referenced by the cookie ID.
+func (s *ServerStore) Load(ctx context.Context, value string) (*Session, error) {
+	if raw, err := base64.RawURLEncoding.DecodeString(value); err != nil || len(raw) != sessionIDBytes {
+		return nil, ErrNotFound
+	}
+	data, err := s.backend.Get(ctx, s.prefix+value)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSession(data)
+}
+
+// Save stores the session and removes the entry replaced by Rotate.
+func (s *ServerStore) Save(ctx context.Context, sess *Session, ttl time.Duration) (string, error) {
+	data, err := json.Marshal(sess.data)
+	if err != nil {
+		return "", err
+	}
+	if err := s.backend.Set(ctx, s.prefix+sess.data.ID, data, ttl); err != nil {
+		return "", err
+	}
+	if sess.previousID != "" {
+		if err := s.backend.Delete(ctx, s.prefix+sess.previousID); err != nil {
+			return "", err
+		}
+		sess.previousID = ""
+	}
+	return sess.data.ID, nil
+}
+
+// Delete removes the session.
+func (s *ServerStore) Delete(ctx context.Context, id string) error {
+	return s.backend.Delete(ctx, s.prefix+id)
+}
+
+func decodeSession(data []byte) (*Session, error) {
+	sess := &Session{}
+	if err := json.Unmarshal(data, &sess.data); err != nil || sess.data.ID == "" {
+		return nil, ErrNotFound
+	}
+	if sess.data.Values == nil {
+		sess.data.Values = make(map[string]interface{})
+	}
+	return sess, nil
+}
+
+// MemoryBackend is an in-process Backend for tests and single instances.
+type MemoryBackend struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{entries: make(map[string]memoryEntry)}
+}
+
+// Get returns an unexpired value.
+func (m *MemoryBackend) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil, ErrNotFound
+	}
+	return entry.value, nil
+}
+
+// Set stores value until ttl elapses. Expired entries are evicted at most
+// once a minute.
+func (m *MemoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.lastSweep) > time.Minute {
+		m.lastSweep = now
+		for k, entry := range m.entries {
+			if now.After(entry.expires) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	m.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete removes key.
+func (m *MemoryBackend) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
@@ -0,0 +1,187 @@
+package sessions
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func savedSession(t *testing.T, store Store, values map[string]interface{}) (*Session, string) {
+	t.Helper()
+	sess := newSession(time.Now())
+	for k, v := range values {
+		sess.Set(k, v)
+	}
+	value, err := store.Save(context.Background(), sess, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess, value
+}
+
+func TestNewCookieStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    [][]byte
+		wantErr bool
+	}{
+		{"no keys", nil, true},
+		{"short key", [][]byte{[]byte("short")}, true},
+		{"AES-128", [][]byte{bytes.Repeat([]byte{1}, 16)}, false},
+		{"AES-256 with old key", [][]byte{testKey(1), testKey(2)}, false},
+		{"one invalid key", [][]byte{testKey(1), []byte("short")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCookieStore(tt.keys...); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCookieStoreLoad(t *testing.T) {
+	store, err := NewCookieStore(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, value := savedSession(t, store, map[string]interface{}{"user": "alice"})
+
+	raw, _ := base64.RawURLEncoding.DecodeString(value)
+	flipped := append([]byte(nil), raw...)
+	flipped[len(flipped)-1] ^= 1
+	nonceFlipped := append([]byte(nil), raw...)
+	nonceFlipped[0] ^= 1
+	other, _ := NewCookieStore(testKey(9))
+	_, foreign := savedSession(t, other, map[string]interface{}{"user": "mallory"})
+
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"valid", value, true},
+		{"tampered ciphertext", base64.RawURLEncoding.EncodeToString(flipped), false},
+		{"tampered nonce", base64.RawURLEncoding.EncodeToString(nonceFlipped), false},
+		{"truncated", value[:len(value)-4], false},
+		{"shorter than nonce", base64.RawURLEncoding.EncodeToString(raw[:4]), false},
+		{"not base64", "!!" + value, false},
+		{"other key", foreign, false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Load(context.Background(), tt.value)
+			if !tt.ok {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("err = %v, want ErrNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID() != sess.ID() || got.GetString("user") != "alice" {
+				t.Errorf("loaded %+v", got.data)
+			}
+		})
+	}
+}
+
+func TestCookieStoreKeyRotation(t *testing.T) {
+	old, _ := NewCookieStore(testKey(1))
+	_, value := savedSession(t, old, map[string]interface{}{"n": 1})
+
+	rotated, _ := NewCookieStore(testKey(2), testKey(1))
+	sess, err := rotated.Load(context.Background(), value)
+	if err != nil {
+		t.Fatalf("old key no longer decrypts: %v", err)
+	}
+	fresh, err := rotated.Save(context.Background(), sess, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Load(context.Background(), fresh); !errors.Is(err, ErrNotFound) {
+		t.Errorf("new cookie encrypted with the old key: err = %v", err)
+	}
+}
+
+func TestCookieStoreTooLarge(t *testing.T) {
+	store, _ := NewCookieStore(testKey(1))
+	sess := newSession(time.Now())
+	sess.Set("blob", strings.Repeat("x", maxCookieSize))
+	if _, err := store.Save(context.Background(), sess, time.Hour); !errors.Is(err, ErrCookieTooLarge) {
+		t.Errorf("err = %v, want ErrCookieTooLarge", err)
+	}
+}
+
+func TestServerStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewServerStore(NewMemoryBackend())
+	sess, id := savedSession(t, store, map[string]interface{}{"user": "alice"})
+
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"valid", id, true},
+		{"unknown id", newSessionID(), false},
+		{"wrong length", id[:10], false},
+		{"backend key injection", id + ":other", false},
+		{"not base64", "%%%", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Load(ctx, tt.value)
+			if !tt.ok {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("err = %v, want ErrNotFound", err)
+				}
+				return
+			}
+			if err != nil || got.GetString("user") != "alice" {
+				t.Fatalf("Load = %v, %v", got, err)
+			}
+		})
+	}
+
+	sess.isNew = false
+	sess.Rotate()
+	newID, err := store.Save(ctx, sess, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newID == id {
+		t.Fatal("Rotate kept the session ID")
+	}
+	if _, err := store.Load(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("rotated-out session still loads: err = %v", err)
+	}
+	if err := store.Delete(ctx, newID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(ctx, newID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted session still loads: err = %v", err)
+	}
+}
+
+func TestMemoryBackendExpiry(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryBackend()
+	if err := m.Set(ctx, "k", []byte("v"), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := m.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired entry returned, err = %v", err)
+	}
+}